@@ -0,0 +1,135 @@
+// Copyright © 2017 Kevin Kirsche <kev.kirsche[at]gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer"
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+	"github.com/spf13/cobra"
+)
+
+// clientFlags hold the connection settings of commands querying a single
+// host from the command line
+var clientFlags struct {
+	version       string
+	community     string
+	port          uint16
+	retries       int
+	timeout       time.Duration
+	username      string
+	securityLevel string
+	authProtocol  string
+	authPassword  string
+	privProtocol  string
+	privPassword  string
+	contextName   string
+	contextEngine string
+	engineID      string
+	engineBoots   uint32
+	engineTime    uint32
+}
+
+// addClientFlags adds the flags used to connect to a single host
+func addClientFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&clientFlags.version, "snmp-version", "V", "v2c", "SNMP version, v1, v2c or v3")
+	cmd.Flags().StringVarP(&clientFlags.community, "community", "C", "public", "Community used by v1 and v2c")
+	cmd.Flags().Uint16VarP(&clientFlags.port, "port", "p", 161, "UDP port of the agent")
+	cmd.Flags().IntVarP(&clientFlags.retries, "retries", "r", 0, "Number of times a request is retried")
+	cmd.Flags().DurationVarP(&clientFlags.timeout, "timeout", "t", 5*time.Second, "Time to wait for each response")
+	cmd.Flags().StringVarP(&clientFlags.username, "username", "u", "", "v3 user name")
+	cmd.Flags().StringVarP(&clientFlags.securityLevel, "security-level", "l", "AuthPriv", "v3 security level, NoAuthNoPriv, AuthNoPriv or AuthPriv")
+	cmd.Flags().StringVarP(&clientFlags.authProtocol, "auth-protocol", "a", "SHA", "v3 authentication protocol")
+	cmd.Flags().StringVarP(&clientFlags.authPassword, "auth-password", "A", "", "v3 authentication passphrase")
+	cmd.Flags().StringVarP(&clientFlags.privProtocol, "priv-protocol", "x", "AES", "v3 privacy protocol")
+	cmd.Flags().StringVarP(&clientFlags.privPassword, "priv-password", "X", "", "v3 privacy passphrase")
+	cmd.Flags().StringVar(&clientFlags.contextName, "context", "", "v3 context name, such as vlan-10 for per-VLAN data")
+	cmd.Flags().StringVarP(&clientFlags.contextEngine, "context-engine-id", "E", "", "v3 hex encoded context engine ID, defaulting to the engine ID of the agent")
+	cmd.Flags().StringVarP(&clientFlags.engineID, "engine-id", "e", "", "v3 hex encoded authoritative engine ID, skipping its discovery")
+	cmd.Flags().Uint32Var(&clientFlags.engineBoots, "engine-boots", 0, "v3 engine boots used with --engine-id")
+	cmd.Flags().Uint32Var(&clientFlags.engineTime, "engine-time", 0, "v3 engine time used with --engine-id")
+}
+
+// connectClient creates and connects a client for a host using the client
+// flags
+func connectClient(host string) (*gosnmp.GoSNMP, error) {
+	version := libinquirer.NewVersion(clientFlags.version)
+
+	var auth *libinquirer.SNMPAuth
+	if version.V3 {
+		var err error
+		auth, err = libinquirer.NewAuth(clientFlags.username, clientFlags.securityLevel, clientFlags.authPassword, clientFlags.authProtocol, clientFlags.privPassword, clientFlags.privProtocol)
+		if err != nil {
+			return nil, err
+		}
+
+		auth.ContextName = clientFlags.contextName
+		if auth.ContextEngineID, err = libinquirer.DecodeEngineID(clientFlags.contextEngine); err != nil {
+			return nil, err
+		}
+		if auth.AuthoritativeEngineID, err = libinquirer.DecodeEngineID(clientFlags.engineID); err != nil {
+			return nil, err
+		}
+		auth.AuthoritativeEngineBoots = clientFlags.engineBoots
+		auth.AuthoritativeEngineTime = clientFlags.engineTime
+	}
+
+	client, err := libinquirer.CreateClient(host, clientFlags.community, clientFlags.retries, version, auth)
+	if err != nil {
+		return nil, err
+	}
+	client.Port = clientFlags.port
+	client.Timeout = clientFlags.timeout
+
+	if err = libinquirer.ConnectClient(client); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// queryConfiguration parses the configuration file for its MIBs and outputs.
+// Commands querying a single host do not require one, so an empty
+// configuration is used when the default configuration file does not exist.
+// Configuration files which were provided or can not be parsed are errors
+func queryConfiguration() (*libinquirer.Configuration, error) {
+	conf, err := libinquirer.ParseConfigFile(cfgFile)
+	if err != nil && os.IsNotExist(err) && !RootCmd.PersistentFlags().Changed("config") {
+		logrus.WithError(err).Debugln("Querying without a configuration file")
+		return &libinquirer.Configuration{MIB: mib.New()}, nil
+	}
+
+	return conf, err
+}
+
+// resolveArgs resolves OIDs or names provided as arguments, returning the
+// numeric OIDs and their names
+func resolveArgs(m *mib.MIB, args []string) ([]string, []string, error) {
+	oids, names := []string{}, []string{}
+	for _, arg := range args {
+		oid, err := m.Resolve(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		oids = append(oids, oid)
+		names = append(names, m.Name(oid))
+	}
+
+	return oids, names, nil
+}
@@ -0,0 +1,89 @@
+// Copyright © 2017 Kevin Kirsche <kev.kirsche[at]gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonWorkers     int
+	daemonHostTimeout time.Duration
+	daemonInterval    time.Duration
+	daemonJitter      time.Duration
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Long running SNMP polling without cron",
+	Long: `Daemon polls every configured host on its own interval until it is
+stopped. Hosts may set an interval in seconds in the configuration file,
+otherwise the interval flag is used. A random jitter is added to each interval
+so that hosts are not all polled at the same moment.
+
+SNMP clients are kept alive between polls. The daemon shuts down gracefully,
+waiting for in-flight polls to finish, when it receives SIGINT or SIGTERM.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := libinquirer.ParseConfigFile(cfgFile)
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to parse configuration file")
+			return
+		}
+
+		sink, err := createSink(conf)
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to create output")
+			return
+		}
+		defer sink.Close()
+
+		logrus.WithField("requested_poll_qty", len(conf.Poll)).Infof("%s poll configurations provided", cfgFile)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			logrus.WithField("signal", sig.String()).Infoln("Shutting down once in-flight polls complete")
+			cancel()
+		}()
+
+		poller := libinquirer.NewPoller(daemonWorkers, daemonHostTimeout, 0)
+		scheduler := libinquirer.NewScheduler(poller, daemonInterval, daemonJitter, func(result *libinquirer.HostResult) {
+			outputHostResult(result, sink, conf.MIB)
+		})
+		scheduler.Run(ctx, conf.Poll)
+		logrus.Infoln("Daemon stopped")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(daemonCmd)
+
+	daemonCmd.Flags().IntVarP(&daemonWorkers, "workers", "w", 64, "Number of hosts to poll concurrently")
+	daemonCmd.Flags().DurationVar(&daemonHostTimeout, "host-timeout", 30*time.Second, "Deadline for polling all OIDs of a single host")
+	daemonCmd.Flags().DurationVarP(&daemonInterval, "interval", "i", time.Minute, "Polling interval for hosts without one configured")
+	daemonCmd.Flags().DurationVarP(&daemonJitter, "jitter", "j", 5*time.Second, "Maximum random delay added to each polling interval")
+	addOutputFlags(daemonCmd)
+}
@@ -0,0 +1,154 @@
+// Copyright © 2017 Kevin Kirsche <kev.kirsche[at]gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	discoverCIDRs       []string
+	discoverCommunities []string
+	discoverPort        uint16
+	discoverWorkers     int
+	discoverTimeout     time.Duration
+	discoverWrite       string
+)
+
+// discoverCmd represents the discover command
+var discoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "Discover SNMP agents and write a configuration polling them",
+	Long: `Discover probes every address of one or more CIDR ranges using candidate
+credentials, recording the sysName, sysDescr and sysObjectID of each agent
+which answers. A configuration polling the responders, using the credentials
+they answered, is then written. Credentials which are secret references, such
+as env:SNMP_COMMUNITY, are written as the reference rather than its value.
+
+Candidate communities are tried using v2c before the candidate v3 users. Both
+are read from the discovery section of the configuration file, and
+communities may also be provided as flags. The outputs, state directory and
+MIB directories of the configuration file are kept in the configuration
+written, which records the agents found in its discovered section.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(discoverCIDRs) == 0 {
+			logrus.Errorln("At least one range must be provided using --cidr")
+			return
+		}
+
+		conf, err := discoverConfiguration()
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to parse configuration file")
+			return
+		}
+
+		if len(discoverCommunities) > 0 {
+			conf.Discovery.Communities = libinquirer.Secrets(discoverCommunities)
+		}
+		if discoverPort != 0 {
+			conf.Discovery.Port = discoverPort
+		}
+		if len(conf.Discovery.Communities) == 0 && len(conf.Discovery.Users) == 0 {
+			logrus.Errorln("No candidate communities or users were provided")
+			return
+		}
+
+		hosts := []string{}
+		for _, cidr := range discoverCIDRs {
+			expanded, err := libinquirer.Hosts(cidr)
+			if err != nil {
+				logrus.WithError(err).Errorln("Invalid range")
+				return
+			}
+			hosts = append(hosts, expanded...)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"addresses":   len(hosts),
+			"communities": len(conf.Discovery.Communities),
+			"users":       len(conf.Discovery.Users),
+		}).Infoln("Discovering SNMP agents")
+
+		discovered := []libinquirer.DiscoveredHost{}
+		d := libinquirer.NewDiscoverer(conf.Discovery, discoverWorkers, discoverTimeout)
+		for result := range d.Run(context.Background(), hosts) {
+			if result.Err != nil {
+				logrus.WithError(result.Err).WithField("host", result.Host).Debugln("No SNMP agent discovered")
+				continue
+			}
+
+			h := result.Discovered
+			logrus.WithFields(logrus.Fields{
+				"host":          h.Host,
+				"version":       h.Version,
+				"username":      h.Username,
+				"sys_name":      h.SysName,
+				"sys_descr":     h.SysDescr,
+				"sys_object_id": h.SysObjectID,
+			}).Infoln("SNMP agent discovered")
+			discovered = append(discovered, *h)
+		}
+
+		var w io.Writer = os.Stdout
+		if discoverWrite != "" && discoverWrite != "-" {
+			f, err := os.OpenFile(discoverWrite, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+			if err != nil {
+				logrus.WithError(err).Errorln("Failed to create configuration file")
+				return
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if err = libinquirer.WriteDiscoveredConfiguration(w, conf, discovered); err != nil {
+			logrus.WithError(err).Errorln("Failed to write discovered configuration")
+			return
+		}
+		logrus.WithField("discovered", len(discovered)).Infoln("Discovery complete")
+	},
+}
+
+// discoverConfiguration parses the configuration file. Discovery may use the
+// communities provided as flags without a configuration file, but only when
+// the default file does not exist, so that the outputs and directories of a
+// file which fails to parse are not silently left out of the configuration
+// written
+func discoverConfiguration() (*libinquirer.Configuration, error) {
+	conf, err := libinquirer.ParseConfigFile(cfgFile)
+	if err != nil && len(discoverCommunities) > 0 && os.IsNotExist(err) && !RootCmd.PersistentFlags().Changed("config") {
+		logrus.WithError(err).Debugln("Discovering using the communities provided as flags only")
+		return &libinquirer.Configuration{}, nil
+	}
+
+	return conf, err
+}
+
+func init() {
+	RootCmd.AddCommand(discoverCmd)
+
+	discoverCmd.Flags().StringSliceVar(&discoverCIDRs, "cidr", nil, "Range of addresses to probe, such as 10.0.0.0/24, may be repeated")
+	discoverCmd.Flags().StringSliceVar(&discoverCommunities, "community", nil, "Candidate v2c community, may be repeated. Overrides communities in the config file")
+	discoverCmd.Flags().Uint16Var(&discoverPort, "port", 0, "UDP port agents listen on (default 161)")
+	discoverCmd.Flags().IntVarP(&discoverWorkers, "workers", "w", 64, "Number of addresses to probe concurrently")
+	discoverCmd.Flags().DurationVar(&discoverTimeout, "timeout", 2*time.Second, "Time to wait for an agent to answer each candidate")
+	discoverCmd.Flags().StringVarP(&discoverWrite, "write", "f", "-", "File the discovered configuration is written to, - for stdout")
+}
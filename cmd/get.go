@@ -0,0 +1,85 @@
+// Copyright © 2017 Kevin Kirsche <kev.kirsche[at]gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// getCmd represents the get command
+var getCmd = &cobra.Command{
+	Use:   "get HOST OID...",
+	Short: "Retrieve OIDs from a single host",
+	Long: `Get retrieves one or more OIDs from a host using a single request, in the
+same way as snmpget. OIDs may be numeric or names from the MIBs in mib_dirs,
+such as SNMPv2-MIB::sysName.0.
+
+Values are decoded and written to the outputs in the same way as poll results.
+The configuration file is only used for its MIB directories and outputs.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := queryConfiguration()
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to parse configuration file")
+			return
+		}
+		host := args[0]
+
+		oids, names, err := resolveArgs(conf.MIB, args[1:])
+		if err != nil {
+			logrus.WithError(err).Errorln("Invalid OID")
+			return
+		}
+
+		sink, err := createSink(conf)
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to create output")
+			return
+		}
+		defer sink.Close()
+
+		client, err := connectClient(host)
+		if err != nil {
+			logrus.WithError(err).WithField("host", host).Errorln("Failed to create SNMP client")
+			return
+		}
+		defer client.Conn.Close()
+
+		started := time.Now()
+		walks, err := libinquirer.Get(client, oids, names)
+		if err != nil {
+			logrus.WithError(err).WithField("host", host).Errorln("Failed to execute get request")
+			return
+		}
+		libinquirer.LogEngine(client)
+
+		outputHostResult(&libinquirer.HostResult{
+			Config:   libinquirer.PollConfiguration{Host: host},
+			Walks:    walks,
+			Started:  started,
+			Duration: time.Since(started),
+		}, sink, conf.MIB)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(getCmd)
+	addClientFlags(getCmd)
+	addOutputFlags(getCmd)
+}
@@ -0,0 +1,100 @@
+// Copyright © 2017 Kevin Kirsche <kev.kirsche[at]gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/kkirsche/snmpInquirer2/libinquirer"
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	outputs []string
+
+	// rateTracker keeps the previous sample of each counter so per-second
+	// rates can be output alongside raw values
+	rateTracker = libinquirer.NewRateTracker()
+)
+
+// addOutputFlags adds the flags used to select where results are written
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&outputs, "output", "o", nil, "Output results to type[=target], may be repeated. Overrides outputs in the config file (default logrus)")
+}
+
+// createSink creates the sink results are written to, preferring outputs
+// provided as flags over those in the configuration file
+func createSink(conf *libinquirer.Configuration) (libinquirer.Sink, error) {
+	configured := conf.Outputs
+	if len(outputs) > 0 {
+		configured = []libinquirer.OutputConfiguration{}
+		for _, o := range outputs {
+			configured = append(configured, libinquirer.ParseOutput(o))
+		}
+	}
+
+	if len(configured) == 0 {
+		configured = append(configured, libinquirer.OutputConfiguration{Type: libinquirer.LogrusOutput})
+	}
+
+	for _, o := range configured {
+		logrus.WithFields(logrus.Fields{
+			"type":   o.Type,
+			"target": o.Target,
+		}).Debugln("Creating output")
+	}
+
+	return libinquirer.NewMultiSink(configured)
+}
+
+// outputHostResult writes the result of polling a single host to a sink,
+// rendering values using the MIB when one is provided
+func outputHostResult(result *libinquirer.HostResult, sink libinquirer.Sink, m *mib.MIB) {
+	cfg := result.Config
+	if result.Err != nil {
+		logrus.WithError(result.Err).WithFields(logrus.Fields{
+			"host":     cfg.Host,
+			"duration": result.Duration,
+		}).Errorln("Failed to poll host")
+	}
+
+	for _, walk := range result.AllWalks() {
+		if walk.Err != nil {
+			logrus.WithError(walk.Err).WithField("host", cfg.Host).Errorln("Failed to execute bulk walk request")
+			continue
+		}
+		if len(walk.PDUs) < 1 {
+			logrus.WithFields(logrus.Fields{
+				"oid":      walk.OID,
+				"oid_name": walk.Name,
+			}).Warnln("No SNMP PDUs retrieved for OID. This may be an indication of a problem")
+		}
+	}
+
+	if err := sink.Write(libinquirer.Records(result, rateTracker, m)); err != nil {
+		logrus.WithError(err).WithField("host", cfg.Host).Errorln("Failed to output results")
+	}
+
+	if len(result.Tables) > 0 {
+		if err := sink.WriteRows(libinquirer.Rows(result, rateTracker, m)); err != nil {
+			logrus.WithError(err).WithField("host", cfg.Host).Errorln("Failed to output table rows")
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"host":     cfg.Host,
+		"duration": result.Duration,
+	}).Debugln("Host output complete")
+}
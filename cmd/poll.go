@@ -15,154 +15,94 @@
 package cmd
 
 import (
-	"fmt"
-	"strings"
+	"context"
+	"time"
 
 	"github.com/kkirsche/snmpInquirer2/libinquirer"
 	"github.com/sirupsen/logrus"
-	"github.com/soniah/gosnmp"
 	"github.com/spf13/cobra"
 )
 
+var (
+	pollWorkers     int
+	pollHostTimeout time.Duration
+	pollRunTimeout  time.Duration
+	pollStateDir    string
+	pollStateMaxAge time.Duration
+)
+
 // pollCmd represents the minute command
 var pollCmd = &cobra.Command{
 	Use:   "poll",
 	Short: "SNMP polling for use via cron",
 	Long: `Minute is used for per minute polling, most commonly via cron or
 another automated service. This does not automate the timing, a tool like cron
-must be used to loop this every minute.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		var constLookup = map[gosnmp.Asn1BER]string{
-			gosnmp.UnknownType:       "UnknownType",
-			gosnmp.Boolean:           "Boolean",
-			gosnmp.Integer:           "Integer",
-			gosnmp.BitString:         "BitString",
-			gosnmp.OctetString:       "OctetString",
-			gosnmp.Null:              "Null",
-			gosnmp.ObjectIdentifier:  "ObjectIdentifier",
-			gosnmp.ObjectDescription: "ObjectDescription",
-			gosnmp.IPAddress:         "IPAddress",
-			gosnmp.Counter32:         "Counter32",
-			gosnmp.Gauge32:           "Gauge32",
-			gosnmp.TimeTicks:         "TimeTicks",
-			gosnmp.Opaque:            "Opaque",
-			gosnmp.NsapAddress:       "NsapAddress",
-			gosnmp.Counter64:         "Counter64",
-			gosnmp.Uinteger32:        "Uinteger32",
-			gosnmp.OpaqueFloat:       "OpaqueFloat",
-			gosnmp.OpaqueDouble:      "OpaqueDouble",
-			gosnmp.NoSuchObject:      "NoSuchObject",
-			gosnmp.NoSuchInstance:    "NoSuchInstance",
-			gosnmp.EndOfMibView:      "EndOfMibView",
-		}
+must be used to loop this every minute.
+
+When a state directory is configured, counter samples are stored between runs
+so that per-second rates can be output alongside raw values.
 
+Hosts are polled concurrently by a bounded pool of workers. Each host must
+finish within the host timeout and the whole run within the run timeout.`,
+	Run: func(cmd *cobra.Command, args []string) {
 		conf, err := libinquirer.ParseConfigFile(cfgFile)
 		if err != nil {
 			logrus.WithError(err).Errorln("Failed to parse configuration file")
+			return
 		}
 
-		logrus.WithField("requested_poll_qty", len(conf.Poll)).Infof("%s poll configurations provided", cfgFile)
-		for i, cfg := range conf.Poll {
-			logrus.WithField("iteration", i).Debugln("Beginning poll process")
-			logrus.WithField("version", cfg.Version).Debugln("SNMP version requested --- Validating")
-			sv := libinquirer.NewVersion(cfg.Version)
-
-			var auth *libinquirer.SNMPAuth
-			if sv.Get() == "v3" {
-				auth, err = libinquirer.NewAuth(cfg.Username, cfg.SecurityLevel, cfg.AuthPassword, cfg.AuthProtocol, cfg.PrivPassword, cfg.PrivProtocol)
-				if err != nil {
-					logrus.WithError(err).Errorln("Failed to create SNMP V3 authentication object")
-					return
-				}
-			}
-
-			logrus.WithField("version", cfg.Version).Debugln("SNMP version accepted")
+		sink, err := createSink(conf)
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to create output")
+			return
+		}
+		defer sink.Close()
 
-			logrus.WithFields(logrus.Fields{
-				"host":             cfg.Host,
-				"community_string": cfg.Community,
-				"retries":          cfg.Retries,
-				"version":          cfg.Version,
-			}).Debugln("Creating SNMP client")
+		logrus.WithField("requested_poll_qty", len(conf.Poll)).Infof("%s poll configurations provided", cfgFile)
+		logrus.WithFields(logrus.Fields{
+			"workers":      pollWorkers,
+			"host_timeout": pollHostTimeout,
+			"run_timeout":  pollRunTimeout,
+		}).Debugln("Creating poller")
+		poller := libinquirer.NewPoller(pollWorkers, pollHostTimeout, pollRunTimeout)
+
+		stateDir := conf.StateDir
+		if pollStateDir != "" {
+			stateDir = pollStateDir
+		}
 
-			client, err := libinquirer.CreateClient(cfg.Host, cfg.Community, cfg.Retries, sv, auth)
+		var store *libinquirer.StateStore
+		if stateDir != "" {
+			store, err = libinquirer.NewStateStore(stateDir)
 			if err != nil {
-				logrus.WithError(err).Errorln("Failed to create SNMP client")
-				return
+				logrus.WithError(err).Errorln("Failed to create state store, rates will not be calculated")
+			}
+		}
+
+		if store != nil {
+			if conf.StateMaxAge > 0 {
+				store.MaxAge = time.Duration(conf.StateMaxAge) * time.Second
 			}
-			logrus.Debugln("SNMP client created")
-
-			logrus.Debugln("Generating OID object for querying process")
-			stroids := []string{}
-			for oid, mib := range cfg.OIDs {
-				logrus.WithFields(logrus.Fields{
-					"oid": oid,
-					"mib": mib,
-				}).Debugln("Adding object for querying")
-				stroids = append(stroids, oid)
+			if pollStateMaxAge > 0 {
+				store.MaxAge = pollStateMaxAge
 			}
 
-			logrus.WithFields(logrus.Fields{
-				"host": cfg.Host,
-			}).Debugln("Creating client connection to host")
-			err = client.Connect()
+			samples, err := store.LoadSamples()
 			if err != nil {
-				logrus.WithError(err).Errorln("Failed to open SNMP connection")
-				continue
+				logrus.WithError(err).Errorln("Failed to load previous samples from state store")
 			}
-			defer client.Conn.Close()
-			logrus.Debugln("Client connection created successfully")
-
-			logrus.WithFields(logrus.Fields{
-				"nonrepeaters":   0,
-				"maxrepetitions": 10,
-			}).Debugln("Beginning bulk walk")
-			for _, oid := range stroids {
-				pdus, err := client.BulkWalkAll(oid)
-				if err != nil {
-					logrus.WithError(err).Errorln("Failed to execute bulk walk request")
-					continue
-				}
-				logrus.Debugln("PDU's retrieved, checking for PDU error(s)")
-				if len(pdus) < 1 {
-					logrus.WithFields(logrus.Fields{
-						"oid":      oid,
-						"oid_name": cfg.OIDs[oid],
-					}).Warnln("No SNMP PDUs retrieved for OID. This may be an indication of a problem")
-				}
-				for _, pdu := range pdus {
-					logrus.Debugln("Bulk walk completed successfully")
-					logrus.Debugln("Outputting result values")
-					splitOID := strings.Split(pdu.Name, ".")
-					intIndex := strings.Join(splitOID[len(splitOID)-1:len(splitOID)], ".")
-					switch pdu.Type {
-					case gosnmp.OctetString:
-						logrus.WithFields(logrus.Fields{
-							"full_oid":        pdu.Name,
-							"host_queried":    cfg.Host,
-							"oid":             oid,
-							"oid_name":        cfg.OIDs[oid],
-							"interface_index": intIndex,
-							"pdu_type":        fmt.Sprintf("0x%x", pdu.Type),
-							"pdu_type_name":   constLookup[pdu.Type],
-							"value":           string(pdu.Value.([]byte)),
-						}).Infoln("OID successfully retrieved")
-					default:
-						logrus.WithFields(logrus.Fields{
-							"full_oid":        pdu.Name,
-							"host_queried":    cfg.Host,
-							"oid":             oid,
-							"oid_name":        cfg.OIDs[oid],
-							"interface_index": intIndex,
-							"pdu_type":        fmt.Sprintf("0x%x", pdu.Type),
-							"pdu_type_name":   constLookup[pdu.Type],
-							"value":           gosnmp.ToBigInt(pdu.Value),
-						}).Infoln("OID successfully retrieved")
-					}
-
-				}
+			logrus.WithField("samples", len(samples)).Debugln("Loaded previous samples from state store")
+			rateTracker.Load(samples)
+		}
+
+		for result := range poller.Run(context.Background(), conf.Poll) {
+			outputHostResult(result, sink, conf.MIB)
+		}
+
+		if store != nil {
+			if err = store.SaveSamples(rateTracker.Samples()); err != nil {
+				logrus.WithError(err).Errorln("Failed to save samples to state store")
 			}
-			logrus.Debugln("Host output complete")
 		}
 	},
 }
@@ -170,13 +110,10 @@ must be used to loop this every minute.`,
 func init() {
 	RootCmd.AddCommand(pollCmd)
 
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// pollCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// pollCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	pollCmd.Flags().IntVarP(&pollWorkers, "workers", "w", 64, "Number of hosts to poll concurrently")
+	pollCmd.Flags().DurationVar(&pollHostTimeout, "host-timeout", 30*time.Second, "Deadline for polling all OIDs of a single host")
+	pollCmd.Flags().StringVar(&pollStateDir, "state-dir", "", "Directory used to store counter samples between runs, overrides state_dir in the config file")
+	pollCmd.Flags().DurationVar(&pollStateMaxAge, "state-max-age", 0, "How long counter samples are kept in the state store without being updated, overrides state_max_age in the config file (default 24h)")
+	pollCmd.Flags().DurationVar(&pollRunTimeout, "run-timeout", 55*time.Second, "Deadline for polling all hosts, 0 disables the deadline")
+	addOutputFlags(pollCmd)
 }
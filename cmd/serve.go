@@ -0,0 +1,82 @@
+// Copyright © 2017 Kevin Kirsche <kev.kirsche[at]gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveListen      string
+	serveWorkers     int
+	serveHostTimeout time.Duration
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Prometheus exporter for the configured hosts",
+	Long: `Serve exposes an HTTP /metrics endpoint in the Prometheus text
+exposition format. Every configured host is polled each time the endpoint is
+scraped.
+
+OID names such as IF-MIB::ifHCInOctets become the metric name ifHCInOctets,
+with the host and the instance index as labels. The components of table
+indexes, such as ifIndex, are also added as labels when the MIBs in mib_dirs
+define the table. Counter32 and Counter64 values are exposed as counters,
+numeric values such as Gauge32 as gauges, and string values as a gauge of 1
+with the value as a label.
+
+A /snmp endpoint is also provided for multi-target probing, in the style of the
+Prometheus snmp_exporter. Requests such as /snmp?target=192.0.2.1&module=if_mib
+poll only the target provided, using the settings and OIDs of the named module
+from the modules section of the configuration file.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := libinquirer.ParseConfigFile(cfgFile)
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to parse configuration file")
+			return
+		}
+
+		poller := libinquirer.NewPoller(serveWorkers, serveHostTimeout, 0)
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(libinquirer.NewPrometheusCollector(poller, conf.Poll, conf.MIB))
+
+		http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+			ErrorHandling: promhttp.ContinueOnError,
+		}))
+		http.Handle("/snmp", libinquirer.NewProbeHandler(poller, conf.Modules, conf.MIB))
+
+		logrus.WithField("listen", serveListen).Infoln("Serving Prometheus metrics")
+		if err = http.ListenAndServe(serveListen, nil); err != nil {
+			logrus.WithError(err).Errorln("HTTP server stopped")
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVarP(&serveListen, "listen", "l", ":9116", "Address to serve HTTP requests on")
+	serveCmd.Flags().IntVarP(&serveWorkers, "workers", "w", 64, "Number of hosts to poll concurrently")
+	serveCmd.Flags().DurationVar(&serveHostTimeout, "host-timeout", 10*time.Second, "Deadline for polling all OIDs of a single host")
+}
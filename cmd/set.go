@@ -0,0 +1,210 @@
+// Copyright © 2017 Kevin Kirsche <kev.kirsche[at]gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer"
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	setHosts         []string
+	setAllConfigured bool
+	setBatch         string
+	setDryRun        bool
+	setYes           bool
+)
+
+// setCmd represents the set command
+var setCmd = &cobra.Command{
+	Use:   "set [OID TYPE VALUE]...",
+	Short: "Set OIDs on one or more hosts",
+	Long: `Set sends typed varbinds to one or more hosts, in the same way as snmpset.
+Varbinds are provided as arguments in groups of OID, type and value, or read
+from a batch file with one varbind per line. Types are integer (i), unsigned
+(u), counter32 (c), counter64 (C), timeticks (t), ipaddress (a), oid (o),
+string (s) and hex (x), or = to use the type of the object in the MIB.
+Integers may be given as enumeration labels, such as
+
+  inquirer2 set -H 192.0.2.1 IF-MIB::ifAdminStatus.3 = down
+
+Hosts are provided using --host and the connection flags. To set the varbinds
+on every host in the poll section of the configuration file instead, each
+with its own credentials, --all-configured must be provided.
+
+A dry run reads the current value of each varbind instead of setting it.
+Otherwise, confirmation is requested unless --yes is provided. The outcome of
+each host is logged, including the error-status and error-index of failed
+requests.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := queryConfiguration()
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to parse configuration file")
+			return
+		}
+
+		if setBatch != "" && len(args) > 0 {
+			logrus.Errorln("Varbinds may be provided as arguments or using --batch, but not both")
+			return
+		}
+		if len(setHosts) > 0 && setAllConfigured {
+			logrus.Errorln("Hosts may be provided using --host or --all-configured, but not both")
+			return
+		}
+
+		var pdus []gosnmp.SnmpPDU
+		if setBatch != "" {
+			pdus, err = parseBatchFile(conf.MIB, setBatch)
+		} else {
+			pdus, err = libinquirer.ParseVarbinds(conf.MIB, args)
+		}
+		if err != nil {
+			logrus.WithError(err).Errorln("Invalid varbinds")
+			return
+		}
+
+		hosts := setHosts
+		if setAllConfigured {
+			for _, cfg := range conf.Poll {
+				hosts = append(hosts, cfg.Host)
+			}
+		}
+		if len(hosts) == 0 {
+			logrus.Errorln("No hosts were provided using --host, or --all-configured with hosts in the configuration file")
+			return
+		}
+
+		if !setDryRun && !setYes && !confirm(fmt.Sprintf("Set %d varbinds on %d hosts?", len(pdus), len(hosts))) {
+			logrus.Infoln("Set cancelled")
+			return
+		}
+
+		failed := 0
+		for i, host := range hosts {
+			var client *gosnmp.GoSNMP
+			if len(setHosts) > 0 {
+				client, err = connectClient(host)
+			} else {
+				client, err = libinquirer.CreateClientFromConfig(&conf.Poll[i])
+				if err == nil {
+					err = libinquirer.ConnectClient(client)
+				}
+			}
+			if err != nil {
+				logrus.WithError(err).WithField("host", host).Errorln("Failed to create SNMP client")
+				failed++
+				continue
+			}
+
+			result := libinquirer.Set(client, pdus, setDryRun)
+			libinquirer.LogEngine(client)
+			client.Conn.Close()
+			if result.Err != nil {
+				failed++
+			}
+			logSetResult(result, conf.MIB)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"hosts":   len(hosts),
+			"failed":  failed,
+			"dry_run": setDryRun,
+		}).Infoln("Set complete")
+	},
+}
+
+func parseBatchFile(m *mib.MIB, path string) ([]gosnmp.SnmpPDU, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return libinquirer.ParseBatch(m, f)
+}
+
+// confirm asks a yes or no question on stdin, defaulting to no
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+// logSetResult logs the outcome of a set on a single host, rendering values
+// using the MIB
+func logSetResult(result *libinquirer.SetResult, m *mib.MIB) {
+	logger := logrus.WithFields(logrus.Fields{
+		"host":    result.Host,
+		"dry_run": result.DryRun,
+	})
+
+	if result.Err != nil {
+		logger = logger.WithError(result.Err)
+		if result.ErrorStatus != gosnmp.NoError {
+			logger = logger.WithFields(logrus.Fields{
+				"error_status": result.ErrorStatus.String(),
+				"error_index":  result.ErrorIndex,
+			})
+		}
+		logger.Errorln("Failed to set varbinds")
+		return
+	}
+
+	msg := "Varbind set"
+	if result.DryRun {
+		msg = "Varbind would be set"
+	}
+
+	for i, pdu := range result.Requested {
+		fields := logrus.Fields{
+			"oid":   pdu.Name,
+			"name":  m.Name(pdu.Name),
+			"value": setValue(m, pdu),
+		}
+		if result.DryRun && i < len(result.Current) {
+			fields["current"] = setValue(m, result.Current[i])
+		}
+		logger.WithFields(fields).Infoln(msg)
+	}
+}
+
+func setValue(m *mib.MIB, pdu gosnmp.SnmpPDU) interface{} {
+	if display := libinquirer.DisplayValue(m, pdu); display != "" {
+		return display
+	}
+
+	return libinquirer.ReadableValue(pdu.Type, libinquirer.DecodeValue(pdu))
+}
+
+func init() {
+	RootCmd.AddCommand(setCmd)
+
+	setCmd.Flags().StringSliceVarP(&setHosts, "host", "H", nil, "Host to set the varbinds on, may be repeated")
+	setCmd.Flags().BoolVar(&setAllConfigured, "all-configured", false, "Set the varbinds on every host in the poll section of the config file")
+	setCmd.Flags().StringVarP(&setBatch, "batch", "b", "", "File of varbinds, one OID TYPE VALUE per line")
+	setCmd.Flags().BoolVarP(&setDryRun, "dry-run", "n", false, "Read the current value of each varbind instead of setting it")
+	setCmd.Flags().BoolVarP(&setYes, "yes", "y", false, "Set the varbinds without asking for confirmation")
+	addClientFlags(setCmd)
+}
@@ -0,0 +1,95 @@
+// Copyright © 2017 Kevin Kirsche <kev.kirsche[at]gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var relayListen string
+
+// trapRelayCmd represents the trap-relay command
+var trapRelayCmd = &cobra.Command{
+	Use:   "trap-relay",
+	Short: "Filter SNMP traps and forward them to other receivers",
+	Long: `Trap-relay listens for SNMP notifications in the same way as the traps
+command, and applies the rules in the relay section of the configuration file
+to each one. Rules match on the host a trap was received from, its trap OID
+and the values of its variables, and are evaluated in order until one matches.
+
+A matching rule may drop the trap, such as for noisy link flaps, drop repeats
+of the trap within a window of seconds, rewrite the variable named by
+severity_oid, and forward the trap to destinations. Destinations are other
+receivers, which are sent v2c traps, or webhooks, which are posted the trap as
+JSON. Traps matching no rule are not forwarded.
+
+Each destination forwards traps from its own queue of queue_size traps, so a
+slow destination does not delay the reception of traps or other destinations.
+Traps are dropped and logged while the queue of a destination is full.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := libinquirer.ParseConfigFile(cfgFile)
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to parse configuration file")
+			return
+		}
+
+		if relayListen != "" {
+			conf.Traps.Listen = relayListen
+		}
+
+		relay, err := libinquirer.NewRelay(conf.Relay, conf.MIB)
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to create trap relay")
+			return
+		}
+		defer relay.Close()
+
+		receiver, err := libinquirer.NewTrapReceiver(conf.Traps, conf.MIB)
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to start trap receiver")
+			return
+		}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			logrus.WithField("signal", sig.String()).Infoln("Shutting down trap relay")
+			receiver.Close()
+		}()
+
+		logrus.WithField("listen", receiver.Addr().String()).Infoln("Relaying SNMP notifications")
+		err = receiver.Serve(func(trap *libinquirer.Trap) {
+			if _, err := relay.Process(trap); err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{"host": trap.Host, "trap_oid": trap.OID}).Errorln("Failed to relay trap")
+			}
+		})
+		if err != nil {
+			logrus.WithError(err).Errorln("Trap receiver stopped")
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(trapRelayCmd)
+
+	trapRelayCmd.Flags().StringVarP(&relayListen, "listen", "l", "", "UDP address to receive notifications on, overriding the configuration file (default :162)")
+}
@@ -0,0 +1,93 @@
+// Copyright © 2017 Kevin Kirsche <kev.kirsche[at]gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var trapsListen string
+
+// trapsCmd represents the traps command
+var trapsCmd = &cobra.Command{
+	Use:   "traps",
+	Short: "Receive SNMP traps and informs",
+	Long: `Traps listens for SNMP notifications and writes each one to the
+configured outputs, in the same way as poll results. Variables are named and
+rendered using the MIBs in mib_dirs.
+
+v1 traps, v2c traps and informs are accepted from agents using one of the
+communities in the traps section of the configuration file, or from any agent
+when no communities are configured. Informs are acknowledged.
+
+v3 traps and informs are authenticated using the users in the traps section,
+which take the same fields as v3 poll configurations. Agents sending v3
+informs discover the engine ID of the receiver, which may be set as engine_id.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := libinquirer.ParseConfigFile(cfgFile)
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to parse configuration file")
+			return
+		}
+
+		if trapsListen != "" {
+			conf.Traps.Listen = trapsListen
+		}
+
+		sink, err := createSink(conf)
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to create output")
+			return
+		}
+		defer sink.Close()
+
+		receiver, err := libinquirer.NewTrapReceiver(conf.Traps, conf.MIB)
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to start trap receiver")
+			return
+		}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			logrus.WithField("signal", sig.String()).Infoln("Shutting down trap receiver")
+			receiver.Close()
+		}()
+
+		logrus.WithField("listen", receiver.Addr().String()).Infoln("Receiving SNMP notifications")
+		err = receiver.Serve(func(trap *libinquirer.Trap) {
+			if err := sink.WriteTraps([]libinquirer.Trap{*trap}); err != nil {
+				logrus.WithError(err).WithField("host", trap.Host).Errorln("Failed to output trap")
+			}
+		})
+		if err != nil {
+			logrus.WithError(err).Errorln("Trap receiver stopped")
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(trapsCmd)
+
+	trapsCmd.Flags().StringVarP(&trapsListen, "listen", "l", "", "UDP address to receive notifications on, overriding the configuration file (default :162)")
+	addOutputFlags(trapsCmd)
+}
@@ -0,0 +1,80 @@
+// Copyright © 2017 Kevin Kirsche <kev.kirsche[at]gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// walkCmd represents the walk command
+var walkCmd = &cobra.Command{
+	Use:   "walk HOST OID",
+	Short: "Walk an OID of a single host",
+	Long: `Walk retrieves every OID below an OID of a host, in the same way as
+snmpwalk. Bulk requests are used except for v1. The OID may be numeric or a
+name from the MIBs in mib_dirs, such as IF-MIB::ifXTable.
+
+Values are decoded and written to the outputs in the same way as poll results.
+The configuration file is only used for its MIB directories and outputs.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := queryConfiguration()
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to parse configuration file")
+			return
+		}
+		host := args[0]
+
+		oids, names, err := resolveArgs(conf.MIB, args[1:])
+		if err != nil {
+			logrus.WithError(err).Errorln("Invalid OID")
+			return
+		}
+
+		sink, err := createSink(conf)
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to create output")
+			return
+		}
+		defer sink.Close()
+
+		client, err := connectClient(host)
+		if err != nil {
+			logrus.WithError(err).WithField("host", host).Errorln("Failed to create SNMP client")
+			return
+		}
+		defer client.Conn.Close()
+
+		started := time.Now()
+		walk := libinquirer.Walk(client, oids[0], names[0])
+		libinquirer.LogEngine(client)
+		outputHostResult(&libinquirer.HostResult{
+			Config:   libinquirer.PollConfiguration{Host: host},
+			Walks:    []libinquirer.WalkResult{walk},
+			Started:  started,
+			Duration: time.Since(started),
+		}, sink, conf.MIB)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(walkCmd)
+	addClientFlags(walkCmd)
+	addOutputFlags(walkCmd)
+}
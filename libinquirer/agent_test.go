@@ -0,0 +1,237 @@
+package libinquirer
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soniah/gosnmp"
+)
+
+// testAgent is a minimal SNMP v1/v2c agent answering get, get-next, get-bulk
+// and set requests from an in-memory MIB view. Requests using a community
+// other than the agent's are ignored once one is set
+type testAgent struct {
+	sync.Mutex
+	conn      *net.UDPConn
+	pdus      []gosnmp.SnmpPDU
+	delay     time.Duration
+	community string
+	readOnly  map[string]bool
+}
+
+func startTestAgent(t *testing.T, pdus []gosnmp.SnmpPDU) *testAgent {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(localhost)})
+	if err != nil {
+		t.Fatalf("Failed to start test agent: %s", err)
+	}
+
+	a := &testAgent{conn: conn}
+	a.set(pdus)
+	go a.serve()
+
+	return a
+}
+
+func (a *testAgent) Port() uint16 {
+	return uint16(a.conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func (a *testAgent) Close() {
+	a.conn.Close()
+}
+
+func (a *testAgent) setDelay(d time.Duration) {
+	a.Lock()
+	defer a.Unlock()
+	a.delay = d
+}
+
+func (a *testAgent) setCommunity(c string) {
+	a.Lock()
+	defer a.Unlock()
+	a.community = c
+}
+
+// setReadOnly causes sets of the OIDs to fail with notWritable
+func (a *testAgent) setReadOnly(oids ...string) {
+	a.Lock()
+	defer a.Unlock()
+	a.readOnly = map[string]bool{}
+	for _, oid := range oids {
+		a.readOnly[oid] = true
+	}
+}
+
+func (a *testAgent) set(pdus []gosnmp.SnmpPDU) {
+	a.Lock()
+	defer a.Unlock()
+	for _, pdu := range pdus {
+		replaced := false
+		for i := range a.pdus {
+			if a.pdus[i].Name == pdu.Name {
+				a.pdus[i] = pdu
+				replaced = true
+			}
+		}
+		if !replaced {
+			a.pdus = append(a.pdus, pdu)
+		}
+	}
+	sort.Slice(a.pdus, func(i, j int) bool {
+		return compareTestOIDs(a.pdus[i].Name, a.pdus[j].Name) < 0
+	})
+}
+
+func (a *testAgent) serve() {
+	buf := make([]byte, 65535)
+	for {
+		n, remote, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		decoder := &gosnmp.GoSNMP{Version: gosnmp.Version2c}
+		req, err := decoder.SnmpDecodePacket(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		a.Lock()
+		community := a.community
+		a.Unlock()
+		if community != "" && req.Community != community {
+			continue
+		}
+
+		vars, status, index := a.answer(req)
+		resp := &gosnmp.SnmpPacket{
+			Version:    req.Version,
+			Community:  req.Community,
+			PDUType:    gosnmp.GetResponse,
+			RequestID:  req.RequestID,
+			Variables:  vars,
+			Error:      status,
+			ErrorIndex: index,
+		}
+		out, err := resp.MarshalMsg()
+		if err != nil {
+			continue
+		}
+
+		a.Lock()
+		delay := a.delay
+		a.Unlock()
+		time.Sleep(delay)
+		a.conn.WriteToUDP(out, remote)
+	}
+}
+
+func (a *testAgent) answer(req *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError, uint8) {
+	if req.PDUType == gosnmp.SetRequest {
+		a.Lock()
+		for i, v := range req.Variables {
+			if a.readOnly[v.Name] {
+				a.Unlock()
+				return req.Variables, gosnmp.NotWritable, uint8(i + 1)
+			}
+		}
+		a.Unlock()
+
+		a.set(req.Variables)
+		return req.Variables, gosnmp.NoError, 0
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	vars := []gosnmp.SnmpPDU{}
+	switch req.PDUType {
+	case gosnmp.GetRequest:
+		for _, v := range req.Variables {
+			vars = append(vars, a.get(v.Name))
+		}
+	case gosnmp.GetNextRequest:
+		for _, v := range req.Variables {
+			vars = append(vars, a.next(v.Name, 1)...)
+		}
+	case gosnmp.GetBulkRequest:
+		for _, v := range req.Variables {
+			vars = append(vars, a.next(v.Name, int(req.MaxRepetitions))...)
+		}
+	}
+
+	return vars, gosnmp.NoError, 0
+}
+
+func (a *testAgent) get(oid string) gosnmp.SnmpPDU {
+	for _, pdu := range a.pdus {
+		if pdu.Name == oid {
+			return pdu
+		}
+	}
+
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.NoSuchObject}
+}
+
+func (a *testAgent) next(oid string, max int) []gosnmp.SnmpPDU {
+	vars := []gosnmp.SnmpPDU{}
+	for _, pdu := range a.pdus {
+		if len(vars) >= max {
+			break
+		}
+		if compareTestOIDs(pdu.Name, oid) > 0 {
+			vars = append(vars, pdu)
+		}
+	}
+
+	if len(vars) == 0 {
+		vars = append(vars, gosnmp.SnmpPDU{Name: oid, Type: gosnmp.EndOfMibView})
+	}
+
+	return vars
+}
+
+func compareTestOIDs(a, b string) int {
+	as := strings.Split(strings.Trim(a, "."), ".")
+	bs := strings.Split(strings.Trim(b, "."), ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		ai, _ := strconv.Atoi(as[i])
+		bi, _ := strconv.Atoi(bs[i])
+		if ai != bi {
+			return ai - bi
+		}
+	}
+
+	return len(as) - len(bs)
+}
+
+func testAgentPDUs() []gosnmp.SnmpPDU {
+	return []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(1000)},
+		{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.OctetString, Value: []byte("test-agent")},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.1.1", Type: gosnmp.OctetString, Value: []byte("ge-0/0/0")},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.1.2", Type: gosnmp.OctetString, Value: []byte("ge-0/0/1")},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.6.1", Type: gosnmp.Counter64, Value: uint64(1000)},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.6.2", Type: gosnmp.Counter64, Value: uint64(2000)},
+	}
+}
+
+func testAgentConfig(port uint16) PollConfiguration {
+	return PollConfiguration{
+		Host:      localhost,
+		Port:      port,
+		Community: testCommunity,
+		Version:   v2,
+		Retries:   0,
+		OIDs: map[string]string{
+			".1.3.6.1.2.1.1.5.0":      "SNMPv2-MIB::sysName",
+			".1.3.6.1.2.1.31.1.1.1.1": "IF-MIB::ifName",
+			".1.3.6.1.2.1.31.1.1.1.6": "IF-MIB::ifHCInOctets",
+		},
+	}
+}
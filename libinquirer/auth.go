@@ -1,6 +1,9 @@
 package libinquirer
 
 import (
+	"encoding/hex"
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/soniah/gosnmp"
@@ -12,8 +15,16 @@ const (
 	authpriv     = "AuthPriv"
 	md5          = "MD5"
 	sha          = "SHA"
+	sha224       = "SHA224"
+	sha256       = "SHA256"
+	sha384       = "SHA384"
+	sha512       = "SHA512"
 	des          = "DES"
 	aes          = "AES"
+	aes192       = "AES192"
+	aes256       = "AES256"
+	aes192c      = "AES192C"
+	aes256c      = "AES256C"
 
 	securityLevel          = "security_level"
 	authenticationProtocol = "auth_proto"
@@ -21,24 +32,53 @@ const (
 )
 
 // SNMPAuth is used to collect the necessary data to authenticate over SNMP v3
-// connections
+// connections. The SHA-2 authentication protocols are those of RFC 7860. The
+// AES192 and AES256 privacy protocols extend short keys as described in
+// draft-blumenthal-aes-usm, while AES192C and AES256C use the extension of
+// draft-reeder-snmpv3-usm-3desede, which Cisco devices expect
 type SNMPAuth struct {
 	Username      string
 	SecurityLevel gosnmp.SnmpV3MsgFlags
-	AuthPassword  string
+	AuthPassword  Secret
 	AuthProtocol  gosnmp.SnmpV3AuthProtocol
-	PrivPassword  string
+	PrivPassword  Secret
 	PrivProtocol  gosnmp.SnmpV3PrivProtocol
+	// ContextName and ContextEngineID select the context of requests, such
+	// as a VLAN or VRF instance. The engine ID of the agent is used when
+	// ContextEngineID is empty
+	ContextName     string
+	ContextEngineID string
+	// AuthoritativeEngineID, AuthoritativeEngineBoots and
+	// AuthoritativeEngineTime are used instead of discovering the engine of
+	// the agent when the engine ID is set
+	AuthoritativeEngineID    string
+	AuthoritativeEngineBoots uint32
+	AuthoritativeEngineTime  uint32
+	// AuthKey and PrivKey are keys localized to AuthoritativeEngineID, used
+	// instead of deriving them from the passphrases
+	AuthKey []byte
+	PrivKey []byte
 }
 
 // auth is used when parsing the user's configuration file
 type auth struct {
-	Username      string `json:"username"`
-	SecurityLevel string `json:"security_level"`
-	AuthPassword  string `json:"auth_password"`
-	AuthProtocol  string `json:"auth_protocol"`
-	PrivPassword  string `json:"priv_password"`
-	PrivProtocol  string `json:"priv_protocol"`
+	Username      string `json:"username,omitempty"`
+	SecurityLevel string `json:"security_level,omitempty"`
+	AuthPassword  Secret `json:"auth_password,omitempty"`
+	AuthProtocol  string `json:"auth_protocol,omitempty"`
+	PrivPassword  Secret `json:"priv_password,omitempty"`
+	PrivProtocol  string `json:"priv_protocol,omitempty"`
+	// ContextEngineID and AuthoritativeEngineID are hex encoded
+	ContextName           string `json:"context_name,omitempty"`
+	ContextEngineID       string `json:"context_engine_id,omitempty"`
+	AuthoritativeEngineID string `json:"authoritative_engine_id,omitempty"`
+	EngineBoots           uint32 `json:"engine_boots,omitempty"`
+	EngineTime            uint32 `json:"engine_time,omitempty"`
+	// AuthKey and PrivKey are hex encoded keys localized to the
+	// authoritative engine ID, which may be configured instead of the
+	// passphrases
+	AuthKey Secret `json:"auth_key,omitempty"`
+	PrivKey Secret `json:"priv_key,omitempty"`
 }
 
 func retrieveSecurityLevel(s string) (gosnmp.SnmpV3MsgFlags, error) {
@@ -66,9 +106,21 @@ func retrieveAuthProto(a string) (gosnmp.SnmpV3AuthProtocol, error) {
 	case sha:
 		logrus.WithField(authenticationProtocol, sha).Debugln("Authentication protocol set")
 		return gosnmp.SHA, nil
+	case sha224:
+		logrus.WithField(authenticationProtocol, sha224).Debugln("Authentication protocol set")
+		return gosnmp.SHA224, nil
+	case sha256:
+		logrus.WithField(authenticationProtocol, sha256).Debugln("Authentication protocol set")
+		return gosnmp.SHA256, nil
+	case sha384:
+		logrus.WithField(authenticationProtocol, sha384).Debugln("Authentication protocol set")
+		return gosnmp.SHA384, nil
+	case sha512:
+		logrus.WithField(authenticationProtocol, sha512).Debugln("Authentication protocol set")
+		return gosnmp.SHA512, nil
 	default:
 		logrus.WithField(authenticationProtocol, a).Debugln("Invalid authentication protocol detected")
-		return gosnmp.SHA, errors.Errorf("Invalid auth protocol. Please select MD5 or SHA")
+		return gosnmp.SHA, errors.Errorf("Invalid auth protocol. Please select MD5, SHA, SHA224, SHA256, SHA384 or SHA512")
 	}
 }
 
@@ -80,9 +132,21 @@ func retrievePrivProto(p string) (gosnmp.SnmpV3PrivProtocol, error) {
 	case aes:
 		logrus.WithField(privateProtocol, aes).Debugln("Private communication protocol set")
 		return gosnmp.AES, nil
+	case aes192:
+		logrus.WithField(privateProtocol, aes192).Debugln("Private communication protocol set")
+		return gosnmp.AES192, nil
+	case aes256:
+		logrus.WithField(privateProtocol, aes256).Debugln("Private communication protocol set")
+		return gosnmp.AES256, nil
+	case aes192c:
+		logrus.WithField(privateProtocol, aes192c).Debugln("Private communication protocol set")
+		return gosnmp.AES192C, nil
+	case aes256c:
+		logrus.WithField(privateProtocol, aes256c).Debugln("Private communication protocol set")
+		return gosnmp.AES256C, nil
 	default:
 		logrus.WithField(privateProtocol, p).Debugln("Invalid authentication protocol detected")
-		return gosnmp.AES, errors.Errorf("Invalid private communication protocol. Please select DES or AES")
+		return gosnmp.AES, errors.Errorf("Invalid private communication protocol. Please select DES, AES, AES192, AES256, AES192C or AES256C")
 	}
 }
 
@@ -107,9 +171,73 @@ func NewAuth(u, s, apass, a, ppass, p string) (*SNMPAuth, error) {
 	return &SNMPAuth{
 		Username:      u,
 		SecurityLevel: sl,
-		AuthPassword:  apass,
+		AuthPassword:  Secret(apass),
 		AuthProtocol:  aproto,
-		PrivPassword:  ppass,
+		PrivPassword:  Secret(ppass),
 		PrivProtocol:  pproto,
 	}, nil
 }
+
+// newAuthFromConfig creates the authentication object of a configured user
+func newAuthFromConfig(a auth) (*SNMPAuth, error) {
+	sa, err := NewAuth(a.Username, a.SecurityLevel, a.AuthPassword.Reveal(), a.AuthProtocol, a.PrivPassword.Reveal(), a.PrivProtocol)
+	if err != nil {
+		return nil, err
+	}
+
+	sa.ContextName = a.ContextName
+	if sa.ContextEngineID, err = DecodeEngineID(a.ContextEngineID); err != nil {
+		return nil, errors.Wrap(err, "invalid context engine ID")
+	}
+	if sa.AuthoritativeEngineID, err = DecodeEngineID(a.AuthoritativeEngineID); err != nil {
+		return nil, errors.Wrap(err, "invalid authoritative engine ID")
+	}
+	if sa.AuthoritativeEngineID == "" && (a.EngineBoots != 0 || a.EngineTime != 0) {
+		return nil, errors.New("engine boots and time require an authoritative engine ID")
+	}
+	sa.AuthoritativeEngineBoots = a.EngineBoots
+	sa.AuthoritativeEngineTime = a.EngineTime
+
+	if a.AuthKey == "" && a.PrivKey == "" {
+		return sa, nil
+	}
+	if sa.AuthoritativeEngineID == "" {
+		return nil, errors.New("localized keys require an authoritative engine ID")
+	}
+	if a.AuthKey != "" {
+		hash, _ := authHash(sa.AuthProtocol)
+		if sa.AuthKey, err = decodeHex(a.AuthKey.Reveal()); err != nil || len(sa.AuthKey) != hash.Size() {
+			return nil, errors.Errorf("invalid authentication key, it must be %d hex encoded octets", hash.Size())
+		}
+	}
+	if a.PrivKey != "" {
+		length := privKeyLength(sa.PrivProtocol)
+		if sa.PrivKey, err = decodeHex(a.PrivKey.Reveal()); err != nil || len(sa.PrivKey) != length {
+			return nil, errors.Errorf("invalid privacy key, it must be %d hex encoded octets", length)
+		}
+	}
+
+	return sa, nil
+}
+
+// DecodeEngineID decodes a hex encoded engine ID, which may start with 0x and
+// separate its octets with colons. An empty string decodes to an empty engine
+// ID
+func DecodeEngineID(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	id, err := decodeHex(s)
+	if err != nil || len(id) < 5 || len(id) > 32 {
+		return "", errors.Errorf("invalid engine ID %s, it must be 5 to 32 hex encoded octets", s)
+	}
+
+	return string(id), nil
+}
+
+// decodeHex decodes a hex string which may start with 0x and separate its
+// octets with colons
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.Replace(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"), ":", "", -1))
+}
@@ -1,9 +1,19 @@
 package libinquirer
 
 import (
+	"bytes"
+	"encoding/hex"
 	"testing"
 
 	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+// The passphrase and engine ID of the key localization examples of RFC 3414
+// section A.3
+const (
+	testUSMPassphrase = "maplesyrup"
+	testUSMEngineID   = "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02"
 )
 
 func TestValidNoAuthNoPrivSecurityLevel(t *testing.T) {
@@ -54,6 +64,23 @@ func TestValidSHARetrieveAuthProto(t *testing.T) {
 	}
 }
 
+func TestValidSHA2RetrieveAuthProto(t *testing.T) {
+	expected := map[string]gosnmp.SnmpV3AuthProtocol{
+		sha224: gosnmp.SHA224,
+		sha256: gosnmp.SHA256,
+		sha384: gosnmp.SHA384,
+		sha512: gosnmp.SHA512,
+	}
+
+	for name, proto := range expected {
+		p, err := retrieveAuthProto(name)
+		if err != nil || p != proto {
+			logrus.WithError(err).WithField(authenticationProtocol, name).Errorln("Failed to correctly set SHA-2 authentication protocol")
+			t.Fail()
+		}
+	}
+}
+
 func TestInvalidAuthProto(t *testing.T) {
 	_, err := retrieveAuthProto(invalid)
 	if err == nil {
@@ -78,6 +105,23 @@ func TestValidAESRetrievePrivProto(t *testing.T) {
 	}
 }
 
+func TestValidAESVariantRetrievePrivProto(t *testing.T) {
+	expected := map[string]gosnmp.SnmpV3PrivProtocol{
+		aes192:  gosnmp.AES192,
+		aes256:  gosnmp.AES256,
+		aes192c: gosnmp.AES192C,
+		aes256c: gosnmp.AES256C,
+	}
+
+	for name, proto := range expected {
+		p, err := retrievePrivProto(name)
+		if err != nil || p != proto {
+			logrus.WithError(err).WithField(privateProtocol, name).Errorln("Failed to correctly set AES private communication protocol")
+			t.Fail()
+		}
+	}
+}
+
 func TestInvalidPrivProto(t *testing.T) {
 	_, err := retrievePrivProto(invalid)
 	if err == nil {
@@ -117,3 +161,207 @@ func TestInvalidNewAuthStructurePrivProto(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestValidNewAuthStructureSHA2(t *testing.T) {
+	a, err := NewAuth("test_user", authpriv, "test_auth_pass", sha512, "test_priv_pass", aes256c)
+	if err != nil || a.AuthProtocol != gosnmp.SHA512 || a.PrivProtocol != gosnmp.AES256C {
+		logrus.WithError(err).Errorln("Failed to correctly set SHA-512 and AES-256 protocols")
+		t.Fail()
+	}
+}
+
+func TestPasswordToKey(t *testing.T) {
+	// RFC 3414 sections A.3.1 and A.3.2
+	expected := map[gosnmp.SnmpV3AuthProtocol]string{
+		gosnmp.MD5: "9faf3283884e92834ebc9847d8edd963",
+		gosnmp.SHA: "9fb5cc0381497b3793528939ff788d5d79145211",
+	}
+
+	for proto, key := range expected {
+		ku, err := PasswordToKey(proto, testUSMPassphrase)
+		if err != nil || hex.EncodeToString(ku) != key {
+			logrus.WithError(err).WithField("key", hex.EncodeToString(ku)).Errorln("Passphrase was converted to an invalid key")
+			t.Fail()
+		}
+	}
+
+	if _, err := PasswordToKey(gosnmp.SHA, ""); err == nil {
+		logrus.Errorln("Empty passphrase was converted to a key")
+		t.Fail()
+	}
+}
+
+func TestLocalizedAuthKey(t *testing.T) {
+	// The MD5 and SHA keys are those of RFC 3414 sections A.3.1 and A.3.2.
+	// RFC 7860 applies the same algorithm to the SHA-2 hashes without
+	// publishing examples, so the SHA-2 keys localize the same passphrase
+	expected := map[gosnmp.SnmpV3AuthProtocol]string{
+		gosnmp.MD5:    "526f5eed9fcce26f8964c2930787d82b",
+		gosnmp.SHA:    "6695febc9288e36282235fc7151f128497b38f3f",
+		gosnmp.SHA224: "0bd8827c6e29f8065e08e09237f177e410f69b90e1782be682075674",
+		gosnmp.SHA256: "8982e0e549e866db361a6b625d84cccc11162d453ee8ce3a6445c2d6776f0f8b",
+		gosnmp.SHA384: "3b298f16164a11184279d5432bf169e2d2a48307de02b3d3f7e2b4f36eb6f0455a53689a3937eea07319a633d2ccba78",
+		gosnmp.SHA512: "22a5a36cedfcc085807a128d7bc6c2382167ad6c0dbc5fdff856740f3d84c099ad1ea87a8db096714d9788bd544047c9021e4229ce27e4c0a69250adfcffbb0b",
+	}
+
+	for proto, key := range expected {
+		kul, err := LocalizedAuthKey(proto, testUSMPassphrase, testUSMEngineID)
+		if err != nil || hex.EncodeToString(kul) != key {
+			logrus.WithError(err).WithFields(logrus.Fields{"protocol": proto, "key": hex.EncodeToString(kul)}).Errorln("Passphrase was localized to an invalid key")
+			t.Fail()
+		}
+	}
+
+	if _, err := LocalizedAuthKey(gosnmp.NoAuth, testUSMPassphrase, testUSMEngineID); err == nil {
+		logrus.Errorln("Key was localized without an authentication protocol")
+		t.Fail()
+	}
+}
+
+func TestLocalizedPrivKey(t *testing.T) {
+	tests := []struct {
+		priv gosnmp.SnmpV3PrivProtocol
+		auth gosnmp.SnmpV3AuthProtocol
+		key  string
+	}{
+		// Keys long enough for the protocol are truncated
+		{gosnmp.AES, gosnmp.SHA, "6695febc9288e36282235fc7151f1284"},
+		{gosnmp.AES256, gosnmp.SHA256, "8982e0e549e866db361a6b625d84cccc11162d453ee8ce3a6445c2d6776f0f8b"},
+		{gosnmp.AES256C, gosnmp.SHA512, "22a5a36cedfcc085807a128d7bc6c2382167ad6c0dbc5fdff856740f3d84c099"},
+		// Blumenthal extension, appending the hash of the key
+		{gosnmp.AES192, gosnmp.MD5, "526f5eed9fcce26f8964c2930787d82bfa24a92467426c2f"},
+		{gosnmp.AES256, gosnmp.SHA, "6695febc9288e36282235fc7151f128497b38f3f505e07eb9af25568fa1f5dbe"},
+		// Reeder extension, localizing the key as a passphrase
+		{gosnmp.AES192C, gosnmp.MD5, "526f5eed9fcce26f8964c2930787d82b79eff44a90650ee0"},
+		{gosnmp.AES256C, gosnmp.SHA, "6695febc9288e36282235fc7151f128497b38f3f9b8b6d78936ba6e7d19dfd9c"},
+	}
+
+	for _, test := range tests {
+		key, err := LocalizedPrivKey(test.priv, test.auth, testUSMPassphrase, testUSMEngineID)
+		if err != nil || hex.EncodeToString(key) != test.key {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"priv": test.priv,
+				"auth": test.auth,
+				"key":  hex.EncodeToString(key),
+			}).Errorln("Privacy key was extended incorrectly")
+			t.Fail()
+		}
+	}
+}
+
+func TestDecodeEngineID(t *testing.T) {
+	for _, s := range []string{"000000000000000000000002", "0x000000000000000000000002", "00:00:00:00:00:00:00:00:00:00:00:02"} {
+		id, err := DecodeEngineID(s)
+		if err != nil || id != testUSMEngineID {
+			logrus.WithError(err).WithField("engine_id", s).Errorln("Engine ID was decoded incorrectly")
+			t.Fail()
+		}
+	}
+
+	if id, err := DecodeEngineID(""); err != nil || id != "" {
+		logrus.WithError(err).Errorln("Empty engine ID was not accepted")
+		t.Fail()
+	}
+
+	for _, s := range []string{invalid, "00000002", "0x" + hex.EncodeToString(make([]byte, 33))} {
+		if _, err := DecodeEngineID(s); err == nil {
+			logrus.WithField("engine_id", s).Errorln("Invalid engine ID was accepted")
+			t.Fail()
+		}
+	}
+}
+
+func TestNewAuthFromConfigEngine(t *testing.T) {
+	a := auth{
+		Username:              "user",
+		SecurityLevel:         authnopriv,
+		AuthProtocol:          sha,
+		AuthPassword:          testUSMPassphrase,
+		PrivProtocol:          aes,
+		ContextName:           "vlan-10",
+		ContextEngineID:       "80:00:00:09:03:00:1a:2b:3c:4d:5e",
+		AuthoritativeEngineID: "000000000000000000000002",
+		EngineBoots:           4,
+		EngineTime:            3600,
+	}
+
+	sa, err := newAuthFromConfig(a)
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to create authentication object")
+		t.FailNow()
+	}
+	if sa.ContextName != "vlan-10" || sa.ContextEngineID != "\x80\x00\x00\x09\x03\x00\x1a\x2b\x3c\x4d\x5e" ||
+		sa.AuthoritativeEngineID != testUSMEngineID || sa.AuthoritativeEngineBoots != 4 || sa.AuthoritativeEngineTime != 3600 {
+		logrus.WithField("auth", sa).Errorln("Context and engine were not configured")
+		t.Fail()
+	}
+
+	a.AuthoritativeEngineID = ""
+	if _, err := newAuthFromConfig(a); err == nil {
+		logrus.Errorln("Engine boots and time were accepted without an engine ID")
+		t.Fail()
+	}
+
+	a.EngineBoots, a.EngineTime, a.ContextEngineID = 0, 0, invalid
+	if _, err := newAuthFromConfig(a); err == nil {
+		logrus.Errorln("Invalid context engine ID was accepted")
+		t.Fail()
+	}
+}
+
+func TestKeyCache(t *testing.T) {
+	cache := NewKeyCache()
+	expected, _ := LocalizedAuthKey(gosnmp.SHA, testUSMPassphrase, testUSMEngineID)
+
+	for i := 0; i < 2; i++ {
+		key, err := cache.AuthKey(gosnmp.SHA, testUSMPassphrase, testUSMEngineID)
+		if err != nil || !bytes.Equal(key, expected) || cache.Len() != 1 {
+			logrus.WithError(err).WithField("keys", cache.Len()).Errorln("Cached authentication key is invalid")
+			t.Fail()
+		}
+	}
+
+	// Keys of other engines, protocols and passphrases are cached separately
+	cache.AuthKey(gosnmp.SHA, testUSMPassphrase, "\x80\x00\x00\x00\x05agent")
+	cache.AuthKey(gosnmp.MD5, testUSMPassphrase, testUSMEngineID)
+	cache.AuthKey(gosnmp.SHA, "other-passphrase", testUSMEngineID)
+	key, err := cache.PrivKey(gosnmp.AES256, gosnmp.SHA, testUSMPassphrase, testUSMEngineID)
+	if err != nil || hex.EncodeToString(key) != "6695febc9288e36282235fc7151f128497b38f3f505e07eb9af25568fa1f5dbe" || cache.Len() != 5 {
+		logrus.WithError(err).WithField("keys", cache.Len()).Errorln("Keys were not cached separately")
+		t.Fail()
+	}
+
+	if _, err := cache.AuthKey(gosnmp.SHA, "", testUSMEngineID); err == nil || cache.Len() != 5 {
+		logrus.Errorln("Key of an empty passphrase was cached")
+		t.Fail()
+	}
+}
+
+func TestNewAuthFromConfigKeys(t *testing.T) {
+	a := auth{
+		Username:              "user",
+		SecurityLevel:         authpriv,
+		AuthProtocol:          sha,
+		PrivProtocol:          aes,
+		AuthoritativeEngineID: "000000000000000000000002",
+		AuthKey:               "6695febc9288e36282235fc7151f128497b38f3f",
+		PrivKey:               "0x6695febc9288e36282235fc7151f1284",
+	}
+
+	sa, err := newAuthFromConfig(a)
+	if err != nil || hex.EncodeToString(sa.AuthKey) != "6695febc9288e36282235fc7151f128497b38f3f" || len(sa.PrivKey) != 16 {
+		logrus.WithError(err).Errorln("Localized keys were not configured")
+		t.FailNow()
+	}
+
+	invalidKeys := []auth{a, a, a}
+	invalidKeys[0].AuthoritativeEngineID = ""
+	invalidKeys[1].AuthKey = "6695febc9288e36282235fc7151f1284"
+	invalidKeys[2].PrivKey = invalid
+	for _, k := range invalidKeys {
+		if _, err := newAuthFromConfig(k); err == nil {
+			logrus.WithField("auth", k).Errorln("Invalid localized keys were accepted")
+			t.Fail()
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package libinquirer
 
 import (
+	"encoding/hex"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/soniah/gosnmp"
 )
@@ -40,23 +42,154 @@ func CreateClient(a, c string, r int, vers *SNMPVersion, auth *SNMPAuth) (*gosnm
 		return params, nil
 	}
 
+	usm := &gosnmp.UsmSecurityParameters{
+		UserName:                 auth.Username,
+		AuthenticationProtocol:   auth.AuthProtocol,
+		AuthenticationPassphrase: auth.AuthPassword.Reveal(),
+		PrivacyProtocol:          auth.PrivProtocol,
+		PrivacyPassphrase:        auth.PrivPassword.Reveal(),
+		AuthoritativeEngineID:    auth.AuthoritativeEngineID,
+		AuthoritativeEngineBoots: auth.AuthoritativeEngineBoots,
+		AuthoritativeEngineTime:  auth.AuthoritativeEngineTime,
+		SecretKey:                auth.AuthKey,
+		PrivacyKey:               auth.PrivKey,
+	}
+	if err := localizeKeys(auth.SecurityLevel, usm, DefaultKeyCache); err != nil {
+		return nil, err
+	}
+
 	params := &gosnmp.GoSNMP{
-		Target:        a,
-		Port:          161,
-		Version:       v,
-		Timeout:       time.Duration(30) * time.Second,
-		SecurityModel: gosnmp.UserSecurityModel,
-		Community:     c,
-		Retries:       r,
-		MsgFlags:      auth.SecurityLevel,
-		SecurityParameters: &gosnmp.UsmSecurityParameters{
-			UserName:                 auth.Username,
-			AuthenticationProtocol:   auth.AuthProtocol,
-			AuthenticationPassphrase: auth.AuthPassword,
-			PrivacyProtocol:          auth.PrivProtocol,
-			PrivacyPassphrase:        auth.PrivPassword,
-		},
+		Target:             a,
+		Port:               161,
+		Version:            v,
+		Timeout:            time.Duration(30) * time.Second,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		Community:          c,
+		Retries:            r,
+		MsgFlags:           auth.SecurityLevel,
+		ContextName:        auth.ContextName,
+		ContextEngineID:    auth.ContextEngineID,
+		SecurityParameters: usm,
 	}
 
 	return params, nil
 }
+
+// CreateClientFromConfig is used to generate a SNMP client for the host
+// described by a single poll configuration entry
+func CreateClientFromConfig(cfg *PollConfiguration) (*gosnmp.GoSNMP, error) {
+	sv := NewVersion(cfg.Version)
+
+	var auth *SNMPAuth
+	if sv.Get() == v3 {
+		var err error
+		auth, err = newAuthFromConfig(cfg.auth)
+		if err != nil {
+			logrus.WithError(err).Debugln("Failed to create SNMP V3 authentication object")
+			return nil, err
+		}
+	}
+
+	client, err := CreateClient(cfg.Host, cfg.Community.Reveal(), cfg.Retries, sv, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Port != 0 {
+		client.Port = cfg.Port
+	}
+
+	return client, nil
+}
+
+// ConnectClient opens the connection of a client. The engine of a v3 agent
+// is discovered when it was not configured, so that the keys of the client
+// are localized using DefaultKeyCache rather than derived for every client
+func ConnectClient(client *gosnmp.GoSNMP) error {
+	if err := client.Connect(); err != nil {
+		return err
+	}
+
+	usm, ok := client.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if client.Version != gosnmp.Version3 || !ok || usm.AuthoritativeEngineID != "" {
+		return nil
+	}
+
+	if err := discoverEngine(client, usm); err != nil {
+		client.Conn.Close()
+		return errors.Wrap(err, "failed to discover SNMP engine")
+	}
+
+	return localizeKeys(client.MsgFlags, usm, DefaultKeyCache)
+}
+
+// discoverEngine sends the unauthenticated request of RFC 3414 section 4 to
+// learn the engine ID, boots and time of an agent from its report
+func discoverEngine(client *gosnmp.GoSNMP, usm *gosnmp.UsmSecurityParameters) error {
+	discovery := &gosnmp.GoSNMP{
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgFlags:           gosnmp.NoAuthNoPriv | gosnmp.Reportable,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{UserName: usm.UserName},
+	}
+	request, err := discovery.SnmpEncodePacket(gosnmp.GetRequest, nil, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 65535)
+	err = errors.New("no report was received")
+	for attempt := 0; attempt <= client.Retries; attempt++ {
+		if _, err = client.Conn.Write(request); err != nil {
+			return err
+		}
+		if err = client.Conn.SetReadDeadline(time.Now().Add(client.Timeout)); err != nil {
+			return err
+		}
+
+		var n int
+		if n, err = client.Conn.Read(buf); err != nil {
+			continue
+		}
+
+		var report *gosnmp.SnmpPacket
+		if report, err = discovery.SnmpDecodePacket(buf[:n]); err != nil {
+			continue
+		}
+		engine, ok := report.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		if !ok || engine.AuthoritativeEngineID == "" {
+			err = errors.New("report does not contain an engine ID")
+			continue
+		}
+
+		usm.AuthoritativeEngineID = engine.AuthoritativeEngineID
+		usm.AuthoritativeEngineBoots = engine.AuthoritativeEngineBoots
+		usm.AuthoritativeEngineTime = engine.AuthoritativeEngineTime
+		if client.ContextEngineID == "" {
+			client.ContextEngineID = engine.AuthoritativeEngineID
+		}
+
+		return nil
+	}
+
+	return err
+}
+
+// LogEngine logs the engine of the agent a v3 client has discovered, or was
+// configured with, at debug level. The boots and time of the engine are
+// updated by responses, so it should be called after a request is answered
+func LogEngine(client *gosnmp.GoSNMP) {
+	usm, ok := client.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if client.Version != gosnmp.Version3 || !ok {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"host":                    client.Target,
+		"authoritative_engine_id": hex.EncodeToString([]byte(usm.AuthoritativeEngineID)),
+		"engine_boots":            usm.AuthoritativeEngineBoots,
+		"engine_time":             usm.AuthoritativeEngineTime,
+		"context_engine_id":       hex.EncodeToString([]byte(client.ContextEngineID)),
+		"context_name":            client.ContextName,
+	}).Debugln("SNMP engine discovered")
+}
@@ -1,9 +1,13 @@
 package libinquirer
 
 import (
+	"bytes"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
 )
 
 // Valid Client Configurations
@@ -44,3 +48,121 @@ func TestCreateInvalidSNMPClient(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestCreateV3SNMPClientEngine(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(localhost)})
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to listen for requests")
+		t.FailNow()
+	}
+	defer conn.Close()
+
+	cfg := &PollConfiguration{
+		Host:    localhost,
+		Version: v3,
+		Port:    uint16(conn.LocalAddr().(*net.UDPAddr).Port),
+		auth: auth{
+			Username:              "user",
+			SecurityLevel:         authnopriv,
+			AuthProtocol:          sha,
+			AuthPassword:          "auth_pass",
+			PrivProtocol:          aes,
+			PrivPassword:          "priv_pass",
+			ContextName:           "vlan-10",
+			AuthoritativeEngineID: "80000000050102030405",
+			EngineBoots:           2,
+			EngineTime:            100,
+		},
+	}
+	client, err := CreateClientFromConfig(cfg)
+	if err != nil {
+		logrus.WithError(err).Errorln("Could not create SNMP client")
+		t.FailNow()
+	}
+	client.Timeout = 100 * time.Millisecond
+	if err = client.Connect(); err != nil {
+		logrus.WithError(err).Errorln("Failed to connect SNMP client")
+		t.FailNow()
+	}
+	defer client.Conn.Close()
+	go client.Get([]string{".1.3.6.1.2.1.1.5.0"})
+
+	// A configured engine ID is used by the first request, rather than
+	// discovering it with an empty one
+	buf := make([]byte, 1500)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		logrus.WithError(err).Errorln("No request was received")
+		t.FailNow()
+	}
+
+	header, err := parseV3Header(buf[:n])
+	if err != nil || header.engineID != "\x80\x00\x00\x00\x05\x01\x02\x03\x04\x05" {
+		logrus.WithError(err).WithField("engine_id", header.engineID).Errorln("Request did not use the configured engine ID")
+		t.Fail()
+	}
+	if !bytes.Contains(buf[:n], []byte("vlan-10")) {
+		logrus.Errorln("Request did not contain the context name")
+		t.Fail()
+	}
+}
+
+func TestCreateV3SNMPClientKeys(t *testing.T) {
+	v := &SNMPVersion{V3: true}
+	a, _ := NewAuth("user", authpriv, testUSMPassphrase, sha, testUSMPassphrase, aes)
+	a.AuthoritativeEngineID = testUSMEngineID
+
+	client, err := CreateClient(localhost, testCommunity, 1, v, a)
+	if err != nil {
+		logrus.WithError(err).Errorln("Could not create SNMP client")
+		t.FailNow()
+	}
+
+	// Keys are localized when the client is created if the engine ID is known
+	authKey, _ := DefaultKeyCache.AuthKey(gosnmp.SHA, testUSMPassphrase, testUSMEngineID)
+	privKey, _ := DefaultKeyCache.PrivKey(gosnmp.AES, gosnmp.SHA, testUSMPassphrase, testUSMEngineID)
+	usm := client.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if !bytes.Equal(usm.SecretKey, authKey) || !bytes.Equal(usm.PrivacyKey, privKey) {
+		logrus.Errorln("Keys were not localized when creating the client")
+		t.Fail()
+	}
+
+	// Configured keys are used instead of the passphrases
+	a.AuthPassword, a.PrivPassword = "", ""
+	a.AuthKey, a.PrivKey = authKey, privKey
+	if client, err = CreateClient(localhost, testCommunity, 1, v, a); err != nil {
+		logrus.WithError(err).Errorln("Could not create SNMP client using localized keys")
+		t.FailNow()
+	}
+	if err = client.Connect(); err != nil {
+		logrus.WithError(err).Errorln("Client using localized keys was not valid")
+		t.Fail()
+	}
+}
+
+func TestConnectClientDiscoversEngine(t *testing.T) {
+	// The trap receiver answers engine discovery in the same way as an agent
+	r := testTrapReceiver(t, TrapConfiguration{EngineID: "80000000050102030405"})
+	defer r.Close()
+	receiveTraps(r)
+
+	v := &SNMPVersion{V3: true}
+	a, _ := NewAuth("user", authpriv, testUSMPassphrase, sha, testUSMPassphrase, aes)
+	client, _ := CreateClient(localhost, testCommunity, 0, v, a)
+	client.Port = uint16(r.Addr().Port)
+	client.Timeout = 2 * time.Second
+
+	if err := ConnectClient(client); err != nil {
+		logrus.WithError(err).Errorln("Failed to discover engine")
+		t.FailNow()
+	}
+	defer client.Conn.Close()
+
+	authKey, _ := LocalizedAuthKey(gosnmp.SHA, testUSMPassphrase, r.EngineID())
+	usm := client.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if usm.AuthoritativeEngineID != r.EngineID() || client.ContextEngineID != r.EngineID() || !bytes.Equal(usm.SecretKey, authKey) || len(usm.PrivacyKey) != 16 {
+		logrus.WithField("engine_id", usm.AuthoritativeEngineID).Errorln("Discovered engine was not used")
+		t.Fail()
+	}
+}
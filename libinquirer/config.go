@@ -3,26 +3,100 @@ package libinquirer
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
 // Configuration object for the inquirer tool
 type Configuration struct {
-	Poll []PollConfiguration `json:"poll"`
+	Poll     []PollConfiguration          `json:"poll"`
+	Modules  map[string]PollConfiguration `json:"modules"`
+	Outputs  []OutputConfiguration        `json:"outputs"`
+	StateDir string                       `json:"state_dir"`
+	// StateMaxAge is the number of seconds a counter sample is kept in the
+	// state store without being updated, defaulting to a day
+	StateMaxAge int                    `json:"state_max_age"`
+	MIBDirs     []string               `json:"mib_dirs"`
+	Traps       TrapConfiguration      `json:"traps"`
+	Relay       RelayConfiguration     `json:"relay"`
+	Discovery   DiscoveryConfiguration `json:"discovery"`
+	// Profiles are named sets of OIDs which poll configurations refer to,
+	// in addition to the BuiltinProfiles
+	Profiles map[string]Profile `json:"profiles"`
+	// SecretProviders are providers of secret references in addition to the
+	// SecretProviders, named by the prefix of the references they resolve
+	SecretProviders map[string]SecretProviderConfiguration `json:"secret_providers"`
+
+	// MIB holds the modules loaded from MIBDirs, used to resolve symbolic
+	// names to OIDs
+	MIB *mib.MIB `json:"-"`
 }
 
 // PollConfiguration represents the configuration on a host by host basis for
 // the inquirer tool
 type PollConfiguration struct {
-	Community string            `json:"community"`
+	Community Secret            `json:"community,omitempty"`
 	Host      string            `json:"host"`
 	Version   string            `json:"version"`
 	OIDs      map[string]string `json:"oids"`
-	Retries   int               `json:"retries"`
+	Retries   int               `json:"retries,omitempty"`
+	Port      uint16            `json:"port,omitempty"`
+	Interval  int               `json:"interval,omitempty"`
+	// Objects lists symbolic names, such as IF-MIB::ifHCInOctets, which are
+	// resolved using the loaded MIBs and added to OIDs
+	Objects []string             `json:"objects,omitempty"`
+	Tables  []TableConfiguration `json:"tables,omitempty"`
+	// Profiles names the profiles whose OIDs and tables are polled, or auto
+	// to select them by the sysObjectID of the host
+	Profiles []string `json:"profiles,omitempty"`
+	// AutoProfiles are the profiles selected from by sysObjectID when the
+	// auto profile is named
+	AutoProfiles map[string]Profile `json:"-"`
 	auth
 }
 
+// TrapConfiguration configures the receiver used by the traps command
+type TrapConfiguration struct {
+	// Listen is the UDP address traps are received on, defaulting to :162
+	Listen string `json:"listen"`
+	// Communities accepted from v1 and v2c agents. Any community is accepted
+	// when none are configured
+	Communities []Secret `json:"communities"`
+	// Users authenticate v3 traps and informs
+	Users []auth `json:"users"`
+	// EngineID is the hex encoded engine ID of the receiver, which agents
+	// sending v3 informs discover. One is generated when empty
+	EngineID string `json:"engine_id"`
+}
+
+// TableConfiguration selects columns of a table which are joined into one row
+// per index
+type TableConfiguration struct {
+	// Name labels the rows of the table, defaulting to the MIB name of the
+	// root
+	Name string `json:"name"`
+	// Root is the OID or name of the table, such as IF-MIB::ifXTable
+	Root string `json:"root"`
+	// Columns are the names or OIDs of the columns to retrieve. A column
+	// number, such as 6, refers to a column of the root's entry
+	Columns []string `json:"columns"`
+
+	// Resolved holds the OID and name of each column, in the order
+	// configured, once the table has been resolved
+	Resolved []TableColumn `json:"-"`
+}
+
+// TableColumn is a single resolved column of a table
+type TableColumn struct {
+	Name string
+	OID  string
+}
+
 // ParseConfigFile is used to retrieve an SNMP configuration object from
 func ParseConfigFile(c string) (*Configuration, error) {
 	configFile, err := os.Open(c)
@@ -39,5 +113,166 @@ func ParseConfigFile(c string) (*Configuration, error) {
 		return nil, err
 	}
 
+	// Communities and passphrases may refer to secrets held elsewhere
+	if err = resolveSecrets(&conf); err != nil {
+		logrus.WithError(err).Debugln("Could not resolve secrets")
+		return nil, err
+	}
+
+	// Relative MIB directories are relative to the configuration file
+	for i, dir := range conf.MIBDirs {
+		if !filepath.IsAbs(dir) {
+			conf.MIBDirs[i] = filepath.Join(filepath.Dir(c), dir)
+		}
+	}
+
+	conf.MIB = mib.New()
+	if len(conf.MIBDirs) > 0 {
+		if err = conf.MIB.LoadDirs(conf.MIBDirs...); err != nil {
+			logrus.WithError(err).Debugln("Could not load MIB directories")
+			return nil, err
+		}
+	}
+
+	profiles, err := ResolveProfiles(conf.Profiles, conf.MIB)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range conf.Poll {
+		if err = ResolveOIDs(&conf.Poll[i], conf.MIB); err != nil {
+			return nil, err
+		}
+		if err = ApplyProfiles(&conf.Poll[i], profiles); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, module := range conf.Modules {
+		if err = ResolveOIDs(&module, conf.MIB); err != nil {
+			return nil, errors.Wrapf(err, "invalid module %s", name)
+		}
+		if err = ApplyProfiles(&module, profiles); err != nil {
+			return nil, errors.Wrapf(err, "invalid module %s", name)
+		}
+		conf.Modules[name] = module
+	}
+
 	return &conf, nil
 }
+
+// ResolveOIDs normalizes the OIDs of a poll configuration and adds the OIDs
+// of its symbolic objects. OIDs may be numeric, with or without a leading
+// dot, or symbolic. Labels left empty are filled in from the MIB, and labels
+// which do not match the MIB are logged
+func ResolveOIDs(cfg *PollConfiguration, m *mib.MIB) error {
+	oids := map[string]string{}
+	for oid, label := range cfg.OIDs {
+		numeric, err := m.Resolve(oid)
+		if err != nil {
+			return errors.Wrapf(err, "invalid OID for host %s", cfg.Host)
+		}
+
+		// Only labels of OIDs within an object are checked, as OIDs below
+		// an unloaded vendor MIB only match a node such as enterprises
+		if node, index := m.Lookup(numeric); node != nil && node.Kind == "OBJECT-TYPE" {
+			name := m.Name(numeric)
+			switch {
+			case label == "":
+				label = name
+			case !labelMatches(label, node, index):
+				logrus.WithFields(logrus.Fields{
+					"host":     cfg.Host,
+					"oid":      numeric,
+					"label":    label,
+					"mib_name": name,
+				}).Warnln("OID label does not match the loaded MIBs")
+			}
+		}
+
+		oids[numeric] = label
+	}
+
+	for _, object := range cfg.Objects {
+		numeric, err := m.Resolve(object)
+		if err != nil {
+			return errors.Wrapf(err, "invalid object for host %s", cfg.Host)
+		}
+		oids[numeric] = m.Name(numeric)
+	}
+
+	for i := range cfg.Tables {
+		if err := ResolveTable(&cfg.Tables[i], m); err != nil {
+			return errors.Wrapf(err, "invalid table for host %s", cfg.Host)
+		}
+	}
+
+	cfg.OIDs = oids
+	return nil
+}
+
+// ResolveTable resolves the root and columns of a table. Columns are named
+// after their MIB object when known, otherwise their OID is used
+func ResolveTable(t *TableConfiguration, m *mib.MIB) error {
+	root, err := m.Resolve(t.Root)
+	if err != nil {
+		return err
+	}
+	t.Root = root
+
+	if len(t.Columns) == 0 {
+		return errors.Errorf("table %s has no columns", root)
+	}
+
+	// Column numbers are relative to the entry, which is the first arc
+	// below a table. The root may also be the entry itself
+	entry := root + ".1"
+	node, index := m.Lookup(root)
+	if node != nil && index == "" {
+		if t.Name == "" {
+			t.Name = node.Name
+		}
+		if node.Syntax != nil && !strings.HasPrefix(node.Syntax.Type, "SEQUENCE OF") {
+			entry = root
+		}
+	}
+	if t.Name == "" {
+		t.Name = root
+	}
+
+	t.Resolved = []TableColumn{}
+	for _, c := range t.Columns {
+		var oid string
+		if _, err = strconv.ParseUint(c, 10, 32); err == nil {
+			oid = entry + "." + c
+		} else if oid, err = m.Resolve(c); err != nil {
+			return err
+		}
+
+		name := oid
+		if node, index := m.Lookup(oid); node != nil && index == "" && node.Kind == "OBJECT-TYPE" {
+			name = node.Name
+		}
+
+		t.Resolved = append(t.Resolved, TableColumn{Name: name, OID: oid})
+	}
+
+	return nil
+}
+
+// labelMatches reports whether a label names a node, with or without its
+// module and index
+func labelMatches(label string, node *mib.Node, index string) bool {
+	names := []string{node.String(), node.Name}
+	if index != "" {
+		names = append(names, node.String()+"."+index, node.Name+"."+index)
+	}
+
+	for _, name := range names {
+		if label == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -54,3 +54,71 @@ func TestParseNonExistantConfigFile(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestParseConfigFileNormalizesOIDs(t *testing.T) {
+	cwd, _ := os.Getwd()
+	c, err := ParseConfigFile(fmt.Sprintf("%s/fixtures/inquirer.json", path.Dir(cwd)))
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to parse configuration file")
+		t.FailNow()
+	}
+
+	if c.Poll[0].OIDs[".1.3.6.1.4.1.2636.3.5.2.1.7"] != "Juniper-MIB::jnxFWCounterDisplayName" {
+		logrus.WithField("oids", c.Poll[0].OIDs).Errorln("OID without a leading dot was not normalized")
+		t.Fail()
+	}
+
+	if _, ok := c.Poll[0].OIDs["1.3.6.1.4.1.2636.3.5.2.1.7"]; ok {
+		logrus.Errorln("OID without a leading dot was kept")
+		t.Fail()
+	}
+}
+
+func TestParseConfigFileResolvesObjects(t *testing.T) {
+	cwd, _ := os.Getwd()
+	c, err := ParseConfigFile(fmt.Sprintf("%s/fixtures/mibs_inquirer.json", path.Dir(cwd)))
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to parse configuration file")
+		t.FailNow()
+	}
+
+	expected := map[string]string{
+		".1.3.6.1.2.1.31.1.1.1.6":  "IF-MIB::ifHCInOctets",
+		".1.3.6.1.2.1.31.1.1.1.1":  "IF-MIB::ifName",
+		".1.3.6.1.2.1.1.5.0":       "SNMPv2-MIB::sysName.0",
+		".1.3.6.1.2.1.31.1.1.1.18": "IF-MIB::ifAlias",
+		".1.3.6.1.2.1.2.2.1.8":     "IF-MIB::ifOperStatus",
+	}
+
+	if len(c.Poll[0].OIDs) != len(expected) {
+		logrus.WithField("oids", c.Poll[0].OIDs).Errorln("Resolved OIDs are invalid")
+		t.Fail()
+	}
+
+	for oid, name := range expected {
+		if c.Poll[0].OIDs[oid] != name {
+			logrus.WithFields(logrus.Fields{"oid": oid, "name": c.Poll[0].OIDs[oid], "expected": name}).Errorln("Resolved OID label is invalid")
+			t.Fail()
+		}
+	}
+
+	table := c.Poll[0].Tables[0]
+	if table.Name != "interfaces" || len(table.Resolved) != 4 || table.Resolved[3].OID != ".1.3.6.1.2.1.2.2.1.8" {
+		logrus.WithField("table", table).Errorln("Table was not resolved")
+		t.Fail()
+	}
+
+	if c.Modules["if_mib"].OIDs[".1.3.6.1.2.1.31.1.1.1.10"] != "IF-MIB::ifHCOutOctets" {
+		logrus.WithField("oids", c.Modules["if_mib"].OIDs).Errorln("Module objects were not resolved")
+		t.Fail()
+	}
+}
+
+func TestParseConfigFileUnknownObject(t *testing.T) {
+	cwd, _ := os.Getwd()
+	_, err := ParseConfigFile(fmt.Sprintf("%s/fixtures/unknown_object_inquirer.json", path.Dir(cwd)))
+	if err == nil {
+		logrus.Errorln("Parsed configuration file with an unknown object")
+		t.FailNow()
+	}
+}
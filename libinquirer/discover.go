@@ -0,0 +1,341 @@
+package libinquirer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	sysDescrOID     = ".1.3.6.1.2.1.1.1.0"
+	sysObjectIDOID  = ".1.3.6.1.2.1.1.2.0"
+	sysNameOID      = ".1.3.6.1.2.1.1.5.0"
+	maxDiscoverHost = 65536
+
+	defaultDiscoverTimeout = time.Duration(2) * time.Second
+)
+
+// DefaultDiscoveryOIDs are polled from each host written to a discovered
+// configuration
+var DefaultDiscoveryOIDs = map[string]string{
+	SysUpTimeOID:               "SNMPv2-MIB::sysUpTime.0",
+	".1.3.6.1.2.1.31.1.1.1.6":  "IF-MIB::ifHCInOctets",
+	".1.3.6.1.2.1.31.1.1.1.10": "IF-MIB::ifHCOutOctets",
+}
+
+// DiscoveryConfiguration holds the credentials tried against each address by
+// the discover command
+type DiscoveryConfiguration struct {
+	// Communities are tried using v2c, in order
+	Communities []Secret `json:"communities"`
+	// Users are tried using v3 once no community has been answered
+	Users   []auth `json:"users"`
+	Port    uint16 `json:"port"`
+	Retries int    `json:"retries"`
+
+	// references maps resolved secrets to the references they were resolved
+	// from, which are written to discovered configurations in their place
+	references map[Secret]Secret
+}
+
+// reference returns the reference a secret was resolved from, or the secret
+// itself when it was not a reference
+func (c DiscoveryConfiguration) reference(s Secret) string {
+	if ref, ok := c.references[s]; ok {
+		return ref.Reveal()
+	}
+
+	return s.Reveal()
+}
+
+// DiscoveredHost is a host which answered one of the candidate credentials
+type DiscoveredHost struct {
+	Host        string `json:"host"`
+	Version     string `json:"version"`
+	Username    string `json:"username,omitempty"`
+	SysName     string `json:"sys_name"`
+	SysDescr    string `json:"sys_descr"`
+	SysObjectID string `json:"sys_object_id"`
+
+	// Config polls the host using the credentials it answered
+	Config PollConfiguration `json:"-"`
+}
+
+// DiscoveryResult is the outcome of probing a single address. Err is set
+// when no candidate credentials were answered
+type DiscoveryResult struct {
+	Host       string
+	Discovered *DiscoveredHost
+	Err        error
+}
+
+// Discoverer probes addresses using a bounded pool of workers
+type Discoverer struct {
+	Config DiscoveryConfiguration
+	// Workers is the maximum number of addresses probed at the same time
+	Workers int
+	// Timeout is the time to wait for a response to each candidate
+	Timeout time.Duration
+}
+
+// NewDiscoverer creates a new discoverer, falling back to sane defaults for
+// any values which are not set
+func NewDiscoverer(cfg DiscoveryConfiguration, workers int, timeout time.Duration) *Discoverer {
+	if workers < 1 {
+		workers = defaultWorkers
+	}
+
+	if timeout <= 0 {
+		timeout = defaultDiscoverTimeout
+	}
+
+	return &Discoverer{
+		Config:  cfg,
+		Workers: workers,
+		Timeout: timeout,
+	}
+}
+
+// Hosts returns every address of a CIDR range. The network and broadcast
+// addresses of IPv4 ranges larger than a /31 are excluded
+func Hosts(cidr string) ([]string, error) {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid range %s", cidr)
+	}
+
+	ones, bits := network.Mask.Size()
+	if bits-ones > 16 {
+		return nil, errors.Errorf("range %s is larger than %d addresses", cidr, maxDiscoverHost)
+	}
+
+	size := 1 << uint(bits-ones)
+	start := new(big.Int).SetBytes(network.IP)
+	if ip.To4() != nil {
+		start = new(big.Int).SetBytes(network.IP.To4())
+	}
+
+	hosts := []string{}
+	for i := 0; i < size; i++ {
+		if ip.To4() != nil && size > 2 && (i == 0 || i == size-1) {
+			continue
+		}
+
+		b := new(big.Int).Add(start, big.NewInt(int64(i))).Bytes()
+		addr := make(net.IP, len(network.IP))
+		copy(addr[len(addr)-len(b):], b)
+		hosts = append(hosts, addr.String())
+	}
+
+	return hosts, nil
+}
+
+// Run probes every address provided and sends a result per address on the
+// returned channel, which is closed once every address has been probed
+func (d *Discoverer) Run(ctx context.Context, hosts []string) <-chan *DiscoveryResult {
+	results := make(chan *DiscoveryResult, d.Workers)
+	jobs := make(chan string)
+
+	var wg sync.WaitGroup
+	for i := 0; i < d.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range jobs {
+				results <- d.Probe(ctx, host)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(results)
+
+	Dispatch:
+		for _, host := range hosts {
+			select {
+			case jobs <- host:
+			case <-ctx.Done():
+				break Dispatch
+			}
+		}
+		close(jobs)
+		wg.Wait()
+	}()
+
+	return results
+}
+
+// Probe tries each candidate community and then each candidate user against
+// a single address, stopping at the first which is answered
+func (d *Discoverer) Probe(ctx context.Context, host string) *DiscoveryResult {
+	candidates := []PollConfiguration{}
+	for _, community := range d.Config.Communities {
+		candidates = append(candidates, PollConfiguration{Version: v2, Community: community})
+	}
+	for _, user := range d.Config.Users {
+		candidates = append(candidates, PollConfiguration{Version: v3, auth: user})
+	}
+
+	result := &DiscoveryResult{Host: host, Err: errors.New("no candidate credentials were answered")}
+	for _, cfg := range candidates {
+		if err := ctx.Err(); err != nil {
+			result.Err = err
+			return result
+		}
+
+		cfg.Host = host
+		cfg.Port = d.Config.Port
+		cfg.Retries = d.Config.Retries
+
+		discovered, err := d.probe(ctx, cfg)
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"host":     host,
+				"version":  cfg.Version,
+				"username": cfg.Username,
+			}).Debugln("Candidate credentials were not answered")
+			continue
+		}
+
+		result.Discovered, result.Err = discovered, nil
+		return result
+	}
+
+	return result
+}
+
+// probe retrieves the system group of a host using a single candidate
+func (d *Discoverer) probe(ctx context.Context, cfg PollConfiguration) (*DiscoveredHost, error) {
+	client, err := CreateClientFromConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	client.Context = ctx
+	client.Timeout = d.Timeout
+
+	if err = ConnectClient(client); err != nil {
+		return nil, err
+	}
+	defer client.Conn.Close()
+
+	packet, err := client.Get([]string{sysNameOID, sysDescrOID, sysObjectIDOID})
+	if err != nil {
+		return nil, err
+	}
+	if packet.Error != gosnmp.NoError {
+		return nil, errors.Errorf("request failed with error status %s", packet.Error)
+	}
+	LogEngine(client)
+
+	h := &DiscoveredHost{
+		Host:     cfg.Host,
+		Version:  cfg.Version,
+		Username: cfg.Username,
+		Config:   cfg,
+	}
+	for _, pdu := range packet.Variables {
+		switch pdu.Name {
+		case sysNameOID:
+			h.SysName = discoveredString(pdu)
+		case sysDescrOID:
+			h.SysDescr = discoveredString(pdu)
+		case sysObjectIDOID:
+			h.SysObjectID = discoveredString(pdu)
+		}
+	}
+
+	return h, nil
+}
+
+func discoveredString(pdu gosnmp.SnmpPDU) string {
+	switch v := pdu.Value.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+
+	return ""
+}
+
+// discoveredConfiguration is the configuration written for discovered hosts,
+// which keeps the settings of the configuration discovery was run with
+type discoveredConfiguration struct {
+	Poll        []revealedPollConfiguration `json:"poll"`
+	Outputs     []OutputConfiguration       `json:"outputs,omitempty"`
+	StateDir    string                      `json:"state_dir,omitempty"`
+	StateMaxAge int                         `json:"state_max_age,omitempty"`
+	MIBDirs     []string                    `json:"mib_dirs,omitempty"`
+	Discovered  []DiscoveredHost            `json:"discovered"`
+}
+
+// revealedPollConfiguration marshals the secrets of a poll configuration,
+// which are otherwise redacted, as its fields take precedence over the
+// embedded fields of the same name
+type revealedPollConfiguration struct {
+	PollConfiguration
+	Community    string `json:"community,omitempty"`
+	AuthPassword string `json:"auth_password,omitempty"`
+	PrivPassword string `json:"priv_password,omitempty"`
+	AuthKey      string `json:"auth_key,omitempty"`
+	PrivKey      string `json:"priv_key,omitempty"`
+}
+
+// WriteDiscoveredConfiguration writes a configuration polling the
+// DefaultDiscoveryOIDs of each discovered host, sorted by address. The
+// outputs, state settings and MIB directories of base are kept when
+// provided. Discovered hosts are recorded in the discovered section, which
+// ParseConfigFile ignores. The credentials each host answered are written
+// as the secret references of the discovery section of base they were
+// resolved from, such as env:SNMP_COMMUNITY, and otherwise in the clear so
+// that the configuration can be polled
+func WriteDiscoveredConfiguration(w io.Writer, base *Configuration, hosts []DiscoveredHost) error {
+	sorted := append([]DiscoveredHost{}, hosts...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := net.ParseIP(sorted[i].Host), net.ParseIP(sorted[j].Host)
+		if a == nil || b == nil {
+			return sorted[i].Host < sorted[j].Host
+		}
+		return new(big.Int).SetBytes(a.To16()).Cmp(new(big.Int).SetBytes(b.To16())) < 0
+	})
+
+	conf := discoveredConfiguration{
+		Poll:       []revealedPollConfiguration{},
+		Discovered: sorted,
+	}
+	discovery := DiscoveryConfiguration{}
+	if base != nil {
+		conf.Outputs, conf.StateDir, conf.StateMaxAge, conf.MIBDirs = base.Outputs, base.StateDir, base.StateMaxAge, base.MIBDirs
+		discovery = base.Discovery
+	}
+
+	for _, h := range sorted {
+		cfg := h.Config
+		cfg.OIDs = map[string]string{}
+		for oid, label := range DefaultDiscoveryOIDs {
+			cfg.OIDs[oid] = label
+		}
+		conf.Poll = append(conf.Poll, revealedPollConfiguration{
+			PollConfiguration: cfg,
+			Community:         discovery.reference(cfg.Community),
+			AuthPassword:      discovery.reference(cfg.AuthPassword),
+			PrivPassword:      discovery.reference(cfg.PrivPassword),
+			AuthKey:           discovery.reference(cfg.AuthKey),
+			PrivKey:           discovery.reference(cfg.PrivKey),
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(conf)
+}
@@ -0,0 +1,172 @@
+package libinquirer
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+func TestHosts(t *testing.T) {
+	tests := map[string][]string{
+		"192.0.2.0/30":   {"192.0.2.1", "192.0.2.2"},
+		"192.0.2.7/31":   {"192.0.2.6", "192.0.2.7"},
+		"192.0.2.9/32":   {"192.0.2.9"},
+		"2001:db8::/127": {"2001:db8::", "2001:db8::1"},
+	}
+
+	for cidr, expected := range tests {
+		hosts, err := Hosts(cidr)
+		if err != nil || strings.Join(hosts, ",") != strings.Join(expected, ",") {
+			logrus.WithError(err).WithFields(logrus.Fields{"cidr": cidr, "hosts": hosts}).Errorln("Range was expanded incorrectly")
+			t.Fail()
+		}
+	}
+
+	if hosts, _ := Hosts("10.0.0.0/24"); len(hosts) != 254 || hosts[253] != "10.0.0.254" {
+		logrus.WithField("hosts", len(hosts)).Errorln("/24 was expanded incorrectly")
+		t.Fail()
+	}
+
+	for _, cidr := range []string{invalid, "10.0.0.0/8"} {
+		if _, err := Hosts(cidr); err == nil {
+			logrus.WithField("cidr", cidr).Errorln("Invalid range was accepted")
+			t.Fail()
+		}
+	}
+}
+
+func startDiscoveryAgent(t *testing.T) *testAgent {
+	agent := startTestAgent(t, append(testAgentPDUs(),
+		gosnmp.SnmpPDU{Name: sysDescrOID, Type: gosnmp.OctetString, Value: []byte("Test agent")},
+		gosnmp.SnmpPDU{Name: sysObjectIDOID, Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.2636.1.1.1.2.29"},
+	))
+	agent.setCommunity(testCommunity)
+
+	return agent
+}
+
+func TestDiscover(t *testing.T) {
+	agent := startDiscoveryAgent(t)
+	defer agent.Close()
+
+	d := NewDiscoverer(DiscoveryConfiguration{
+		Communities: []Secret{invalid, testCommunity},
+		Port:        agent.Port(),
+	}, 2, 200*time.Millisecond)
+
+	results := map[string]*DiscoveryResult{}
+	for result := range d.Run(context.Background(), []string{localhost, "127.0.0.2"}) {
+		results[result.Host] = result
+	}
+
+	found := results[localhost]
+	if found == nil || found.Err != nil {
+		logrus.WithField("result", found).Errorln("Agent was not discovered")
+		t.FailNow()
+	}
+
+	h := found.Discovered
+	if h.SysName != "test-agent" || h.SysDescr != "Test agent" || h.SysObjectID != ".1.3.6.1.4.1.2636.1.1.1.2.29" {
+		logrus.WithField("host", h).Errorln("System group was not recorded")
+		t.Fail()
+	}
+	if h.Version != v2 || h.Config.Community != testCommunity || h.Config.Port != agent.Port() {
+		logrus.WithField("config", h.Config).Errorln("Answered credentials were not recorded")
+		t.Fail()
+	}
+
+	if missing := results["127.0.0.2"]; missing == nil || missing.Err == nil || missing.Discovered != nil {
+		logrus.WithField("result", missing).Errorln("Silent address was discovered")
+		t.Fail()
+	}
+}
+
+func TestWriteDiscoveredConfiguration(t *testing.T) {
+	hosts := []DiscoveredHost{
+		{Host: "192.0.2.10", Version: v3, Username: "discover", Config: PollConfiguration{Host: "192.0.2.10", Version: v3, auth: auth{
+			Username:      "discover",
+			SecurityLevel: authnopriv,
+			AuthPassword:  "password",
+			AuthProtocol:  sha,
+		}}},
+		{Host: "192.0.2.9", Version: v2, SysName: "router", Config: PollConfiguration{Host: "192.0.2.9", Version: v2, Community: testCommunity}},
+	}
+	base := &Configuration{Outputs: []OutputConfiguration{{Type: JSONOutput}}}
+
+	var buf bytes.Buffer
+	if err := WriteDiscoveredConfiguration(&buf, base, hosts); err != nil {
+		logrus.WithError(err).Errorln("Failed to write discovered configuration")
+		t.FailNow()
+	}
+
+	dir, _ := ioutil.TempDir("", "discover")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "inquirer.json")
+	ioutil.WriteFile(path, buf.Bytes(), 0600)
+
+	conf, err := ParseConfigFile(path)
+	if err != nil || len(conf.Poll) != 2 || len(conf.Outputs) != 1 {
+		logrus.WithError(err).WithField("config", buf.String()).Errorln("Discovered configuration could not be parsed")
+		t.FailNow()
+	}
+
+	first, second := conf.Poll[0], conf.Poll[1]
+	if first.Host != "192.0.2.9" || first.Community != testCommunity || len(first.OIDs) != len(DefaultDiscoveryOIDs) {
+		logrus.WithField("poll", first).Errorln("Discovered hosts were not sorted by address")
+		t.Fail()
+	}
+	if second.Username != "discover" || second.AuthPassword != "password" || second.SecurityLevel != authnopriv {
+		logrus.WithField("poll", second).Errorln("v3 credentials were not written")
+		t.Fail()
+	}
+
+	if !strings.Contains(buf.String(), `"sys_name": "router"`) {
+		logrus.WithField("config", buf.String()).Errorln("Discovered hosts were not recorded")
+		t.Fail()
+	}
+}
+
+func TestWriteDiscoveredConfigurationReferences(t *testing.T) {
+	agent := startDiscoveryAgent(t)
+	defer agent.Close()
+
+	os.Setenv("INQUIRER_TEST_COMMUNITY", testCommunity)
+	defer os.Unsetenv("INQUIRER_TEST_COMMUNITY")
+
+	dir, _ := ioutil.TempDir("", "discover")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "inquirer.json")
+	ioutil.WriteFile(path, []byte(`{"discovery": {"communities": ["Invalid", "env:INQUIRER_TEST_COMMUNITY"]}}`), 0600)
+
+	base, err := ParseConfigFile(path)
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to parse configuration file")
+		t.FailNow()
+	}
+	base.Discovery.Port = agent.Port()
+
+	result := NewDiscoverer(base.Discovery, 1, 200*time.Millisecond).Probe(context.Background(), localhost)
+	if result.Err != nil || result.Discovered.Config.Community != testCommunity {
+		logrus.WithError(result.Err).Errorln("Agent was not discovered using the resolved community")
+		t.FailNow()
+	}
+
+	var buf bytes.Buffer
+	if err = WriteDiscoveredConfiguration(&buf, base, []DiscoveredHost{*result.Discovered}); err != nil {
+		logrus.WithError(err).Errorln("Failed to write discovered configuration")
+		t.FailNow()
+	}
+
+	if !strings.Contains(buf.String(), `"community": "env:INQUIRER_TEST_COMMUNITY"`) || strings.Contains(buf.String(), `"`+testCommunity+`"`) {
+		logrus.WithField("config", buf.String()).Errorln("Resolved community was written in place of its reference")
+		t.Fail()
+	}
+}
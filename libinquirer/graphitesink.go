@@ -0,0 +1,333 @@
+package libinquirer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// GraphiteOutput is the output type which sends records to carbon
+	GraphiteOutput = "graphite"
+
+	graphitePlaintext = "plaintext"
+	graphitePickle    = "pickle"
+
+	defaultGraphiteTemplate  = "snmp.{host}.{oid_name}.{index}"
+	defaultGraphiteBatchSize = 500
+	defaultGraphiteTimeout   = time.Duration(10) * time.Second
+)
+
+var (
+	invalidGraphiteChars = regexp.MustCompile(`[^a-zA-Z0-9_\-]`)
+	graphitePlaceholders = regexp.MustCompile(`\{[a-z_]+\}`)
+)
+
+// graphiteMetric is a single data point sent to carbon
+type graphiteMetric struct {
+	Path      string
+	Value     float64
+	Timestamp int64
+}
+
+// GraphiteSink sends numeric records to carbon using either the plaintext or
+// pickle protocol. Metric paths are built from a template such as
+// snmp.{host}.{oid_name}.{index}, where {host}, {mib}, {oid_name}, {oid} and
+// {index} are replaced with sanitized values from each record. Table rows
+// also replace {table}
+type GraphiteSink struct {
+	sync.Mutex
+	Address   string
+	Protocol  string
+	Template  string
+	BatchSize int
+	Timeout   time.Duration
+
+	conn net.Conn
+}
+
+// NewGraphiteSink creates a sink sending to the carbon address in the output
+// target. The protocol, template and batch_size options are supported
+func NewGraphiteSink(o OutputConfiguration) (*GraphiteSink, error) {
+	if o.Target == "" {
+		return nil, errors.Errorf("Graphite output requires a target of host:port")
+	}
+
+	s := &GraphiteSink{
+		Address:   o.Target,
+		Protocol:  graphitePlaintext,
+		Template:  defaultGraphiteTemplate,
+		BatchSize: defaultGraphiteBatchSize,
+		Timeout:   defaultGraphiteTimeout,
+	}
+
+	if p, ok := o.Options["protocol"]; ok {
+		if p != graphitePlaintext && p != graphitePickle {
+			return nil, errors.Errorf("Invalid Graphite protocol %s. Please select plaintext or pickle", p)
+		}
+		s.Protocol = p
+	}
+
+	if t, ok := o.Options["template"]; ok && t != "" {
+		s.Template = t
+	}
+
+	if b, ok := o.Options["batch_size"]; ok {
+		size, err := strconv.Atoi(b)
+		if err != nil || size < 1 {
+			return nil, errors.Errorf("Invalid Graphite batch size %s", b)
+		}
+		s.BatchSize = size
+	}
+
+	return s, nil
+}
+
+// Write sends every numeric record to carbon in batches. If sending a batch
+// fails the connection is re-established and the batch retried once
+func (s *GraphiteSink) Write(records []Record) error {
+	metrics := []graphiteMetric{}
+	for _, r := range records {
+		value, ok := graphiteValue(r.Value)
+		if !ok {
+			continue
+		}
+
+		metrics = append(metrics, graphiteMetric{
+			Path:      s.Path(r),
+			Value:     value,
+			Timestamp: r.Timestamp.Unix(),
+		})
+	}
+
+	return s.sendMetrics(metrics)
+}
+
+// WriteRows sends every numeric cell of each row to carbon. Paths are built
+// from the template with the column as {oid_name}, the row index as {index}
+// and the table as {table}
+func (s *GraphiteSink) WriteRows(rows []Row) error {
+	metrics := []graphiteMetric{}
+	for _, r := range rows {
+		for _, c := range r.Cells {
+			value, ok := graphiteValue(c.Value)
+			if !ok {
+				continue
+			}
+
+			metrics = append(metrics, graphiteMetric{
+				Path: s.expand(map[string]string{
+					"{host}":     r.Host,
+					"{table}":    r.Table,
+					"{oid_name}": c.Name,
+					"{oid}":      strings.Trim(c.OID, "."),
+					"{index}":    r.Index,
+				}),
+				Value:     value,
+				Timestamp: r.Timestamp.Unix(),
+			})
+		}
+	}
+
+	return s.sendMetrics(metrics)
+}
+
+// WriteTraps sends a value of 1 for each trap, so that carbon can count
+// them. Paths are built from the template with the trap object as
+// {oid_name}, its module as {mib} and no {index}
+func (s *GraphiteSink) WriteTraps(traps []Trap) error {
+	metrics := []graphiteMetric{}
+	for _, t := range traps {
+		mib, object := "", t.OID
+		if parts := strings.SplitN(t.Name, "::", 2); len(parts) == 2 {
+			mib, object = parts[0], parts[1]
+		}
+
+		metrics = append(metrics, graphiteMetric{
+			Path: s.expand(map[string]string{
+				"{host}":     t.Host,
+				"{mib}":      mib,
+				"{oid_name}": object,
+				"{oid}":      strings.Trim(t.OID, "."),
+			}),
+			Value:     1,
+			Timestamp: t.Timestamp.Unix(),
+		})
+	}
+
+	return s.sendMetrics(metrics)
+}
+
+func (s *GraphiteSink) sendMetrics(metrics []graphiteMetric) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for start := 0; start < len(metrics); start += s.BatchSize {
+		end := start + s.BatchSize
+		if end > len(metrics) {
+			end = len(metrics)
+		}
+
+		payload := s.encode(metrics[start:end])
+		if err := s.send(payload); err != nil {
+			logrus.WithError(err).WithField("address", s.Address).Debugln("Failed to send to carbon, reconnecting")
+			s.disconnect()
+			if err = s.send(payload); err != nil {
+				s.disconnect()
+				return errors.Wrap(err, "failed to send metrics to carbon")
+			}
+		}
+	}
+
+	return nil
+}
+
+// Path builds the metric path of a record from the template
+func (s *GraphiteSink) Path(r Record) string {
+	mib, object := "", r.MIBName
+	if parts := strings.SplitN(r.MIBName, "::", 2); len(parts) == 2 {
+		mib, object = parts[0], parts[1]
+	}
+	if object == "" {
+		object = r.BaseOID
+	}
+
+	return s.expand(map[string]string{
+		"{host}":     r.Host,
+		"{mib}":      mib,
+		"{oid_name}": object,
+		"{oid}":      strings.Trim(r.BaseOID, "."),
+		"{index}":    r.Index,
+	})
+}
+
+// expand replaces the placeholders of the template with sanitized values
+func (s *GraphiteSink) expand(values map[string]string) string {
+	path := graphitePlaceholders.ReplaceAllStringFunc(s.Template, func(p string) string {
+		return SanitizeGraphite(values[p])
+	})
+
+	// Drop any empty nodes left by placeholders without a value
+	nodes := []string{}
+	for _, node := range strings.Split(path, ".") {
+		if node != "" {
+			nodes = append(nodes, node)
+		}
+	}
+
+	return strings.Join(nodes, ".")
+}
+
+// Close closes the connection to carbon
+func (s *GraphiteSink) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.disconnect()
+}
+
+// SanitizeGraphite converts a value to a single node of a Graphite metric
+// path, replacing dots and any other unsafe characters with underscores
+func SanitizeGraphite(v string) string {
+	return invalidGraphiteChars.ReplaceAllString(v, "_")
+}
+
+func (s *GraphiteSink) send(payload []byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout("tcp", s.Address, s.Timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.Timeout)); err != nil {
+		return err
+	}
+
+	_, err := s.conn.Write(payload)
+	return err
+}
+
+func (s *GraphiteSink) disconnect() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *GraphiteSink) encode(metrics []graphiteMetric) []byte {
+	if s.Protocol == graphitePickle {
+		return encodeGraphitePickle(metrics)
+	}
+
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		fmt.Fprintf(&buf, "%s %s %d\n", m.Path, strconv.FormatFloat(m.Value, 'f', -1, 64), m.Timestamp)
+	}
+
+	return buf.Bytes()
+}
+
+// encodeGraphitePickle encodes metrics as a length prefixed pickle (protocol
+// 2) of a list of (path, (timestamp, value)) tuples, as expected by carbon's
+// pickle receiver
+func encodeGraphitePickle(metrics []graphiteMetric) []byte {
+	var p bytes.Buffer
+	p.Write([]byte{0x80, 0x02}) // PROTO 2
+	p.WriteByte(']')            // EMPTY_LIST
+	p.WriteByte('(')            // MARK
+	for _, m := range metrics {
+		p.WriteByte('X') // BINUNICODE
+		binary.Write(&p, binary.LittleEndian, uint32(len(m.Path)))
+		p.WriteString(m.Path)
+
+		if m.Timestamp >= math.MinInt32 && m.Timestamp <= math.MaxInt32 {
+			p.WriteByte('J') // BININT
+			binary.Write(&p, binary.LittleEndian, int32(m.Timestamp))
+		} else {
+			p.WriteByte('G') // BINFLOAT
+			binary.Write(&p, binary.BigEndian, float64(m.Timestamp))
+		}
+
+		p.WriteByte('G') // BINFLOAT
+		binary.Write(&p, binary.BigEndian, m.Value)
+
+		p.WriteByte(0x86) // TUPLE2 (timestamp, value)
+		p.WriteByte(0x86) // TUPLE2 (path, (timestamp, value))
+	}
+	p.WriteByte('e') // APPENDS
+	p.WriteByte('.') // STOP
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(p.Len()))
+	buf.Write(p.Bytes())
+
+	return buf.Bytes()
+}
+
+func graphiteValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case uint64:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,213 @@
+package libinquirer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+func graphiteTestRecords() []Record {
+	return []Record{
+		{
+			Host:      "core1.example.com",
+			BaseOID:   ".1.3.6.1.2.1.31.1.1.1.6",
+			MIBName:   "IF-MIB::ifHCInOctets",
+			Index:     "1",
+			PDUType:   gosnmp.Counter64,
+			Value:     uint64(1000),
+			Timestamp: time.Unix(1500000000, 0),
+		},
+		{
+			Host:      "core1.example.com",
+			MIBName:   "IF-MIB::ifAlias",
+			Index:     "1",
+			PDUType:   gosnmp.OctetString,
+			Value:     "uplink",
+			Timestamp: time.Unix(1500000000, 0),
+		},
+	}
+}
+
+func startGraphiteListener(t *testing.T) (net.Listener, chan []byte) {
+	l, err := net.Listen("tcp", localhost+":0")
+	if err != nil {
+		t.Fatalf("Failed to start carbon listener: %s", err)
+	}
+
+	received := make(chan []byte, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				buf := make([]byte, 65535)
+				for {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					received <- append([]byte{}, buf[:n]...)
+				}
+			}(conn)
+		}
+	}()
+
+	return l, received
+}
+
+func TestGraphitePath(t *testing.T) {
+	s, _ := NewGraphiteSink(OutputConfiguration{Type: GraphiteOutput, Target: "localhost:2003"})
+	r := graphiteTestRecords()[0]
+	if p := s.Path(r); p != "snmp.core1_example_com.ifHCInOctets.1" {
+		logrus.WithField("path", p).Errorln("Incorrect metric path generated")
+		t.Fail()
+	}
+
+	s.Template = "network.{mib}.{host}.{oid_name}.{index}"
+	r.MIBName = "Juniper MIB::jnx/Counter"
+	r.Index = ""
+	if p := s.Path(r); p != "network.Juniper_MIB.core1_example_com.jnx_Counter" {
+		logrus.WithField("path", p).Errorln("Incorrect metric path generated")
+		t.Fail()
+	}
+}
+
+func TestNewGraphiteSinkInvalid(t *testing.T) {
+	invalidOutputs := []OutputConfiguration{
+		{Type: GraphiteOutput},
+		{Type: GraphiteOutput, Target: "localhost:2003", Options: map[string]string{"protocol": invalid}},
+		{Type: GraphiteOutput, Target: "localhost:2003", Options: map[string]string{"batch_size": "0"}},
+	}
+
+	for _, o := range invalidOutputs {
+		if _, err := NewSink(o); err == nil {
+			logrus.WithField("output", o).Errorln("Created Graphite sink with an invalid configuration")
+			t.Fail()
+		}
+	}
+}
+
+func TestGraphiteSinkPlaintext(t *testing.T) {
+	l, received := startGraphiteListener(t)
+	defer l.Close()
+
+	s, _ := NewSink(OutputConfiguration{Type: GraphiteOutput, Target: l.Addr().String()})
+	defer s.Close()
+
+	if err := s.Write(graphiteTestRecords()); err != nil {
+		logrus.WithError(err).Errorln("Failed to write to carbon")
+		t.FailNow()
+	}
+
+	select {
+	case data := <-received:
+		if string(data) != "snmp.core1_example_com.ifHCInOctets.1 1000 1500000000\n" {
+			logrus.WithField("data", string(data)).Errorln("Incorrect plaintext metrics sent")
+			t.Fail()
+		}
+	case <-time.After(time.Second):
+		logrus.Errorln("No metrics received")
+		t.Fail()
+	}
+}
+
+func TestGraphiteSinkPickle(t *testing.T) {
+	l, received := startGraphiteListener(t)
+	defer l.Close()
+
+	s, _ := NewSink(OutputConfiguration{
+		Type:    GraphiteOutput,
+		Target:  l.Addr().String(),
+		Options: map[string]string{"protocol": "pickle", "batch_size": "1"},
+	})
+	defer s.Close()
+
+	records := graphiteTestRecords()
+	records = append(records, records[0])
+	if err := s.Write(records); err != nil {
+		logrus.WithError(err).Errorln("Failed to write to carbon")
+		t.FailNow()
+	}
+
+	var data bytes.Buffer
+	deadline := time.After(time.Second)
+	for data.Len() == 0 || !bytes.HasSuffix(data.Bytes(), []byte("e.")) || strings.Count(data.String(), "ifHCInOctets") < 2 {
+		select {
+		case b := <-received:
+			data.Write(b)
+		case <-deadline:
+			logrus.Errorln("Not every batch was received")
+			t.FailNow()
+		}
+	}
+
+	// Each batch is a length prefixed pickle
+	r := bufio.NewReader(&data)
+	batches := 0
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err == io.EOF {
+			break
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			logrus.WithError(err).Errorln("Incorrect pickle length header")
+			t.FailNow()
+		}
+		if !bytes.HasPrefix(payload, []byte{0x80, 0x02, ']', '('}) || !bytes.HasSuffix(payload, []byte("e.")) {
+			logrus.WithField("payload", payload).Errorln("Invalid pickle payload")
+			t.Fail()
+		}
+		batches++
+	}
+
+	if batches != 2 {
+		logrus.WithField("batches", batches).Errorln("Metrics were not batched")
+		t.Fail()
+	}
+}
+
+func TestGraphiteSinkReconnect(t *testing.T) {
+	l, received := startGraphiteListener(t)
+	addr := l.Addr().String()
+	l.Close()
+
+	s, _ := NewSink(OutputConfiguration{Type: GraphiteOutput, Target: addr})
+	defer s.Close()
+	if err := s.Write(graphiteTestRecords()); err == nil {
+		logrus.Errorln("Write succeeded without a carbon listener")
+		t.FailNow()
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("Unable to listen on %s again: %s", addr, err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 1024)
+		n, _ := conn.Read(buf)
+		received <- buf[:n]
+	}()
+
+	if err = s.Write(graphiteTestRecords()); err != nil {
+		logrus.WithError(err).Errorln("Sink did not reconnect to carbon")
+		t.Fail()
+	}
+}
@@ -0,0 +1,291 @@
+package libinquirer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// InfluxOutput is the output type which writes InfluxDB line protocol
+	InfluxOutput = "influx"
+
+	defaultInfluxMeasurement = "snmp"
+	influxTrapMeasurement    = "snmp_traps"
+)
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxKeyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	influxStringEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+// InfluxSink writes records in the InfluxDB line protocol. The MIB module of
+// each record is used as the measurement, the object as the field, and the
+// host and interface index as tags. Rendered values are written to an
+// additional <object>_display string field. Lines are written to stdout, a
+// file, or the HTTP write endpoint of an InfluxDB server
+type InfluxSink struct {
+	// Unsigned writes unsigned integers using the u suffix, which requires
+	// InfluxDB 1.8 or later. Otherwise the i suffix is used, so that a field
+	// always has the integer type, and values above the largest signed
+	// integer, which only Counter64 objects reach, are skipped
+	Unsigned bool
+
+	w      io.Writer
+	url    string
+	client *http.Client
+}
+
+// NewInfluxSink creates a sink writing to the target provided. An empty
+// target or - writes to stdout, http and https URLs are written to using
+// HTTP POST requests and anything else is treated as a file path
+func NewInfluxSink(o OutputConfiguration) (*InfluxSink, error) {
+	s := &InfluxSink{Unsigned: o.Options["unsigned"] == "true"}
+
+	switch {
+	case o.Target == "" || o.Target == "-":
+		s.w = os.Stdout
+	case strings.HasPrefix(o.Target, "http://") || strings.HasPrefix(o.Target, "https://"):
+		s.url = o.Target
+		s.client = &http.Client{Timeout: time.Duration(10) * time.Second}
+	default:
+		f, err := os.OpenFile(o.Target, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not open InfluxDB output file %s", o.Target)
+		}
+		s.w = f
+	}
+
+	return s, nil
+}
+
+// Write writes one line per record
+func (s *InfluxSink) Write(records []Record) error {
+	var buf bytes.Buffer
+	for _, r := range records {
+		line, ok := s.Line(r)
+		if !ok {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	return s.write(&buf)
+}
+
+// WriteRows writes one line per table row
+func (s *InfluxSink) WriteRows(rows []Row) error {
+	var buf bytes.Buffer
+	for _, r := range rows {
+		line, ok := s.RowLine(r)
+		if !ok {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	return s.write(&buf)
+}
+
+// WriteTraps writes one line per trap
+func (s *InfluxSink) WriteTraps(traps []Trap) error {
+	var buf bytes.Buffer
+	for _, t := range traps {
+		buf.WriteString(s.TrapLine(t))
+		buf.WriteByte('\n')
+	}
+
+	return s.write(&buf)
+}
+
+func (s *InfluxSink) write(buf *bytes.Buffer) error {
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	if s.url == "" {
+		_, err := s.w.Write(buf.Bytes())
+		return err
+	}
+
+	resp, err := s.client.Post(s.url, "text/plain; charset=utf-8", buf)
+	if err != nil {
+		return errors.Wrap(err, "failed to write to InfluxDB")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf("InfluxDB write failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// Line converts a record to a line of InfluxDB line protocol. False is
+// returned for records without a value
+func (s *InfluxSink) Line(r Record) (string, bool) {
+	value, ok := s.fieldValue(r.Readable())
+	if !ok {
+		return "", false
+	}
+
+	measurement, field := defaultInfluxMeasurement, strings.Trim(r.BaseOID, ".")
+	if parts := strings.SplitN(r.MIBName, "::", 2); len(parts) == 2 {
+		measurement, field = parts[0], parts[1]
+	} else if r.MIBName != "" {
+		field = r.MIBName
+	}
+
+	fields := influxKeyEscaper.Replace(field) + "=" + value
+	if r.Display != "" {
+		fields += "," + influxKeyEscaper.Replace(field+"_display") + `="` + influxStringEscaper.Replace(r.Display) + `"`
+	}
+	if r.Rate != nil {
+		fields += "," + influxKeyEscaper.Replace(field+"_rate") + "=" + strconv.FormatFloat(r.Rate.PerSecond, 'f', -1, 64)
+	}
+
+	return fmt.Sprintf("%s,host_queried=%s,interface_index=%s%s %s %d",
+		influxMeasurementEscaper.Replace(measurement),
+		influxKeyEscaper.Replace(r.Host),
+		influxKeyEscaper.Replace(influxTagValue(r.Index)),
+		influxIndexTags(r.IndexValues),
+		fields,
+		r.Timestamp.UnixNano(),
+	), true
+}
+
+// RowLine converts a table row to a line of InfluxDB line protocol. The table
+// is used as the measurement, each column as a field, and the host and row
+// index as tags. False is returned for rows without any values
+func (s *InfluxSink) RowLine(r Row) (string, bool) {
+	fields := []string{}
+	for _, c := range r.Cells {
+		value, ok := s.fieldValue(c.Readable())
+		if !ok {
+			continue
+		}
+
+		fields = append(fields, influxKeyEscaper.Replace(c.Name)+"="+value)
+		if c.Display != "" {
+			fields = append(fields, influxKeyEscaper.Replace(c.Name+"_display")+`="`+influxStringEscaper.Replace(c.Display)+`"`)
+		}
+		if c.Rate != nil {
+			fields = append(fields, influxKeyEscaper.Replace(c.Name+"_rate")+"="+strconv.FormatFloat(c.Rate.PerSecond, 'f', -1, 64))
+		}
+	}
+
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s,host_queried=%s,index=%s%s %s %d",
+		influxMeasurementEscaper.Replace(r.Table),
+		influxKeyEscaper.Replace(r.Host),
+		influxKeyEscaper.Replace(influxTagValue(r.Index)),
+		influxIndexTags(r.IndexValues),
+		strings.Join(fields, ","),
+		r.Timestamp.UnixNano(),
+	), true
+}
+
+// TrapLine converts a trap to a line of InfluxDB line protocol. Traps are
+// written to the snmp_traps measurement, tagged with the host, version and
+// trap name, with a count field of 1 and a field per variable
+func (s *InfluxSink) TrapLine(t Trap) string {
+	name := t.Name
+	if name == "" {
+		name = t.OID
+	}
+
+	fields := []string{"count=1i"}
+	for _, v := range t.Varbinds {
+		value, ok := s.fieldValue(v.Readable())
+		if !ok {
+			continue
+		}
+
+		field := VarbindName(v)
+		fields = append(fields, influxKeyEscaper.Replace(field)+"="+value)
+		if v.Display != "" {
+			fields = append(fields, influxKeyEscaper.Replace(field+"_display")+`="`+influxStringEscaper.Replace(v.Display)+`"`)
+		}
+	}
+
+	return fmt.Sprintf("%s,host=%s,trap=%s,version=%s %s %d",
+		influxTrapMeasurement,
+		influxKeyEscaper.Replace(influxTagValue(t.Host)),
+		influxKeyEscaper.Replace(influxTagValue(name)),
+		influxKeyEscaper.Replace(influxTagValue(t.Version)),
+		strings.Join(fields, ","),
+		t.Timestamp.UnixNano(),
+	)
+}
+
+// influxIndexTags renders the decoded components of an index as additional
+// tags, one per component
+func influxIndexTags(values []mib.IndexValue) string {
+	tags := ""
+	for _, v := range values {
+		tags += "," + influxKeyEscaper.Replace(v.Name) + "=" + influxKeyEscaper.Replace(influxTagValue(v.String()))
+	}
+
+	return tags
+}
+
+// Close closes the output file, if one is in use
+func (s *InfluxSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok && s.w != os.Stdout {
+		return c.Close()
+	}
+
+	return nil
+}
+
+func (s *InfluxSink) fieldValue(v interface{}) (string, bool) {
+	switch n := v.(type) {
+	case uint64:
+		if s.Unsigned {
+			return strconv.FormatUint(n, 10) + "u", true
+		}
+		if n > math.MaxInt64 {
+			// Writing a float would conflict with the type of the field
+			logrus.WithField("value", n).Warnln("Skipping unsigned value which does not fit an integer field, enable the unsigned option to write it")
+			return "", false
+		}
+		return strconv.FormatUint(n, 10) + "i", true
+	case int64:
+		return strconv.FormatInt(n, 10) + "i", true
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64), true
+	case string:
+		return `"` + influxStringEscaper.Replace(n) + `"`, true
+	case nil:
+		return "", false
+	default:
+		return `"` + influxStringEscaper.Replace(fmt.Sprint(n)) + `"`, true
+	}
+}
+
+// influxTagValue ensures tag values are never empty, which InfluxDB rejects
+func influxTagValue(v string) string {
+	if v == "" {
+		return "none"
+	}
+
+	return v
+}
@@ -0,0 +1,229 @@
+package libinquirer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+func influxTestRecords() []Record {
+	ts := time.Unix(1500000000, 123)
+	return []Record{
+		{
+			Host:      localhost,
+			BaseOID:   ".1.3.6.1.2.1.31.1.1.1.6",
+			MIBName:   "IF-MIB::ifHCInOctets",
+			Index:     "1",
+			PDUType:   gosnmp.Counter64,
+			Value:     uint64(1000),
+			Rate:      &Rate{PerSecond: 12.5},
+			Timestamp: ts,
+		},
+		{
+			Host:      localhost,
+			BaseOID:   ".1.3.6.1.2.1.31.1.1.1.18",
+			MIBName:   "IF-MIB::ifAlias",
+			Index:     "1",
+			PDUType:   gosnmp.OctetString,
+			Value:     `uplink "core"`,
+			Timestamp: ts,
+		},
+	}
+}
+
+func TestInfluxLine(t *testing.T) {
+	s := &InfluxSink{}
+	expected := []string{
+		`IF-MIB,host_queried=127.0.0.1,interface_index=1 ifHCInOctets=1000i,ifHCInOctets_rate=12.5 1500000000000000123`,
+		`IF-MIB,host_queried=127.0.0.1,interface_index=1 ifAlias="uplink \"core\"" 1500000000000000123`,
+	}
+
+	records := append(influxTestRecords(), Record{
+		Host:      localhost,
+		BaseOID:   ".1.3.6.1.2.1.2.2.1.8",
+		MIBName:   "IF-MIB::ifOperStatus",
+		Index:     "1",
+		PDUType:   gosnmp.Integer,
+		Value:     int64(2),
+		Display:   "down",
+		Timestamp: time.Unix(1500000000, 123),
+	})
+	expected = append(expected, `IF-MIB,host_queried=127.0.0.1,interface_index=1 ifOperStatus=2i,ifOperStatus_display="down" 1500000000000000123`)
+
+	for i, r := range records {
+		if line, ok := s.Line(r); !ok || line != expected[i] {
+			logrus.WithFields(logrus.Fields{
+				"expected": expected[i],
+				"line":     line,
+			}).Errorln("Incorrect line protocol generated")
+			t.Fail()
+		}
+	}
+}
+
+func TestInfluxLineValues(t *testing.T) {
+	s := &InfluxSink{}
+	if v, _ := s.fieldValue(uint64(math.MaxInt64)); v != "9223372036854775807i" {
+		logrus.WithField("value", v).Errorln("Unsigned value was not written as an integer")
+		t.Fail()
+	}
+	if v, ok := s.fieldValue(uint64(math.MaxUint64)); ok {
+		logrus.WithField("value", v).Errorln("Unsigned value which does not fit an integer field was written")
+		t.Fail()
+	}
+
+	s.Unsigned = true
+	for n, expected := range map[uint64]string{42: "42u", math.MaxInt64: "9223372036854775807u", math.MaxUint64: "18446744073709551615u"} {
+		if v, _ := s.fieldValue(n); v != expected {
+			logrus.WithField("value", v).Errorln("Unsigned value was not written with the u suffix")
+			t.Fail()
+		}
+	}
+
+	line, _ := s.Line(Record{Host: localhost, BaseOID: ".1.3.6.1.2.1.2.2.1.6", MIBName: "IF-MIB::ifPhysAddress", Index: "1", PDUType: gosnmp.OctetString, Value: "\x00\x1a\x2b\xff"})
+	if !strings.Contains(line, `ifPhysAddress="0x001a2bff"`) {
+		logrus.WithField("line", line).Errorln("Binary octet string was not written as hex")
+		t.Fail()
+	}
+
+	if _, ok := s.Line(Record{Value: nil}); ok {
+		logrus.Errorln("Line generated for a record without a value")
+		t.Fail()
+	}
+}
+
+func TestInfluxLineWithoutMIBName(t *testing.T) {
+	s := &InfluxSink{}
+	line, _ := s.Line(Record{Host: localhost, BaseOID: ".1.3.6.1.2.1.2.2.1.19", Index: "", Value: int64(3), Timestamp: time.Unix(1, 0)})
+	if line != `snmp,host_queried=127.0.0.1,interface_index=none 1.3.6.1.2.1.2.2.1.19=3i 1000000000` {
+		logrus.WithField("line", line).Errorln("Incorrect line generated for record without a MIB name")
+		t.Fail()
+	}
+}
+
+func TestInfluxSinkFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inquirer2")
+	if err != nil {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "influx.txt")
+	s, err := NewSink(OutputConfiguration{Type: InfluxOutput, Target: path})
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to create InfluxDB file sink")
+		t.FailNow()
+	}
+
+	if err = s.Write(influxTestRecords()); err != nil {
+		logrus.WithError(err).Errorln("Failed to write InfluxDB file")
+		t.Fail()
+	}
+	s.Close()
+
+	content, _ := ioutil.ReadFile(path)
+	if strings.Count(string(content), "\n") != 2 {
+		logrus.WithField("content", string(content)).Errorln("Incorrect InfluxDB file written")
+		t.Fail()
+	}
+}
+
+func TestInfluxSinkHTTP(t *testing.T) {
+	var received bytes.Buffer
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Query().Get("db") != "snmp" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received.ReadFrom(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s, err := NewSink(OutputConfiguration{Type: InfluxOutput, Target: server.URL + "/write?db=snmp"})
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to create InfluxDB HTTP sink")
+		t.FailNow()
+	}
+	defer s.Close()
+
+	if err = s.Write(influxTestRecords()); err != nil {
+		logrus.WithError(err).Errorln("Failed to write to InfluxDB HTTP endpoint")
+		t.Fail()
+	}
+
+	if !strings.Contains(received.String(), "ifHCInOctets=1000i") {
+		logrus.WithField("body", received.String()).Errorln("Line protocol was not received")
+		t.Fail()
+	}
+}
+
+func TestInfluxSinkHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"database not found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	s, _ := NewSink(OutputConfiguration{Type: InfluxOutput, Target: server.URL + "/write?db=missing"})
+	if err := s.Write(influxTestRecords()); err == nil {
+		logrus.Errorln("InfluxDB write error was not returned")
+		t.Fail()
+	}
+}
+
+func TestInfluxRowLine(t *testing.T) {
+	s := &InfluxSink{}
+	line, ok := s.RowLine(Row{
+		Host:  localhost,
+		Table: "ifXTable",
+		Index: "1",
+		IndexValues: []mib.IndexValue{
+			{Name: "ifIndex", Type: "InterfaceIndex", Value: int64(1), Display: "1"},
+		},
+		Cells: []Cell{
+			{Name: "ifName", PDUType: gosnmp.OctetString, Value: "ge-0/0/0"},
+			{Name: "ifHCInOctets", PDUType: gosnmp.Counter64, Value: uint64(1000), Rate: &Rate{PerSecond: 12.5}},
+			{Name: "ifOperStatus", PDUType: gosnmp.Integer, Value: int64(1), Display: "up"},
+		},
+		Timestamp: time.Unix(1500000000, 123),
+	})
+
+	expected := `ifXTable,host_queried=127.0.0.1,index=1,ifIndex=1 ifName="ge-0/0/0",ifHCInOctets=1000i,ifHCInOctets_rate=12.5,ifOperStatus=1i,ifOperStatus_display="up" 1500000000000000123`
+	if !ok || line != expected {
+		logrus.WithFields(logrus.Fields{
+			"expected": expected,
+			"line":     line,
+		}).Errorln("Incorrect row line protocol generated")
+		t.Fail()
+	}
+
+	if _, ok = s.RowLine(Row{Table: "ifXTable"}); ok {
+		logrus.Errorln("Line generated for an empty row")
+		t.Fail()
+	}
+}
+
+func TestInfluxTrapLine(t *testing.T) {
+	s := &InfluxSink{}
+	line := s.TrapLine(testTrap())
+
+	expected := `snmp_traps,host=127.0.0.1,trap=IF-MIB::linkDown,version=v2c count=1i,ifOperStatus.3=2i,ifOperStatus.3_display="down",.1.3.6.1.4.1.99999.1.0="port flap" 1500000000000000000`
+	if line != expected {
+		logrus.WithFields(logrus.Fields{
+			"expected": expected,
+			"line":     line,
+		}).Errorln("Incorrect trap line protocol generated")
+		t.Fail()
+	}
+}
@@ -0,0 +1,308 @@
+package libinquirer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/pkg/errors"
+	"github.com/soniah/gosnmp"
+)
+
+// JSONOutput is the output type which writes one JSON object per record
+const JSONOutput = "json"
+
+// jsonRecord is the JSON representation of a record. Field names match those
+// used by the logrus output and must remain stable
+type jsonRecord struct {
+	Timestamp   time.Time   `json:"timestamp"`
+	Host        string      `json:"host_queried"`
+	FullOID     string      `json:"full_oid"`
+	OID         string      `json:"oid"`
+	OIDName     string      `json:"oid_name"`
+	Index       string      `json:"interface_index"`
+	IndexFields jsonIndex   `json:"index_fields,omitempty"`
+	PDUType     string      `json:"pdu_type"`
+	PDUTypeName string      `json:"pdu_type_name"`
+	Value       interface{} `json:"value"`
+	ValueHex    string      `json:"value_hex,omitempty"`
+	Display     string      `json:"display,omitempty"`
+	Delta       *uint64     `json:"delta,omitempty"`
+	Rate        *float64    `json:"rate,omitempty"`
+	Wrapped     *bool       `json:"wrapped,omitempty"`
+}
+
+// jsonRow is the JSON representation of a table row, with each column keyed
+// by name
+type jsonRow struct {
+	Timestamp   time.Time           `json:"timestamp"`
+	Host        string              `json:"host_queried"`
+	Table       string              `json:"table"`
+	Index       string              `json:"index"`
+	IndexFields jsonIndex           `json:"index_fields,omitempty"`
+	Columns     map[string]jsonCell `json:"columns"`
+}
+
+// jsonIndex holds the decoded components of an index keyed by name
+type jsonIndex map[string]interface{}
+
+func newJSONIndex(values []mib.IndexValue) jsonIndex {
+	if len(values) == 0 {
+		return nil
+	}
+
+	index := jsonIndex{}
+	for _, v := range values {
+		index[v.Name] = v.Value
+	}
+
+	return index
+}
+
+// jsonTrap is the JSON representation of a trap, with its variables in the
+// order they were received
+type jsonTrap struct {
+	Timestamp    time.Time     `json:"timestamp"`
+	Host         string        `json:"host"`
+	Version      string        `json:"version"`
+	Username     string        `json:"username,omitempty"`
+	Inform       bool          `json:"inform"`
+	TrapOID      string        `json:"trap_oid"`
+	TrapName     string        `json:"trap_name,omitempty"`
+	Uptime       uint32        `json:"uptime"`
+	AgentAddress string        `json:"agent_address,omitempty"`
+	Varbinds     []jsonVarbind `json:"varbinds"`
+}
+
+type jsonVarbind struct {
+	OID         string      `json:"oid"`
+	Name        string      `json:"name,omitempty"`
+	Index       string      `json:"index,omitempty"`
+	IndexFields jsonIndex   `json:"index_fields,omitempty"`
+	PDUType     string      `json:"pdu_type"`
+	PDUTypeName string      `json:"pdu_type_name"`
+	Value       interface{} `json:"value"`
+	ValueHex    string      `json:"value_hex,omitempty"`
+	Display     string      `json:"display,omitempty"`
+}
+
+type jsonCell struct {
+	OID         string      `json:"oid"`
+	PDUType     string      `json:"pdu_type"`
+	PDUTypeName string      `json:"pdu_type_name"`
+	Value       interface{} `json:"value"`
+	ValueHex    string      `json:"value_hex,omitempty"`
+	Display     string      `json:"display,omitempty"`
+	Delta       *uint64     `json:"delta,omitempty"`
+	Rate        *float64    `json:"rate,omitempty"`
+	Wrapped     *bool       `json:"wrapped,omitempty"`
+}
+
+// JSONSink writes records as JSON lines, one object per record. Counters and
+// other numeric values are JSON numbers, octet strings are strings and OID
+// values are arrays of numbers. Octet strings which are not printable are
+// also written hex encoded as value_hex
+type JSONSink struct {
+	sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewJSONSink creates a sink writing to the target provided. An empty target
+// or - writes to stdout, anything else is treated as a file path
+func NewJSONSink(o OutputConfiguration) (*JSONSink, error) {
+	var w io.Writer = os.Stdout
+	if o.Target != "" && o.Target != "-" {
+		f, err := os.OpenFile(o.Target, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not open JSON output file %s", o.Target)
+		}
+		w = f
+	}
+
+	return newJSONSink(w), nil
+}
+
+func newJSONSink(w io.Writer) *JSONSink {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	return &JSONSink{w: w, enc: enc}
+}
+
+// Write writes one line per record
+func (s *JSONSink) Write(records []Record) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, r := range records {
+		jr := jsonRecord{
+			Timestamp:   r.Timestamp,
+			Host:        r.Host,
+			FullOID:     r.FullOID,
+			OID:         r.BaseOID,
+			OIDName:     r.MIBName,
+			Index:       r.Index,
+			IndexFields: newJSONIndex(r.IndexValues),
+			PDUType:     fmt.Sprintf("0x%x", byte(r.PDUType)),
+			PDUTypeName: PDUTypeName(r.PDUType),
+			Value:       jsonValue(r.PDUType, r.Value),
+			ValueHex:    jsonValueHex(r.PDUType, r.Value),
+			Display:     r.Display,
+		}
+
+		if r.Rate != nil {
+			jr.Delta = &r.Rate.Delta
+			jr.Rate = &r.Rate.PerSecond
+			jr.Wrapped = &r.Rate.Wrapped
+		}
+
+		if err := s.enc.Encode(jr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteRows writes one JSON object per table row
+func (s *JSONSink) WriteRows(rows []Row) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, r := range rows {
+		jr := jsonRow{
+			Timestamp:   r.Timestamp,
+			Host:        r.Host,
+			Table:       r.Table,
+			Index:       r.Index,
+			IndexFields: newJSONIndex(r.IndexValues),
+			Columns:     map[string]jsonCell{},
+		}
+
+		for _, c := range r.Cells {
+			cell := jsonCell{
+				OID:         c.OID,
+				PDUType:     fmt.Sprintf("0x%x", byte(c.PDUType)),
+				PDUTypeName: PDUTypeName(c.PDUType),
+				Value:       jsonValue(c.PDUType, c.Value),
+				ValueHex:    jsonValueHex(c.PDUType, c.Value),
+				Display:     c.Display,
+			}
+			if c.Rate != nil {
+				cell.Delta = &c.Rate.Delta
+				cell.Rate = &c.Rate.PerSecond
+				cell.Wrapped = &c.Rate.Wrapped
+			}
+			jr.Columns[c.Name] = cell
+		}
+
+		if err := s.enc.Encode(jr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteTraps writes one JSON object per trap
+func (s *JSONSink) WriteTraps(traps []Trap) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, t := range traps {
+		if err := s.enc.Encode(newJSONTrap(t)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newJSONTrap converts a trap to its JSON representation
+func newJSONTrap(t Trap) jsonTrap {
+	jt := jsonTrap{
+		Timestamp:    t.Timestamp,
+		Host:         t.Host,
+		Version:      t.Version,
+		Username:     t.Username,
+		Inform:       t.Inform,
+		TrapOID:      t.OID,
+		TrapName:     t.Name,
+		Uptime:       t.Uptime,
+		AgentAddress: t.AgentAddress,
+		Varbinds:     []jsonVarbind{},
+	}
+
+	for _, v := range t.Varbinds {
+		jt.Varbinds = append(jt.Varbinds, jsonVarbind{
+			OID:         v.FullOID,
+			Name:        v.MIBName,
+			Index:       v.Index,
+			IndexFields: newJSONIndex(v.IndexValues),
+			PDUType:     fmt.Sprintf("0x%x", byte(v.PDUType)),
+			PDUTypeName: PDUTypeName(v.PDUType),
+			Value:       jsonValue(v.PDUType, v.Value),
+			ValueHex:    jsonValueHex(v.PDUType, v.Value),
+			Display:     v.Display,
+		})
+	}
+
+	return jt
+}
+
+// Close closes the output file, if one is in use
+func (s *JSONSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok && s.w != os.Stdout {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// jsonValue converts OID values to arrays of arcs, leaving other values as
+// they are
+func jsonValue(t gosnmp.Asn1BER, v interface{}) interface{} {
+	if t == gosnmp.ObjectIdentifier {
+		if oid, ok := v.(string); ok {
+			return OIDArcs(oid)
+		}
+	}
+
+	return v
+}
+
+// jsonValueHex hex encodes octet strings which are not printable, such as
+// MAC addresses without a MIB definition, which JSON can not represent
+// exactly as strings. Other values are written only as the value
+func jsonValueHex(t gosnmp.Asn1BER, v interface{}) string {
+	switch t {
+	case gosnmp.OctetString, gosnmp.BitString, gosnmp.Opaque:
+		if s, ok := v.(string); ok && !mib.IsPrintable([]byte(s)) {
+			return hex.EncodeToString([]byte(s))
+		}
+	}
+
+	return ""
+}
+
+// OIDArcs splits a dotted OID into its numeric arcs
+func OIDArcs(oid string) []uint64 {
+	arcs := []uint64{}
+	for _, arc := range strings.Split(strings.Trim(oid, "."), ".") {
+		n, err := strconv.ParseUint(arc, 10, 64)
+		if err != nil {
+			continue
+		}
+		arcs = append(arcs, n)
+	}
+
+	return arcs
+}
@@ -0,0 +1,124 @@
+package libinquirer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+func TestJSONSinkWrite(t *testing.T) {
+	var buf bytes.Buffer
+	s := newJSONSink(&buf)
+
+	ts := time.Unix(1500000000, 0).UTC()
+	err := s.Write([]Record{
+		{Host: localhost, FullOID: ".1.3.6.1.2.1.31.1.1.1.6.1", BaseOID: ".1.3.6.1.2.1.31.1.1.1.6", MIBName: "IF-MIB::ifHCInOctets", Index: "1", PDUType: gosnmp.Counter64, Value: uint64(18446744073709551615), Rate: &Rate{Delta: 10, PerSecond: 1}, Timestamp: ts},
+		{Host: localhost, FullOID: ".1.3.6.1.2.1.31.1.1.1.1.1", PDUType: gosnmp.OctetString, Value: "ge-0/0/0", Timestamp: ts},
+		{Host: localhost, FullOID: ".1.3.6.1.2.1.1.2.0", PDUType: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.2636.1.1.1.2.29", Timestamp: ts},
+		{Host: localhost, FullOID: ".1.3.6.1.2.1.2.2.1.8.1", PDUType: gosnmp.Integer, Value: int64(2), Display: "down", Timestamp: ts},
+		{Host: localhost, FullOID: ".1.3.6.1.2.1.2.2.1.6.1", PDUType: gosnmp.OctetString, Value: "\x00\x1a\x2b\xff", Timestamp: ts},
+	})
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to write JSON records")
+		t.FailNow()
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		logrus.WithField("output", buf.String()).Errorln("Incorrect number of JSON lines written")
+		t.FailNow()
+	}
+
+	expected := []string{
+		`{"timestamp":"2017-07-14T02:40:00Z","host_queried":"127.0.0.1","full_oid":".1.3.6.1.2.1.31.1.1.1.6.1","oid":".1.3.6.1.2.1.31.1.1.1.6","oid_name":"IF-MIB::ifHCInOctets","interface_index":"1","pdu_type":"0x46","pdu_type_name":"Counter64","value":18446744073709551615,"delta":10,"rate":1,"wrapped":false}`,
+		`"value":"ge-0/0/0"}`,
+		`"value":[1,3,6,1,4,1,2636,1,1,1,2,29]}`,
+		`"value":2,"display":"down"}`,
+		`,"value_hex":"001a2bff"}`,
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, expected[i]) {
+			logrus.WithFields(logrus.Fields{
+				"expected": expected[i],
+				"line":     line,
+			}).Errorln("Incorrect JSON line written")
+			t.Fail()
+		}
+
+		var decoded map[string]interface{}
+		if err = json.Unmarshal([]byte(line), &decoded); err != nil {
+			logrus.WithError(err).Errorln("Invalid JSON written")
+			t.Fail()
+		}
+	}
+}
+
+func TestOIDArcs(t *testing.T) {
+	arcs := OIDArcs(".1.3.6.1")
+	if len(arcs) != 4 || arcs[0] != 1 || arcs[3] != 1 {
+		logrus.WithField("arcs", arcs).Errorln("Incorrect OID arcs returned")
+		t.Fail()
+	}
+}
+
+func TestJSONSinkWriteRows(t *testing.T) {
+	var buf bytes.Buffer
+	s := newJSONSink(&buf)
+
+	err := s.WriteRows([]Row{{
+		Host:  localhost,
+		Table: "ifXTable",
+		Index: "1",
+		IndexValues: []mib.IndexValue{
+			{Name: "ifIndex", Type: "InterfaceIndex", Value: int64(1), Display: "1"},
+		},
+		Cells: []Cell{
+			{Name: "ifName", OID: ".1.3.6.1.2.1.31.1.1.1.1", PDUType: gosnmp.OctetString, Value: "ge-0/0/0"},
+			{Name: "ifHCInOctets", OID: ".1.3.6.1.2.1.31.1.1.1.6", PDUType: gosnmp.Counter64, Value: uint64(1000), Rate: &Rate{Delta: 10, PerSecond: 1}},
+		},
+		Timestamp: time.Unix(1500000000, 0).UTC(),
+	}})
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to write JSON rows")
+		t.FailNow()
+	}
+
+	expected := `{"timestamp":"2017-07-14T02:40:00Z","host_queried":"127.0.0.1","table":"ifXTable","index":"1","index_fields":{"ifIndex":1},"columns":{` +
+		`"ifHCInOctets":{"oid":".1.3.6.1.2.1.31.1.1.1.6","pdu_type":"0x46","pdu_type_name":"Counter64","value":1000,"delta":10,"rate":1,"wrapped":false},` +
+		`"ifName":{"oid":".1.3.6.1.2.1.31.1.1.1.1","pdu_type":"0x4","pdu_type_name":"OctetString","value":"ge-0/0/0"}}}`
+	if line := strings.TrimSpace(buf.String()); line != expected {
+		logrus.WithFields(logrus.Fields{
+			"expected": expected,
+			"line":     line,
+		}).Errorln("Incorrect JSON row written")
+		t.Fail()
+	}
+}
+
+func TestJSONSinkWriteTraps(t *testing.T) {
+	var buf bytes.Buffer
+	s := newJSONSink(&buf)
+
+	if err := s.WriteTraps([]Trap{testTrap()}); err != nil {
+		logrus.WithError(err).Errorln("Failed to write JSON trap")
+		t.FailNow()
+	}
+
+	expected := `{"timestamp":"2017-07-14T02:40:00Z","host":"127.0.0.1","version":"v2c","inform":false,` +
+		`"trap_oid":".1.3.6.1.6.3.1.1.5.3","trap_name":"IF-MIB::linkDown","uptime":1200,"varbinds":[` +
+		`{"oid":".1.3.6.1.2.1.2.2.1.8.3","name":"IF-MIB::ifOperStatus","index":"3","pdu_type":"0x2","pdu_type_name":"Integer","value":2,"display":"down"},` +
+		`{"oid":".1.3.6.1.4.1.99999.1.0","pdu_type":"0x4","pdu_type_name":"OctetString","value":"port flap"}]}`
+	if line := strings.TrimSpace(buf.String()); line != expected {
+		logrus.WithFields(logrus.Fields{
+			"expected": expected,
+			"line":     line,
+		}).Errorln("Incorrect JSON trap written")
+		t.Fail()
+	}
+}
@@ -0,0 +1,122 @@
+package libinquirer
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogrusSink logs each record using logrus, which is the original output
+// format of the poll command
+type LogrusSink struct {
+	Logger *logrus.Logger
+}
+
+// NewLogrusSink creates a sink logging to the standard logrus logger
+func NewLogrusSink() *LogrusSink {
+	return &LogrusSink{Logger: logrus.StandardLogger()}
+}
+
+// Write logs every record, with a field per decoded index component
+func (s *LogrusSink) Write(records []Record) error {
+	for _, r := range records {
+		fields := logrus.Fields{
+			"full_oid":        r.FullOID,
+			"host_queried":    r.Host,
+			"oid":             r.BaseOID,
+			"oid_name":        r.MIBName,
+			"interface_index": r.Index,
+			"pdu_type":        fmt.Sprintf("0x%x", byte(r.PDUType)),
+			"pdu_type_name":   PDUTypeName(r.PDUType),
+			"value":           r.Readable(),
+		}
+
+		if r.Display != "" {
+			fields["display"] = r.Display
+		}
+
+		for _, v := range r.IndexValues {
+			fields[v.Name] = v.String()
+		}
+
+		if r.Rate != nil {
+			fields["delta"] = r.Rate.Delta
+			fields["rate"] = r.Rate.PerSecond
+			fields["wrapped"] = r.Rate.Wrapped
+		}
+
+		s.Logger.WithFields(fields).Infoln("OID successfully retrieved")
+	}
+
+	return nil
+}
+
+// WriteRows logs each row as a single entry with a field per column and per
+// decoded index component. Rendered values and rates are added as
+// <column>_display and <column>_rate
+func (s *LogrusSink) WriteRows(rows []Row) error {
+	for _, r := range rows {
+		fields := logrus.Fields{
+			"host_queried": r.Host,
+			"table":        r.Table,
+			"index":        r.Index,
+		}
+
+		for _, v := range r.IndexValues {
+			fields[v.Name] = v.String()
+		}
+
+		for _, c := range r.Cells {
+			fields[c.Name] = c.Readable()
+			if c.Display != "" {
+				fields[c.Name+"_display"] = c.Display
+			}
+			if c.Rate != nil {
+				fields[c.Name+"_rate"] = c.Rate.PerSecond
+			}
+		}
+
+		s.Logger.WithFields(fields).Infoln("Table row successfully retrieved")
+	}
+
+	return nil
+}
+
+// WriteTraps logs each trap as a single entry with a field per variable,
+// named after its MIB object when known. Rendered values are preferred
+func (s *LogrusSink) WriteTraps(traps []Trap) error {
+	for _, t := range traps {
+		fields := logrus.Fields{
+			"host":      t.Host,
+			"version":   t.Version,
+			"inform":    t.Inform,
+			"trap_oid":  t.OID,
+			"trap_name": t.Name,
+			"uptime":    t.Uptime,
+		}
+
+		if t.Username != "" {
+			fields["username"] = t.Username
+		}
+		if t.AgentAddress != "" {
+			fields["agent_address"] = t.AgentAddress
+		}
+
+		for _, v := range t.Varbinds {
+			value := v.Readable()
+			if v.Display != "" {
+				value = v.Display
+			}
+			fields[VarbindName(v)] = value
+		}
+
+		s.Logger.WithFields(fields).Infoln("SNMP notification received")
+	}
+
+	return nil
+}
+
+// Close implements Sink, there is nothing to close for logrus
+func (s *LogrusSink) Close() error {
+	return nil
+}
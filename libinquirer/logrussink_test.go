@@ -0,0 +1,87 @@
+package libinquirer
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/soniah/gosnmp"
+)
+
+func TestLogrusSinkWrite(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	s := &LogrusSink{Logger: logger}
+
+	err := s.Write([]Record{{
+		Host:    localhost,
+		FullOID: ".1.3.6.1.2.1.31.1.1.1.1.1",
+		BaseOID: ".1.3.6.1.2.1.31.1.1.1.1",
+		MIBName: "IF-MIB::ifName",
+		Index:   "1",
+		PDUType: gosnmp.OctetString,
+		Value:   "ge-0/0/0",
+	}, {
+		Host:    localhost,
+		FullOID: ".1.3.6.1.2.1.2.2.1.6.1",
+		PDUType: gosnmp.OctetString,
+		Value:   "\x00\x1a\x2b\xff",
+	}})
+	if err != nil || len(hook.Entries) != 2 {
+		logrus.WithError(err).Errorln("Records were not logged")
+		t.FailNow()
+	}
+
+	entry := hook.Entries[0]
+	if entry.Data["value"] != "ge-0/0/0" || entry.Data["pdu_type_name"] != "OctetString" || entry.Data["interface_index"] != "1" {
+		logrus.WithField("fields", entry.Data).Errorln("Incorrect fields logged")
+		t.Fail()
+	}
+
+	if value := hook.Entries[1].Data["value"]; value != "0x001a2bff" {
+		logrus.WithField("value", value).Errorln("Binary octet string was not logged as hex")
+		t.Fail()
+	}
+}
+
+func TestLogrusSinkWriteRows(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	s := &LogrusSink{Logger: logger}
+
+	err := s.WriteRows([]Row{{
+		Host:  localhost,
+		Table: "ifXTable",
+		Index: "1",
+		Cells: []Cell{
+			{Name: "ifName", PDUType: gosnmp.OctetString, Value: "ge-0/0/0"},
+			{Name: "ifHCInOctets", PDUType: gosnmp.Counter64, Value: uint64(1000), Rate: &Rate{PerSecond: 12.5}},
+		},
+	}})
+	if err != nil || len(hook.Entries) != 1 {
+		logrus.WithError(err).Errorln("Row was not logged")
+		t.FailNow()
+	}
+
+	entry := hook.LastEntry()
+	if entry.Data["table"] != "ifXTable" || entry.Data["index"] != "1" || entry.Data["ifName"] != "ge-0/0/0" ||
+		entry.Data["ifHCInOctets"] != uint64(1000) || entry.Data["ifHCInOctets_rate"] != 12.5 {
+		logrus.WithField("fields", entry.Data).Errorln("Incorrect row fields logged")
+		t.Fail()
+	}
+}
+
+func TestLogrusSinkWriteTraps(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	s := &LogrusSink{Logger: logger}
+
+	if err := s.WriteTraps([]Trap{testTrap()}); err != nil || len(hook.Entries) != 1 {
+		logrus.WithError(err).Errorln("Trap was not logged")
+		t.FailNow()
+	}
+
+	entry := hook.LastEntry()
+	if entry.Data["trap_name"] != "IF-MIB::linkDown" || entry.Data["host"] != localhost || entry.Data["ifOperStatus.3"] != "down" ||
+		entry.Data[".1.3.6.1.4.1.99999.1.0"] != "port flap" {
+		logrus.WithField("fields", entry.Data).Errorln("Incorrect trap fields logged")
+		t.Fail()
+	}
+}
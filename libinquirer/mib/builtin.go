@@ -0,0 +1,122 @@
+package mib
+
+// builtinModules defines the base of the OID tree from the SMI modules so that
+// other modules resolve even when the SMI modules are not in a MIB directory.
+// The SMIv1 modules re-export the SNMPv2-SMI definitions so every node has a
+// single name. The common textual conventions are also defined. Loading the
+// real modules replaces these definitions
+const builtinModules = `
+SNMPv2-SMI DEFINITIONS ::= BEGIN
+zeroDotZero  OBJECT IDENTIFIER ::= { 0 0 }
+org          OBJECT IDENTIFIER ::= { iso 3 }
+dod          OBJECT IDENTIFIER ::= { org 6 }
+internet     OBJECT IDENTIFIER ::= { dod 1 }
+directory    OBJECT IDENTIFIER ::= { internet 1 }
+mgmt         OBJECT IDENTIFIER ::= { internet 2 }
+mib-2        OBJECT IDENTIFIER ::= { mgmt 1 }
+transmission OBJECT IDENTIFIER ::= { mib-2 10 }
+experimental OBJECT IDENTIFIER ::= { internet 3 }
+private      OBJECT IDENTIFIER ::= { internet 4 }
+enterprises  OBJECT IDENTIFIER ::= { private 1 }
+security     OBJECT IDENTIFIER ::= { internet 5 }
+snmpV2       OBJECT IDENTIFIER ::= { internet 6 }
+snmpDomains  OBJECT IDENTIFIER ::= { snmpV2 1 }
+snmpProxys   OBJECT IDENTIFIER ::= { snmpV2 2 }
+snmpModules  OBJECT IDENTIFIER ::= { snmpV2 3 }
+END
+
+RFC1155-SMI DEFINITIONS ::= BEGIN
+IMPORTS internet, directory, mgmt, experimental, private, enterprises
+    FROM SNMPv2-SMI;
+END
+
+RFC1213-MIB DEFINITIONS ::= BEGIN
+IMPORTS mib-2 FROM SNMPv2-SMI;
+END
+
+SNMPv2-TC DEFINITIONS ::= BEGIN
+DisplayString ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "255a"
+    SYNTAX       OCTET STRING (SIZE (0..255))
+PhysAddress ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "1x:"
+    SYNTAX       OCTET STRING
+MacAddress ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "1x:"
+    SYNTAX       OCTET STRING (SIZE (6))
+TruthValue ::= TEXTUAL-CONVENTION
+    SYNTAX       INTEGER { true(1), false(2) }
+TestAndIncr ::= TEXTUAL-CONVENTION
+    SYNTAX       INTEGER (0..2147483647)
+AutonomousType ::= TEXTUAL-CONVENTION
+    SYNTAX       OBJECT IDENTIFIER
+VariablePointer ::= TEXTUAL-CONVENTION
+    SYNTAX       OBJECT IDENTIFIER
+RowPointer ::= TEXTUAL-CONVENTION
+    SYNTAX       OBJECT IDENTIFIER
+RowStatus ::= TEXTUAL-CONVENTION
+    SYNTAX       INTEGER { active(1), notInService(2), notReady(3),
+                           createAndGo(4), createAndWait(5), destroy(6) }
+TimeStamp ::= TEXTUAL-CONVENTION
+    SYNTAX       TimeTicks
+TimeInterval ::= TEXTUAL-CONVENTION
+    SYNTAX       INTEGER (0..2147483647)
+DateAndTime ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "2d-1d-1d,1d:1d:1d.1d,1a1d:1d"
+    SYNTAX       OCTET STRING (SIZE (8 | 11))
+StorageType ::= TEXTUAL-CONVENTION
+    SYNTAX       INTEGER { other(1), volatile(2), nonVolatile(3),
+                           permanent(4), readOnly(5) }
+TDomain ::= TEXTUAL-CONVENTION
+    SYNTAX       OBJECT IDENTIFIER
+TAddress ::= TEXTUAL-CONVENTION
+    SYNTAX       OCTET STRING (SIZE (1..255))
+END
+
+INET-ADDRESS-MIB DEFINITIONS ::= BEGIN
+InetAddressType ::= TEXTUAL-CONVENTION
+    SYNTAX       INTEGER { unknown(0), ipv4(1), ipv6(2), ipv4z(3),
+                           ipv6z(4), dns(16) }
+InetAddress ::= TEXTUAL-CONVENTION
+    SYNTAX       OCTET STRING (SIZE (0..255))
+InetAddressIPv4 ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "1d.1d.1d.1d"
+    SYNTAX       OCTET STRING (SIZE (4))
+InetAddressIPv6 ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "2x:2x:2x:2x:2x:2x:2x:2x"
+    SYNTAX       OCTET STRING (SIZE (16))
+InetAddressPrefixLength ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "d"
+    SYNTAX       Unsigned32 (0..2040)
+InetPortNumber ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "d"
+    SYNTAX       Unsigned32 (0..65535)
+END
+`
+
+// baseTypes are the types defined by the SMI which are not resolved any
+// further, even when the SMI modules defining them are loaded
+var baseTypes = map[string]bool{
+	"INTEGER":           true,
+	"OCTET STRING":      true,
+	"OBJECT IDENTIFIER": true,
+	"BITS":              true,
+	"Integer32":         true,
+	"Unsigned32":        true,
+	"Counter32":         true,
+	"Counter64":         true,
+	"Gauge32":           true,
+	"TimeTicks":         true,
+	"IpAddress":         true,
+	"Opaque":            true,
+	"Counter":           true,
+	"Gauge":             true,
+	"NetworkAddress":    true,
+}
+
+// roots are the top level arcs which are not defined by any module
+var roots = map[string]uint32{
+	"ccitt":           0,
+	"iso":             1,
+	"joint-iso-ccitt": 2,
+}
@@ -0,0 +1,220 @@
+package mib
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// octetSpec is a single specification of an OCTET STRING DISPLAY-HINT, as
+// defined in RFC 2579 section 3.1
+type octetSpec struct {
+	repeat     bool
+	length     int
+	format     byte
+	separator  byte
+	terminator byte
+}
+
+// parseOctetHint parses a hint such as 1x: or 255a into its specifications
+func parseOctetHint(hint string) ([]octetSpec, error) {
+	specs := []octetSpec{}
+	for i := 0; i < len(hint); {
+		s := octetSpec{}
+		if hint[i] == '*' {
+			s.repeat = true
+			i++
+		}
+
+		start := i
+		for i < len(hint) && hint[i] >= '0' && hint[i] <= '9' {
+			i++
+		}
+		if start == i {
+			return nil, errors.Errorf("DISPLAY-HINT %q is missing an octet length", hint)
+		}
+		s.length, _ = strconv.Atoi(hint[start:i])
+		if s.length == 0 && !s.repeat {
+			return nil, errors.Errorf("DISPLAY-HINT %q has an octet length of zero", hint)
+		}
+
+		if i >= len(hint) || !strings.ContainsRune("xdoat", rune(hint[i])) {
+			return nil, errors.Errorf("DISPLAY-HINT %q has an invalid format", hint)
+		}
+		s.format = hint[i]
+		i++
+
+		if i < len(hint) && !isHintStart(hint[i]) {
+			s.separator = hint[i]
+			i++
+		}
+		if s.repeat && i < len(hint) && !isHintStart(hint[i]) {
+			s.terminator = hint[i]
+			i++
+		}
+
+		specs = append(specs, s)
+	}
+
+	if len(specs) == 0 {
+		return nil, errors.Errorf("empty DISPLAY-HINT")
+	}
+
+	return specs, nil
+}
+
+func isHintStart(c byte) bool {
+	return c == '*' || (c >= '0' && c <= '9')
+}
+
+// FormatOctets renders an OCTET STRING using its DISPLAY-HINT, such as 1x:
+// for MAC addresses. The last specification of the hint is reused until every
+// octet has been rendered
+func FormatOctets(hint string, b []byte) (string, error) {
+	specs, err := parseOctetHint(hint)
+	if err != nil {
+		return "", err
+	}
+
+	return formatOctetSpecs(specs, b)
+}
+
+func formatOctetSpecs(specs []octetSpec, b []byte) (string, error) {
+	var out strings.Builder
+	pos := 0
+	for i := 0; pos < len(b); i++ {
+		s := specs[len(specs)-1]
+		if i < len(specs) {
+			s = specs[i]
+		}
+		start := pos
+
+		count := 1
+		if s.repeat {
+			count = int(b[pos])
+			pos++
+		}
+
+		for r := 0; r < count && pos < len(b); r++ {
+			n := s.length
+			if pos+n > len(b) {
+				n = len(b) - pos
+			}
+			chunk := b[pos : pos+n]
+			pos += n
+
+			switch s.format {
+			case 'a', 't':
+				out.Write(chunk)
+			case 'x':
+				out.WriteString(fmt.Sprintf("%0*x", 2*n, new(big.Int).SetBytes(chunk)))
+			case 'd':
+				out.WriteString(new(big.Int).SetBytes(chunk).String())
+			case 'o':
+				out.WriteString(new(big.Int).SetBytes(chunk).Text(8))
+			}
+
+			if pos >= len(b) {
+				break
+			}
+			if s.repeat && r == count-1 && s.terminator != 0 {
+				out.WriteByte(s.terminator)
+			} else if s.separator != 0 {
+				out.WriteByte(s.separator)
+			}
+		}
+
+		// The last specification is reused, so one which consumes nothing
+		// would never finish
+		if pos == start {
+			return "", errors.Errorf("DISPLAY-HINT specification %d consumed no octets", i+1)
+		}
+	}
+
+	return out.String(), nil
+}
+
+// FormatInteger renders an INTEGER using its DISPLAY-HINT. The hint d-2
+// renders 1234 as 12.34, while x, o and b render the value in hexadecimal,
+// octal and binary
+func FormatInteger(hint string, v int64) (string, error) {
+	switch {
+	case hint == "x":
+		return strconv.FormatInt(v, 16), nil
+	case hint == "o":
+		return strconv.FormatInt(v, 8), nil
+	case hint == "b":
+		return strconv.FormatInt(v, 2), nil
+	case hint == "d":
+		return strconv.FormatInt(v, 10), nil
+	case strings.HasPrefix(hint, "d-"):
+		places, err := strconv.Atoi(hint[2:])
+		if err != nil || places < 0 {
+			return "", errors.Errorf("DISPLAY-HINT %q has an invalid number of decimal places", hint)
+		}
+
+		sign, digits := "", strconv.FormatInt(v, 10)
+		if v < 0 {
+			sign, digits = "-", digits[1:]
+		}
+		if places == 0 {
+			return sign + digits, nil
+		}
+		if len(digits) <= places {
+			digits = strings.Repeat("0", places-len(digits)+1) + digits
+		}
+
+		return sign + digits[:len(digits)-places] + "." + digits[len(digits)-places:], nil
+	}
+
+	return "", errors.Errorf("invalid INTEGER DISPLAY-HINT %q", hint)
+}
+
+// FormatInetAddress renders an InetAddress, which has no DISPLAY-HINT as its
+// format depends on the accompanying InetAddressType. The type is inferred
+// from the length of the address
+func FormatInetAddress(b []byte) string {
+	switch len(b) {
+	case net.IPv4len, net.IPv6len:
+		return net.IP(b).String()
+	case net.IPv4len + 4, net.IPv6len + 4:
+		// ipv4z and ipv6z addresses are followed by a zone index
+		zone := new(big.Int).SetBytes(b[len(b)-4:])
+		return net.IP(b[:len(b)-4]).String() + "%" + zone.String()
+	}
+
+	// Anything else is treated as a DNS name
+	return string(b)
+}
+
+// RawOctets returns octets as a string when printable, and otherwise as hex
+// with a 0x prefix
+func RawOctets(b []byte) string {
+	if !IsPrintable(b) {
+		return "0x" + hex.EncodeToString(b)
+	}
+
+	return string(b)
+}
+
+// IsPrintable reports whether octets can be output as text
+func IsPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,130 @@
+package mib
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestFormatOctets(t *testing.T) {
+	tests := []struct {
+		hint     string
+		value    []byte
+		expected string
+	}{
+		{"1x:", []byte{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}, "00:1a:2b:3c:4d:5e"},
+		{"255a", []byte("ge-0/0/0"), "ge-0/0/0"},
+		{"1d.1d.1d.1d", []byte{10, 0, 0, 1}, "10.0.0.1"},
+		{"2x:2x:2x:2x:2x:2x:2x:2x", []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, "2001:0db8:0000:0000:0000:0000:0000:0001"},
+		{"2d-1d-1d,1d:1d:1d.1d", []byte{0x07, 0xe2, 1, 2, 3, 4, 5, 6}, "2018-1-2,3:4:5.6"},
+		{"4d", []byte{0, 0, 1, 0}, "256"},
+		{"1o", []byte{8}, "10"},
+		{"*1x:/", []byte{2, 0xaa, 0xbb, 1, 0xcc}, "aa:bb/cc"},
+		{"1x:", []byte{}, ""},
+	}
+
+	for _, test := range tests {
+		s, err := FormatOctets(test.hint, test.value)
+		if err != nil {
+			logrus.WithError(err).WithField("hint", test.hint).Errorln("Failed to format octets")
+			t.Fail()
+			continue
+		}
+
+		if s != test.expected {
+			logrus.WithFields(logrus.Fields{"hint": test.hint, "value": s, "expected": test.expected}).Errorln("Formatted octets are invalid")
+			t.Fail()
+		}
+	}
+
+	for _, hint := range []string{"", "x", "1q", "*", "0a", "1x:0d"} {
+		if _, err := FormatOctets(hint, []byte{1, 2}); err == nil {
+			logrus.WithField("hint", hint).Errorln("Formatted octets with an invalid hint")
+			t.Fail()
+		}
+	}
+
+	if _, err := formatOctetSpecs([]octetSpec{{length: 0, format: 'x'}}, []byte{1}); err == nil {
+		logrus.Errorln("Formatted octets with a specification consuming no octets")
+		t.Fail()
+	}
+}
+
+func TestFormatInteger(t *testing.T) {
+	tests := []struct {
+		hint     string
+		value    int64
+		expected string
+	}{
+		{"d", 1234, "1234"},
+		{"d-2", 1234, "12.34"},
+		{"d-2", 5, "0.05"},
+		{"d-1", -15, "-1.5"},
+		{"x", 255, "ff"},
+		{"o", 8, "10"},
+		{"b", 5, "101"},
+	}
+
+	for _, test := range tests {
+		s, err := FormatInteger(test.hint, test.value)
+		if err != nil {
+			logrus.WithError(err).WithField("hint", test.hint).Errorln("Failed to format integer")
+			t.Fail()
+			continue
+		}
+
+		if s != test.expected {
+			logrus.WithFields(logrus.Fields{"hint": test.hint, "value": s, "expected": test.expected}).Errorln("Formatted integer is invalid")
+			t.Fail()
+		}
+	}
+
+	if _, err := FormatInteger("d-x", 1); err == nil {
+		logrus.Errorln("Formatted integer with an invalid hint")
+		t.Fail()
+	}
+}
+
+func TestFormatInetAddress(t *testing.T) {
+	tests := map[string][]byte{
+		"192.0.2.1":       {192, 0, 2, 1},
+		"192.0.2.1%3":     {192, 0, 2, 1, 0, 0, 0, 3},
+		"2001:db8::1":     {0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		"www.example.com": []byte("www.example.com"),
+	}
+
+	for expected, value := range tests {
+		if s := FormatInetAddress(value); s != expected {
+			logrus.WithFields(logrus.Fields{"value": s, "expected": expected}).Errorln("Formatted address is invalid")
+			t.Fail()
+		}
+	}
+}
+
+func TestType(t *testing.T) {
+	m := loadTestMIB(t)
+
+	node, err := m.Find("IF-MIB::ifPhysAddress")
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to find ifPhysAddress")
+		t.FailNow()
+	}
+
+	typ := m.Type(node)
+	if typ.Name != "PhysAddress" || typ.Base != "OCTET STRING" || typ.DisplayHint != "1x:" {
+		logrus.WithField("type", typ).Errorln("Resolved PhysAddress type is invalid")
+		t.Fail()
+	}
+
+	node, _ = m.Find("IF-MIB::ifIndex")
+	if typ = m.Type(node); typ.Name != "InterfaceIndex" || typ.Base != "Integer32" || typ.DisplayHint != "d" {
+		logrus.WithField("type", typ).Errorln("Resolved InterfaceIndex type is invalid")
+		t.Fail()
+	}
+
+	node, _ = m.Find("IF-MIB::ifHCInOctets")
+	if typ = m.Type(node); typ.Name != "Counter64" || typ.Base != "Counter64" {
+		logrus.WithField("type", typ).Errorln("Resolved Counter64 type is invalid")
+		t.Fail()
+	}
+}
@@ -0,0 +1,227 @@
+package mib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IndexValue is a single decoded component of a table index
+type IndexValue struct {
+	// Name is the object named in the INDEX clause, such as ifIndex
+	Name string
+	// Type is the textual convention or base type of the object
+	Type string
+	// Value is an int64 for signed integers, a uint64 for unsigned integers
+	// and a string for addresses, octet strings and OIDs
+	Value interface{}
+	// Display is the value rendered using the enumeration or DISPLAY-HINT
+	// of the object, empty when neither applies
+	Display string
+}
+
+// String returns the rendered value when there is one, and otherwise the
+// value itself
+func (v IndexValue) String() string {
+	if v.Display != "" {
+		return v.Display
+	}
+
+	return fmt.Sprint(v.Value)
+}
+
+// Entry returns the table entry which defines the index of a column,
+// following AUGMENTS clauses. Nil is returned when the node is not a column
+func (m *MIB) Entry(column *Node) *Node {
+	if column == nil || column.Kind != "OBJECT-TYPE" {
+		return nil
+	}
+
+	entry, ok := m.oids[column.OID[:strings.LastIndex(column.OID, ".")]]
+	for depth := 0; ok && depth < maxResolveDepth; depth++ {
+		if len(entry.Index) > 0 {
+			return entry
+		}
+		if entry.Augments == "" {
+			return nil
+		}
+
+		entry = m.symbol(entry.Module, entry.Augments)
+		ok = entry != nil
+	}
+
+	return nil
+}
+
+// symbol finds the node of a name as seen from a module
+func (m *MIB) symbol(module, name string) *Node {
+	if n, ok := m.nodes[module+"::"+name]; ok {
+		return n
+	}
+
+	if mod, ok := m.modules[module]; ok {
+		if from, ok := mod.Imports[name]; ok {
+			if n, ok := m.nodes[from+"::"+name]; ok {
+				return n
+			}
+		}
+	}
+
+	if nodes := m.names[name]; len(nodes) > 0 {
+		return nodes[0]
+	}
+
+	return nil
+}
+
+// DecodeIndex splits the index of a table row, such as 3.10.0.0.1, into the
+// objects of the entry's INDEX clause following the rules of RFC 2578
+// section 7.7. Integers take a single arc, IP addresses four arcs, fixed
+// length strings one arc per octet and other strings and OIDs are prefixed
+// by their length unless IMPLIED
+func (m *MIB) DecodeIndex(entry *Node, index string) ([]IndexValue, error) {
+	if entry == nil || len(entry.Index) == 0 {
+		return nil, errors.Errorf("no INDEX clause to decode %s with", index)
+	}
+
+	arcs := []uint64{}
+	for _, arc := range strings.Split(strings.Trim(index, "."), ".") {
+		n, err := strconv.ParseUint(arc, 10, 32)
+		if err != nil {
+			return nil, errors.Errorf("invalid index %s", index)
+		}
+		arcs = append(arcs, n)
+	}
+
+	values := []IndexValue{}
+	for i, part := range entry.Index {
+		node := m.symbol(entry.Module, part.Name)
+		t := m.Type(node)
+		if t == nil {
+			return nil, errors.Errorf("unknown index object %s of %s", part.Name, entry)
+		}
+
+		implied := part.Implied && i == len(entry.Index)-1
+		v := IndexValue{Name: part.Name, Type: t.Name}
+
+		var n int
+		var err error
+		switch t.Base {
+		case "INTEGER", "Integer32":
+			if n, err = take(arcs, 1, part.Name); err != nil {
+				return nil, err
+			}
+			value := int64(arcs[0])
+			v.Value = value
+			if label, ok := t.Enums[value]; ok {
+				v.Display = label
+			} else if t.DisplayHint != "" {
+				v.Display, _ = FormatInteger(t.DisplayHint, value)
+			}
+		case "Unsigned32", "Gauge32", "Counter32", "Counter64", "TimeTicks", "Gauge", "Counter":
+			if n, err = take(arcs, 1, part.Name); err != nil {
+				return nil, err
+			}
+			v.Value = arcs[0]
+		case "IpAddress", "NetworkAddress":
+			// SMIv1 network addresses are prefixed by their address family
+			offset := 0
+			if t.Base == "NetworkAddress" {
+				offset = 1
+			}
+			if n, err = take(arcs, 4+offset, part.Name); err != nil {
+				return nil, err
+			}
+			b, err := octets(arcs[offset:n], part.Name)
+			if err != nil {
+				return nil, err
+			}
+			v.Value = FormatInetAddress(b)
+		case "OBJECT IDENTIFIER":
+			start, length, err := lengthOf(arcs, implied, 0, part.Name)
+			if err != nil {
+				return nil, err
+			}
+			n = start + length
+			parts := make([]string, 0, length)
+			for _, arc := range arcs[start:n] {
+				parts = append(parts, strconv.FormatUint(arc, 10))
+			}
+			v.Value = "." + strings.Join(parts, ".")
+		default:
+			start, length, err := lengthOf(arcs, implied, t.Size, part.Name)
+			if err != nil {
+				return nil, err
+			}
+			n = start + length
+			b, err := octets(arcs[start:n], part.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			v.Value = RawOctets(b)
+			switch {
+			case t.Name == "InetAddress":
+				v.Display = FormatInetAddress(b)
+			case t.DisplayHint != "":
+				v.Display, _ = FormatOctets(t.DisplayHint, b)
+			case !IsPrintable(b):
+				v.Display, _ = FormatOctets("1x:", b)
+			}
+		}
+
+		values = append(values, v)
+		arcs = arcs[n:]
+	}
+
+	if len(arcs) > 0 {
+		return nil, errors.Errorf("index %s has %d arcs not covered by the INDEX of %s", index, len(arcs), entry)
+	}
+
+	return values, nil
+}
+
+// take checks that enough arcs remain for an index object
+func take(arcs []uint64, n int, name string) (int, error) {
+	if len(arcs) < n {
+		return 0, errors.Errorf("index is too short for %s", name)
+	}
+
+	return n, nil
+}
+
+// lengthOf returns where the value of a variable length index object starts
+// and how many arcs it covers
+func lengthOf(arcs []uint64, implied bool, size int, name string) (int, int, error) {
+	switch {
+	case implied:
+		return 0, len(arcs), nil
+	case size > 0:
+		_, err := take(arcs, size, name)
+		return 0, size, err
+	case len(arcs) == 0:
+		return 0, 0, errors.Errorf("index is too short for %s", name)
+	}
+
+	length := int(arcs[0])
+	if _, err := take(arcs, 1+length, name); err != nil {
+		return 0, 0, err
+	}
+
+	return 1, length, nil
+}
+
+// octets converts arcs to bytes, failing for arcs which are not octets
+func octets(arcs []uint64, name string) ([]byte, error) {
+	b := make([]byte, 0, len(arcs))
+	for _, arc := range arcs {
+		if arc > 255 {
+			return nil, errors.Errorf("index arc %d of %s is not an octet", arc, name)
+		}
+		b = append(b, byte(arc))
+	}
+
+	return b, nil
+}
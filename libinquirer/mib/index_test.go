@@ -0,0 +1,223 @@
+package mib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+const testIndexMIB = `
+TEST-INDEX-MIB DEFINITIONS ::= BEGIN
+IMPORTS
+    OBJECT-TYPE, IpAddress, Integer32, enterprises FROM SNMPv2-SMI
+    MacAddress, DisplayString FROM SNMPv2-TC;
+
+testIndex OBJECT IDENTIFIER ::= { enterprises 99998 }
+
+testNetTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF TestNetEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "A table indexed by an interface, IP and MAC address"
+    ::= { testIndex 1 }
+
+testNetEntry OBJECT-TYPE
+    SYNTAX      TestNetEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "An entry"
+    INDEX       { testNetIfIndex, testNetAddress, testNetMac }
+    ::= { testNetTable 1 }
+
+TestNetEntry ::= SEQUENCE {
+    testNetIfIndex  Integer32,
+    testNetAddress  IpAddress,
+    testNetMac      MacAddress,
+    testNetValue    Integer32
+}
+
+testNetIfIndex OBJECT-TYPE
+    SYNTAX      Integer32 (1..2147483647)
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "The interface"
+    ::= { testNetEntry 1 }
+
+testNetAddress OBJECT-TYPE
+    SYNTAX      IpAddress
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "The IP address"
+    ::= { testNetEntry 2 }
+
+testNetMac OBJECT-TYPE
+    SYNTAX      MacAddress
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "The MAC address"
+    ::= { testNetEntry 3 }
+
+testNetValue OBJECT-TYPE
+    SYNTAX      Integer32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "The value"
+    ::= { testNetEntry 4 }
+
+testNameTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF TestNameEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "A table indexed by an OID and an implied string"
+    ::= { testIndex 2 }
+
+testNameEntry OBJECT-TYPE
+    SYNTAX      TestNameEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "An entry"
+    INDEX       { testNameOID, IMPLIED testName }
+    ::= { testNameTable 1 }
+
+TestNameEntry ::= SEQUENCE {
+    testNameOID     OBJECT IDENTIFIER,
+    testName        DisplayString,
+    testNameValue   Integer32
+}
+
+testNameOID OBJECT-TYPE
+    SYNTAX      OBJECT IDENTIFIER
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "The OID"
+    ::= { testNameEntry 1 }
+
+testName OBJECT-TYPE
+    SYNTAX      DisplayString (SIZE (1..32))
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "The name"
+    ::= { testNameEntry 2 }
+
+testNameValue OBJECT-TYPE
+    SYNTAX      Integer32
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "The value"
+    ::= { testNameEntry 3 }
+
+END
+`
+
+func loadIndexMIB(t *testing.T) *MIB {
+	m := loadTestMIB(t)
+	if err := m.Parse(strings.NewReader(testIndexMIB)); err != nil {
+		logrus.WithError(err).Errorln("Failed to parse test MIB")
+		t.FailNow()
+	}
+
+	return m
+}
+
+func decodeColumnIndex(t *testing.T, m *MIB, column, index string) []IndexValue {
+	node, err := m.Find(column)
+	if err != nil {
+		logrus.WithError(err).WithField("column", column).Errorln("Failed to find column")
+		t.FailNow()
+	}
+
+	entry := m.Entry(node)
+	if entry == nil {
+		logrus.WithField("column", column).Errorln("Column has no table entry")
+		t.FailNow()
+	}
+
+	values, err := m.DecodeIndex(entry, index)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"column": column, "index": index}).Errorln("Failed to decode index")
+		t.FailNow()
+	}
+
+	return values
+}
+
+func checkIndexValues(t *testing.T, values, expected []IndexValue) {
+	if len(values) != len(expected) {
+		logrus.WithFields(logrus.Fields{"values": values, "expected": expected}).Errorln("Incorrect number of index components")
+		t.FailNow()
+	}
+
+	for i := range expected {
+		if values[i] != expected[i] {
+			logrus.WithFields(logrus.Fields{"value": values[i], "expected": expected[i]}).Errorln("Index component is invalid")
+			t.Fail()
+		}
+	}
+}
+
+func TestDecodeIndexComposite(t *testing.T) {
+	m := loadTestMIB(t)
+
+	// filter "ae0", counter "drops" of type counter(2)
+	values := decodeColumnIndex(t, m, "JUNIPER-FIREWALL-MIB::jnxFWCounterPacketCount", "3.97.101.48.5.100.114.111.112.115.2")
+	checkIndexValues(t, values, []IndexValue{
+		{Name: "jnxFWCounterFilterName", Type: "DisplayString", Value: "ae0", Display: "ae0"},
+		{Name: "jnxFWCounterName", Type: "DisplayString", Value: "drops", Display: "drops"},
+		{Name: "jnxFWCounterType", Type: "INTEGER", Value: int64(2), Display: "counter"},
+	})
+}
+
+func TestDecodeIndexAugments(t *testing.T) {
+	m := loadTestMIB(t)
+
+	values := decodeColumnIndex(t, m, "IF-MIB::ifHCInOctets", "12")
+	checkIndexValues(t, values, []IndexValue{
+		{Name: "ifIndex", Type: "InterfaceIndex", Value: int64(12), Display: "12"},
+	})
+}
+
+func TestDecodeIndexAddresses(t *testing.T) {
+	m := loadIndexMIB(t)
+
+	values := decodeColumnIndex(t, m, "TEST-INDEX-MIB::testNetValue", "4.10.0.0.1.0.26.43.60.77.94")
+	checkIndexValues(t, values, []IndexValue{
+		{Name: "testNetIfIndex", Type: "Integer32", Value: int64(4)},
+		{Name: "testNetAddress", Type: "IpAddress", Value: "10.0.0.1"},
+		{Name: "testNetMac", Type: "MacAddress", Value: "0x001a2b3c4d5e", Display: "00:1a:2b:3c:4d:5e"},
+	})
+}
+
+func TestDecodeIndexImplied(t *testing.T) {
+	m := loadIndexMIB(t)
+
+	values := decodeColumnIndex(t, m, "TEST-INDEX-MIB::testNameValue", "3.1.3.6.101.116.104.48")
+	checkIndexValues(t, values, []IndexValue{
+		{Name: "testNameOID", Type: "OBJECT IDENTIFIER", Value: ".1.3.6"},
+		{Name: "testName", Type: "DisplayString", Value: "eth0", Display: "eth0"},
+	})
+}
+
+func TestDecodeIndexInvalid(t *testing.T) {
+	m := loadIndexMIB(t)
+
+	tests := map[string]string{
+		"JUNIPER-FIREWALL-MIB::jnxFWCounterPacketCount": "3.97.101.48.5.100.114",
+		"TEST-INDEX-MIB::testNetValue":                  "4.10.0.0.1.0.26.43.60.77.94.1",
+		"TEST-INDEX-MIB::testNameValue":                 "3.1.3.6.256",
+		"IF-MIB::ifDescr":                               "1.x",
+	}
+
+	for column, index := range tests {
+		node, _ := m.Find(column)
+		if _, err := m.DecodeIndex(m.Entry(node), index); err == nil {
+			logrus.WithFields(logrus.Fields{"column": column, "index": index}).Errorln("Decoded invalid index")
+			t.Fail()
+		}
+	}
+
+	if entry := m.Entry(m.nodes["SNMPv2-MIB::sysName"]); entry != nil {
+		logrus.WithField("entry", entry).Errorln("Scalar has a table entry")
+		t.Fail()
+	}
+}
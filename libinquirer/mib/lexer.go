@@ -0,0 +1,231 @@
+package mib
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+type tokenKind int
+
+const (
+	identToken tokenKind = iota
+	numberToken
+	stringToken
+	symbolToken
+	eofToken
+)
+
+// token is a single lexical element of a MIB module
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// lexer splits SMIv1 and SMIv2 module definitions into tokens
+type lexer struct {
+	r    *bufio.Reader
+	line int
+}
+
+func newLexer(r io.Reader) *lexer {
+	return &lexer{r: bufio.NewReader(r), line: 1}
+}
+
+// tokenize returns every token of the input
+func tokenize(r io.Reader) ([]token, error) {
+	l := newLexer(r)
+	tokens := []token{}
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if t.kind == eofToken {
+			return tokens, nil
+		}
+		tokens = append(tokens, t)
+	}
+}
+
+func (l *lexer) read() (rune, bool) {
+	c, _, err := l.r.ReadRune()
+	if err != nil {
+		return 0, false
+	}
+	if c == '\n' {
+		l.line++
+	}
+
+	return c, true
+}
+
+func (l *lexer) unread(c rune) {
+	l.r.UnreadRune()
+	if c == '\n' {
+		l.line--
+	}
+}
+
+func (l *lexer) peek() (rune, bool) {
+	c, ok := l.read()
+	if ok {
+		l.unread(c)
+	}
+
+	return c, ok
+}
+
+func (l *lexer) next() (token, error) {
+	for {
+		c, ok := l.read()
+		if !ok {
+			return token{kind: eofToken, line: l.line}, nil
+		}
+
+		switch {
+		case unicode.IsSpace(c):
+			continue
+		case c == '-':
+			if n, ok := l.peek(); ok && n == '-' {
+				l.read()
+				l.skipComment()
+				continue
+			}
+			if n, ok := l.peek(); ok && unicode.IsDigit(n) {
+				return l.number(c), nil
+			}
+			return token{kind: symbolToken, text: "-", line: l.line}, nil
+		case c == '"':
+			return l.quoted()
+		case c == '\'':
+			return l.binaryString()
+		case unicode.IsDigit(c):
+			return l.number(c), nil
+		case unicode.IsLetter(c):
+			return l.identifier(c), nil
+		case c == ':':
+			if l.accept(":=") {
+				return token{kind: symbolToken, text: "::=", line: l.line}, nil
+			}
+			return token{kind: symbolToken, text: ":", line: l.line}, nil
+		case c == '.':
+			if l.accept(".") {
+				return token{kind: symbolToken, text: "..", line: l.line}, nil
+			}
+			return token{kind: symbolToken, text: ".", line: l.line}, nil
+		default:
+			return token{kind: symbolToken, text: string(c), line: l.line}, nil
+		}
+	}
+}
+
+// accept consumes s if it is next in the input
+func (l *lexer) accept(s string) bool {
+	b, err := l.r.Peek(len(s))
+	if err != nil || string(b) != s {
+		return false
+	}
+	l.r.Discard(len(s))
+
+	return true
+}
+
+// skipComment skips a comment which ends at the end of the line or at the
+// next --
+func (l *lexer) skipComment() {
+	for {
+		c, ok := l.read()
+		if !ok || c == '\n' {
+			return
+		}
+		if c == '-' {
+			if n, ok := l.peek(); ok && n == '-' {
+				l.read()
+				return
+			}
+		}
+	}
+}
+
+func (l *lexer) quoted() (token, error) {
+	start := l.line
+	var b strings.Builder
+	for {
+		c, ok := l.read()
+		if !ok {
+			return token{}, errors.Errorf("unterminated string starting on line %d", start)
+		}
+		if c == '"' {
+			return token{kind: stringToken, text: b.String(), line: start}, nil
+		}
+		b.WriteRune(c)
+	}
+}
+
+// binaryString reads a hex or binary string such as '00'H, which is only
+// used for default values and is kept as a string token
+func (l *lexer) binaryString() (token, error) {
+	start := l.line
+	var b strings.Builder
+	for {
+		c, ok := l.read()
+		if !ok {
+			return token{}, errors.Errorf("unterminated binary string starting on line %d", start)
+		}
+		if c == '\'' {
+			if n, ok := l.peek(); ok && (n == 'H' || n == 'h' || n == 'B' || n == 'b') {
+				l.read()
+			}
+			return token{kind: stringToken, text: b.String(), line: start}, nil
+		}
+		b.WriteRune(c)
+	}
+}
+
+func (l *lexer) number(first rune) token {
+	var b strings.Builder
+	b.WriteRune(first)
+	for {
+		c, ok := l.read()
+		if !ok {
+			break
+		}
+		if !unicode.IsDigit(c) {
+			l.unread(c)
+			break
+		}
+		b.WriteRune(c)
+	}
+
+	return token{kind: numberToken, text: b.String(), line: l.line}
+}
+
+func (l *lexer) identifier(first rune) token {
+	var b strings.Builder
+	b.WriteRune(first)
+	for {
+		// A double hyphen starts a comment rather than continuing the
+		// identifier. It is checked before reading, as only a single rune
+		// can be unread
+		if next, err := l.r.Peek(2); err == nil && string(next) == "--" {
+			break
+		}
+
+		c, ok := l.read()
+		if !ok {
+			break
+		}
+		if c != '-' && !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+			l.unread(c)
+			break
+		}
+		b.WriteRune(c)
+	}
+
+	return token{kind: identToken, text: b.String(), line: l.line}
+}
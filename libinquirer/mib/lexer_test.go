@@ -0,0 +1,39 @@
+package mib
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTokenizeIdentifierHyphens(t *testing.T) {
+	tests := map[string][]string{
+		"ifIndex-- a comment":       {"ifIndex"},
+		"ifIndex -- a comment":      {"ifIndex"},
+		"IF-MIB ::= BEGIN":          {"IF-MIB", "::=", "BEGIN"},
+		"a-b-c--comment--\nifIndex": {"a-b-c", "ifIndex"},
+	}
+
+	for input, expected := range tests {
+		tokens, err := tokenize(strings.NewReader(input))
+		if err != nil {
+			logrus.WithError(err).WithField("input", input).Errorln("Failed to tokenize input")
+			t.Fail()
+			continue
+		}
+
+		texts := []string{}
+		for _, token := range tokens {
+			texts = append(texts, token.text)
+		}
+		if strings.Join(texts, " ") != strings.Join(expected, " ") {
+			logrus.WithFields(logrus.Fields{
+				"input":    input,
+				"tokens":   texts,
+				"expected": expected,
+			}).Errorln("Input was tokenized incorrectly")
+			t.Fail()
+		}
+	}
+}
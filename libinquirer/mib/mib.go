@@ -0,0 +1,384 @@
+// Package mib parses SMIv1 and SMIv2 MIB modules and resolves symbolic names
+// such as IF-MIB::ifHCInOctets to numeric OIDs and back
+package mib
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const maxResolveDepth = 128
+
+// Node is an object of the OID tree defined by a MIB module
+type Node struct {
+	Module string
+	Name   string
+	// OID is the numeric OID of the node with a leading dot
+	OID string
+	// Kind is the macro which defined the node, such as OBJECT-TYPE
+	Kind   string
+	Syntax *Syntax
+	// Index holds the INDEX clause of table entries
+	Index []IndexPart
+	// Augments names the entry a table entry augments, sharing its index
+	Augments string
+}
+
+// String returns the name of the node qualified by its module, such as
+// IF-MIB::ifHCInOctets
+func (n *Node) String() string {
+	return n.Module + "::" + n.Name
+}
+
+// MIB is a set of loaded MIB modules. A MIB is safe for concurrent use once
+// loading has finished
+type MIB struct {
+	modules map[string]*Module
+	nodes   map[string]*Node
+	names   map[string][]*Node
+	oids    map[string]*Node
+}
+
+// New creates a MIB containing only the base of the OID tree, such as
+// SNMPv2-SMI::enterprises
+func New() *MIB {
+	m := &MIB{modules: map[string]*Module{}}
+
+	modules, err := parseModules(strings.NewReader(builtinModules))
+	if err != nil {
+		panic(err)
+	}
+	for _, module := range modules {
+		module.builtin = true
+		m.modules[module.Name] = module
+	}
+	m.build()
+
+	return m
+}
+
+// LoadDirs loads every MIB module found in the directories provided. Files
+// which can not be parsed are logged and skipped
+func (m *MIB) LoadDirs(dirs ...string) error {
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return errors.Wrapf(err, "could not read MIB directory %s", dir)
+		}
+
+		for _, f := range files {
+			if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+				continue
+			}
+
+			path := filepath.Join(dir, f.Name())
+			if err = m.load(path); err != nil {
+				logrus.WithError(err).WithField("file", path).Warnln("Skipping MIB file which could not be parsed")
+			}
+		}
+	}
+	m.build()
+
+	return nil
+}
+
+// LoadFile loads the MIB modules defined in a single file
+func (m *MIB) LoadFile(path string) error {
+	if err := m.load(path); err != nil {
+		return err
+	}
+	m.build()
+
+	return nil
+}
+
+// Parse loads the MIB modules read from r
+func (m *MIB) Parse(r io.Reader) error {
+	modules, err := parseModules(r)
+	if err != nil {
+		return err
+	}
+	m.add(modules)
+	m.build()
+
+	return nil
+}
+
+func (m *MIB) load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	modules, err := parseModules(f)
+	if err != nil {
+		return err
+	}
+	m.add(modules)
+
+	return nil
+}
+
+func (m *MIB) add(modules []*Module) {
+	for _, module := range modules {
+		logrus.WithField("module", module.Name).Debugln("Loaded MIB module")
+		m.modules[module.Name] = module
+	}
+}
+
+// Module returns a loaded module by name
+func (m *MIB) Module(name string) (*Module, bool) {
+	module, ok := m.modules[name]
+	return module, ok
+}
+
+// Resolve converts a name such as IF-MIB::ifHCInOctets, ifHCInOctets or
+// IF-MIB::ifHCInOctets.1 to a numeric OID with a leading dot. Numeric OIDs
+// are returned normalized, with a leading dot added when missing
+func (m *MIB) Resolve(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.Errorf("empty OID")
+	}
+
+	if name[0] == '.' || (name[0] >= '0' && name[0] <= '9') {
+		oid, err := NormalizeOID(name)
+		if err != nil {
+			return "", err
+		}
+		return oid, nil
+	}
+
+	node, suffix, err := m.find(name)
+	if err != nil {
+		return "", err
+	}
+
+	return node.OID + suffix, nil
+}
+
+// Find returns the node of a name such as IF-MIB::ifHCInOctets or
+// ifHCInOctets
+func (m *MIB) Find(name string) (*Node, error) {
+	node, suffix, err := m.find(name)
+	if err != nil {
+		return nil, err
+	}
+	if suffix != "" {
+		return nil, errors.Errorf("%s refers to an instance rather than an object", name)
+	}
+
+	return node, nil
+}
+
+func (m *MIB) find(name string) (*Node, string, error) {
+	module, object := "", name
+	if i := strings.Index(name, "::"); i >= 0 {
+		module, object = name[:i], name[i+2:]
+	}
+
+	suffix := ""
+	if i := strings.Index(object, "."); i >= 0 {
+		object, suffix = object[:i], object[i:]
+		if _, err := NormalizeOID(suffix); err != nil {
+			return nil, "", errors.Wrapf(err, "invalid instance of %s", name)
+		}
+	}
+
+	var node *Node
+	if module != "" {
+		node = m.nodes[module+"::"+object]
+	} else if nodes := m.names[object]; len(nodes) > 0 {
+		node = nodes[0]
+	}
+
+	if node == nil {
+		return nil, "", errors.Errorf("unknown object %s", name)
+	}
+
+	return node, suffix, nil
+}
+
+// Lookup finds the node which is the longest prefix of a numeric OID. The
+// remaining arcs are returned as the index, such as 1 for
+// .1.3.6.1.2.1.31.1.1.1.6.1. A nil node is returned when no prefix is known
+func (m *MIB) Lookup(oid string) (*Node, string) {
+	oid, err := NormalizeOID(oid)
+	if err != nil {
+		return nil, ""
+	}
+
+	for prefix := oid; prefix != ""; prefix = prefix[:strings.LastIndex(prefix, ".")] {
+		if node, ok := m.oids[prefix]; ok {
+			return node, strings.TrimPrefix(oid[len(prefix):], ".")
+		}
+	}
+
+	return nil, ""
+}
+
+// Name converts a numeric OID to a name such as IF-MIB::ifHCInOctets.1. The
+// OID is returned unchanged when no part of it is known
+func (m *MIB) Name(oid string) string {
+	node, index := m.Lookup(oid)
+	if node == nil {
+		return oid
+	}
+
+	if index == "" {
+		return node.String()
+	}
+
+	return node.String() + "." + index
+}
+
+// build resolves the OID of every definition and indexes the resulting nodes
+func (m *MIB) build() {
+	m.nodes = map[string]*Node{}
+	m.names = map[string][]*Node{}
+	m.oids = map[string]*Node{}
+
+	names := make([]string, 0, len(m.modules))
+	for name := range m.modules {
+		names = append(names, name)
+	}
+	// Loaded modules take precedence over the builtin definitions of the
+	// same OIDs
+	sort.Slice(names, func(i, j int) bool {
+		a, b := m.modules[names[i]], m.modules[names[j]]
+		if a.builtin != b.builtin {
+			return !a.builtin
+		}
+		return a.Name < b.Name
+	})
+
+	cache := map[string][]uint32{}
+	for _, name := range names {
+		module := m.modules[name]
+		for _, d := range module.definitions {
+			arcs, err := m.resolve(module, d.name, cache, 0)
+			if err != nil {
+				logrus.WithError(err).WithField("module", module.Name).Debugln("Could not resolve MIB object")
+				continue
+			}
+
+			node := &Node{
+				Module:   module.Name,
+				Name:     d.name,
+				OID:      formatOID(arcs),
+				Kind:     d.kind,
+				Syntax:   d.syntax,
+				Index:    d.index,
+				Augments: d.augments,
+			}
+			m.nodes[node.String()] = node
+			m.names[node.Name] = append(m.names[node.Name], node)
+			if _, ok := m.oids[node.OID]; !ok {
+				m.oids[node.OID] = node
+			}
+		}
+	}
+}
+
+// resolve finds the arcs of a symbol as seen from a module, following the
+// parent of each definition and the module each symbol is imported from
+func (m *MIB) resolve(module *Module, name string, cache map[string][]uint32, depth int) ([]uint32, error) {
+	if depth > maxResolveDepth {
+		return nil, errors.Errorf("%s::%s has a circular definition", module.Name, name)
+	}
+
+	key := module.Name + "::" + name
+	if arcs, ok := cache[key]; ok {
+		return arcs, nil
+	}
+
+	var arcs []uint32
+	if d, ok := module.byName[name]; ok {
+		if d.parent != "" {
+			parent, err := m.resolve(module, d.parent, cache, depth+1)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not resolve parent of %s", key)
+			}
+			arcs = append(arcs, parent...)
+		}
+		arcs = append(arcs, d.arcs...)
+	} else if from, ok := module.Imports[name]; ok && m.modules[from] != nil {
+		parent, err := m.resolve(m.modules[from], name, cache, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		arcs = parent
+	} else if root, ok := roots[name]; ok {
+		arcs = []uint32{root}
+	} else if other := m.definingModule(module, func(other *Module) bool {
+		_, ok := other.byName[name]
+		return ok
+	}); other != nil {
+		// Fall back to any module defining the symbol, as modules do not
+		// always import everything they use and the module imported from
+		// may not be loaded
+		parent, err := m.resolve(other, name, cache, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		arcs = parent
+	} else {
+		return nil, errors.Errorf("unknown symbol %s in module %s", name, module.Name)
+	}
+
+	cache[key] = arcs
+	return arcs, nil
+}
+
+// definingModule returns a module other than exclude which defines a symbol,
+// preferring loaded modules to the builtin ones
+func (m *MIB) definingModule(exclude *Module, defines func(*Module) bool) *Module {
+	var found *Module
+	for _, module := range m.modules {
+		if module == exclude || !defines(module) {
+			continue
+		}
+		if found == nil || (found.builtin && !module.builtin) ||
+			(found.builtin == module.builtin && module.Name < found.Name) {
+			found = module
+		}
+	}
+
+	return found
+}
+
+// NormalizeOID validates a numeric OID and ensures it has a leading dot, as
+// used in the names of PDUs returned by gosnmp
+func NormalizeOID(oid string) (string, error) {
+	trimmed := strings.TrimPrefix(oid, ".")
+	if trimmed == "" {
+		return "", errors.Errorf("invalid OID %s", oid)
+	}
+
+	for _, arc := range strings.Split(trimmed, ".") {
+		if _, err := strconv.ParseUint(arc, 10, 32); err != nil {
+			return "", errors.Errorf("invalid OID %s", oid)
+		}
+	}
+
+	return "." + trimmed, nil
+}
+
+func formatOID(arcs []uint32) string {
+	var b strings.Builder
+	for _, arc := range arcs {
+		b.WriteByte('.')
+		b.WriteString(strconv.FormatUint(uint64(arc), 10))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,206 @@
+package mib
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func testMIBDir() string {
+	cwd, _ := os.Getwd()
+	return fmt.Sprintf("%s/fixtures/mibs", path.Dir(path.Dir(cwd)))
+}
+
+func loadTestMIB(t *testing.T) *MIB {
+	m := New()
+	if err := m.LoadDirs(testMIBDir()); err != nil {
+		logrus.WithError(err).Errorln("Failed to load MIB directory")
+		t.FailNow()
+	}
+
+	return m
+}
+
+func TestResolve(t *testing.T) {
+	m := loadTestMIB(t)
+
+	tests := map[string]string{
+		"IF-MIB::ifHCInOctets":    ".1.3.6.1.2.1.31.1.1.1.6",
+		"ifHCInOctets":            ".1.3.6.1.2.1.31.1.1.1.6",
+		"IF-MIB::ifHCInOctets.12": ".1.3.6.1.2.1.31.1.1.1.6.12",
+		"SNMPv2-MIB::sysName.0":   ".1.3.6.1.2.1.1.5.0",
+		"IF-MIB::ifOperStatus":    ".1.3.6.1.2.1.2.2.1.8",
+		"IF-MIB::linkDown":        ".1.3.6.1.6.3.1.1.5.3",
+		"SNMPv2-SMI::enterprises": ".1.3.6.1.4.1",
+		"1.3.6.1.2.1.1.5.0":       ".1.3.6.1.2.1.1.5.0",
+		".1.3.6.1.2.1.1.5.0":      ".1.3.6.1.2.1.1.5.0",
+	}
+
+	for name, expected := range tests {
+		oid, err := m.Resolve(name)
+		if err != nil {
+			logrus.WithError(err).WithField("name", name).Errorln("Failed to resolve name")
+			t.Fail()
+			continue
+		}
+
+		if oid != expected {
+			logrus.WithFields(logrus.Fields{"name": name, "oid": oid, "expected": expected}).Errorln("Resolved OID is invalid")
+			t.Fail()
+		}
+	}
+}
+
+func TestResolveInvalid(t *testing.T) {
+	m := loadTestMIB(t)
+
+	for _, name := range []string{"", "IF-MIB::ifUnknown", "UNKNOWN-MIB::ifHCInOctets", "ifHCInOctets.x", "1.3.x.1", "."} {
+		if _, err := m.Resolve(name); err == nil {
+			logrus.WithField("name", name).Errorln("Resolved invalid name")
+			t.Fail()
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	m := loadTestMIB(t)
+
+	tests := map[string]string{
+		".1.3.6.1.2.1.31.1.1.1.6":   "IF-MIB::ifHCInOctets",
+		".1.3.6.1.2.1.31.1.1.1.6.1": "IF-MIB::ifHCInOctets.1",
+		"1.3.6.1.2.1.1.5.0":         "SNMPv2-MIB::sysName.0",
+		".1.3.6.1.4.1.2636.3.5":     "JUNIPER-FIREWALL-MIB::jnxFirewalls",
+		".1.3.6.1.4.1.9.9.109":      "SNMPv2-SMI::enterprises.9.9.109",
+		// joint-iso-ccitt is a root rather than a node, so it is not named
+		".2.999": ".2.999",
+	}
+
+	for oid, expected := range tests {
+		if name := m.Name(oid); name != expected {
+			logrus.WithFields(logrus.Fields{"oid": oid, "name": name, "expected": expected}).Errorln("OID name is invalid")
+			t.Fail()
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	m := loadTestMIB(t)
+
+	node, index := m.Lookup(".1.3.6.1.2.1.2.2.1.8.3")
+	if node == nil {
+		logrus.Errorln("Failed to look up ifOperStatus")
+		t.FailNow()
+	}
+
+	if node.Module != "IF-MIB" || node.Name != "ifOperStatus" || index != "3" {
+		logrus.WithFields(logrus.Fields{"node": node.String(), "index": index}).Errorln("Looked up node is invalid")
+		t.Fail()
+	}
+
+	if node.Kind != "OBJECT-TYPE" || node.Syntax == nil || node.Syntax.Type != "INTEGER" {
+		logrus.Errorln("Looked up node syntax is invalid")
+		t.FailNow()
+	}
+
+	if node.Syntax.Enums[7] != "lowerLayerDown" || len(node.Syntax.Enums) != 7 {
+		logrus.WithField("enums", node.Syntax.Enums).Errorln("Parsed enumeration is invalid")
+		t.Fail()
+	}
+}
+
+func TestFind(t *testing.T) {
+	m := loadTestMIB(t)
+
+	node, err := m.Find("IF-MIB::ifTable")
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to find ifTable")
+		t.FailNow()
+	}
+
+	if node.Syntax == nil || node.Syntax.Type != "SEQUENCE OF IfEntry" {
+		logrus.Errorln("Table syntax is invalid")
+		t.Fail()
+	}
+
+	if _, err = m.Find("IF-MIB::ifTable.1"); err == nil {
+		logrus.Errorln("Found an instance as an object")
+		t.Fail()
+	}
+}
+
+func TestParseSMIv1(t *testing.T) {
+	m := New()
+	err := m.Parse(strings.NewReader(`
+TEST-V1-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+        enterprises, Counter
+                FROM RFC1155-SMI
+        OBJECT-TYPE
+                FROM RFC-1212
+        TRAP-TYPE
+                FROM RFC-1215;
+
+test       OBJECT IDENTIFIER ::= { enterprises 99999 }
+testGroup  OBJECT IDENTIFIER ::= { test 1 }
+
+testCounter OBJECT-TYPE
+        SYNTAX  Counter
+        ACCESS  read-only
+        STATUS  mandatory
+        DESCRIPTION
+                "A counter -- which is not a comment."
+        ::= { testGroup 1 }
+
+testTrap TRAP-TYPE
+        ENTERPRISE  test
+        VARIABLES   { testCounter }
+        DESCRIPTION
+                "A test trap."
+        ::= 1
+
+END
+`))
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to parse SMIv1 module")
+		t.FailNow()
+	}
+
+	oid, err := m.Resolve("TEST-V1-MIB::testCounter")
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to resolve SMIv1 object")
+		t.FailNow()
+	}
+
+	if oid != ".1.3.6.1.4.1.99999.1.1" {
+		logrus.WithField("oid", oid).Errorln("Resolved SMIv1 OID is invalid")
+		t.Fail()
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	modules := []string{
+		"",
+		"NO-END DEFINITIONS ::= BEGIN\nfoo OBJECT IDENTIFIER ::= { iso 1 }\n",
+		"BAD-VALUE DEFINITIONS ::= BEGIN\nfoo OBJECT IDENTIFIER ::= { iso 1 bar }\nEND\n",
+		"BAD-STRING DEFINITIONS ::= BEGIN\nfoo OBJECT-TYPE DESCRIPTION \"unterminated\nEND\n",
+	}
+
+	for _, module := range modules {
+		if err := New().Parse(strings.NewReader(module)); err == nil {
+			logrus.WithField("module", module).Errorln("Parsed invalid module")
+			t.Fail()
+		}
+	}
+}
+
+func TestLoadDirsInvalid(t *testing.T) {
+	if err := New().LoadDirs("/nonexistent/mibs"); err == nil {
+		logrus.Errorln("Loaded non-existant MIB directory")
+		t.Fail()
+	}
+}
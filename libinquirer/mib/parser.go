@@ -0,0 +1,576 @@
+package mib
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// macros are the SMI macros whose value is an OID assignment
+var macros = map[string]bool{
+	"OBJECT-TYPE":        true,
+	"OBJECT-IDENTITY":    true,
+	"MODULE-IDENTITY":    true,
+	"NOTIFICATION-TYPE":  true,
+	"OBJECT-GROUP":       true,
+	"NOTIFICATION-GROUP": true,
+	"MODULE-COMPLIANCE":  true,
+	"AGENT-CAPABILITIES": true,
+	"TRAP-TYPE":          true,
+}
+
+// Module is a single parsed MIB module
+type Module struct {
+	Name string
+	// Imports maps each imported symbol to the module it is imported from
+	Imports map[string]string
+
+	definitions []*definition
+	byName      map[string]*definition
+	types       map[string]*typeDefinition
+	builtin     bool
+}
+
+// Syntax is the SYNTAX clause of an object or type as written in the module
+type Syntax struct {
+	// Type is the name of the type, such as INTEGER, OCTET STRING,
+	// DisplayString or SEQUENCE OF IfEntry
+	Type string
+	// Enums holds the named numbers of enumerated INTEGER and BITS types
+	Enums map[int64]string
+	// Size is the length of fixed length strings, such as 6 for
+	// OCTET STRING (SIZE (6)), and zero otherwise
+	Size int
+}
+
+// IndexPart is a single object of an INDEX clause
+type IndexPart struct {
+	Name string
+	// Implied is set for the last object of an index when its length is
+	// not encoded in the OID
+	Implied bool
+}
+
+// definition is an OID assignment which has not been resolved against its
+// parent yet
+type definition struct {
+	name     string
+	kind     string
+	parent   string
+	arcs     []uint32
+	syntax   *Syntax
+	index    []IndexPart
+	augments string
+}
+
+// typeDefinition is a type assignment, such as a textual convention
+type typeDefinition struct {
+	name        string
+	displayHint string
+	syntax      *Syntax
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseModules parses every module defined in the input
+func parseModules(r io.Reader) ([]*Module, error) {
+	tokens, err := tokenize(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	modules := []*Module{}
+	for p.peek(0).kind != eofToken {
+		m, err := p.module()
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, m)
+	}
+
+	if len(modules) == 0 {
+		return nil, errors.Errorf("no MIB modules found")
+	}
+
+	return modules, nil
+}
+
+func newModule(name string) *Module {
+	return &Module{
+		Name:    name,
+		Imports: map[string]string{},
+		byName:  map[string]*definition{},
+		types:   map[string]*typeDefinition{},
+	}
+}
+
+func (p *parser) peek(n int) token {
+	if p.pos+n >= len(p.tokens) {
+		line := 0
+		if len(p.tokens) > 0 {
+			line = p.tokens[len(p.tokens)-1].line
+		}
+		return token{kind: eofToken, line: line}
+	}
+
+	return p.tokens[p.pos+n]
+}
+
+func (p *parser) next() token {
+	t := p.peek(0)
+	if t.kind != eofToken {
+		p.pos++
+	}
+
+	return t
+}
+
+// is reports whether the token n ahead is the keyword or symbol provided
+func (p *parser) is(n int, text string) bool {
+	t := p.peek(n)
+	return t.kind != stringToken && t.kind != eofToken && t.text == text
+}
+
+func (p *parser) expect(text string) error {
+	t := p.next()
+	if t.kind == stringToken || t.text != text {
+		return errors.Errorf("line %d: expected %s, found %q", t.line, text, t.text)
+	}
+
+	return nil
+}
+
+func (p *parser) identifier() (string, error) {
+	t := p.next()
+	if t.kind != identToken {
+		return "", errors.Errorf("line %d: expected an identifier, found %q", t.line, t.text)
+	}
+
+	return t.text, nil
+}
+
+func (p *parser) number() (int64, error) {
+	t := p.next()
+	if t.kind != numberToken {
+		return 0, errors.Errorf("line %d: expected a number, found %q", t.line, t.text)
+	}
+
+	return strconv.ParseInt(t.text, 10, 64)
+}
+
+// skipBalanced skips a bracketed block, including any nested blocks
+func (p *parser) skipBalanced(open, close string) error {
+	start := p.peek(0)
+	if err := p.expect(open); err != nil {
+		return err
+	}
+
+	for depth := 1; depth > 0; {
+		t := p.next()
+		switch {
+		case t.kind == eofToken:
+			return errors.Errorf("line %d: unterminated %s", start.line, open)
+		case t.kind == stringToken:
+		case t.text == open:
+			depth++
+		case t.text == close:
+			depth--
+		}
+	}
+
+	return nil
+}
+
+// module parses NAME DEFINITIONS ::= BEGIN ... END
+func (p *parser) module() (*Module, error) {
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect("DEFINITIONS"); err != nil {
+		return nil, err
+	}
+	for !p.is(0, "::=") && p.peek(0).kind != eofToken {
+		// Skip tag defaults such as IMPLICIT TAGS
+		p.next()
+	}
+	if err = p.expect("::="); err != nil {
+		return nil, err
+	}
+	if err = p.expect("BEGIN"); err != nil {
+		return nil, err
+	}
+
+	m := newModule(name)
+	for {
+		t := p.peek(0)
+		switch {
+		case t.kind == eofToken:
+			return nil, errors.Errorf("module %s is missing END", name)
+		case p.is(0, "END"):
+			p.next()
+			return m, nil
+		case p.is(0, "IMPORTS"):
+			err = p.imports(m)
+		case p.is(0, "EXPORTS"):
+			for !p.is(0, ";") && p.peek(0).kind != eofToken {
+				p.next()
+			}
+			p.next()
+		case t.kind != identToken:
+			p.next()
+		case p.is(1, "MACRO"):
+			err = p.skipMacroDefinition()
+		case p.is(1, "OBJECT") && p.is(2, "IDENTIFIER") && p.is(3, "::="):
+			err = p.objectIdentifier(m)
+		case macros[p.peek(1).text] && p.peek(1).kind == identToken:
+			err = p.macro(m)
+		case p.is(1, "::="):
+			err = p.typeAssignment(m)
+		default:
+			p.next()
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse module %s", name)
+		}
+	}
+}
+
+func (m *Module) add(d *definition) {
+	if _, ok := m.byName[d.name]; !ok {
+		m.definitions = append(m.definitions, d)
+	}
+	m.byName[d.name] = d
+}
+
+// imports parses IMPORTS a, b FROM MODULE-A c FROM MODULE-B ;
+func (p *parser) imports(m *Module) error {
+	p.next()
+
+	symbols := []string{}
+	for {
+		t := p.next()
+		switch {
+		case t.kind == eofToken:
+			return errors.Errorf("line %d: unterminated IMPORTS", t.line)
+		case t.text == ";":
+			return nil
+		case t.text == ",":
+		case t.text == "FROM":
+			from, err := p.identifier()
+			if err != nil {
+				return err
+			}
+			for _, s := range symbols {
+				m.Imports[s] = from
+			}
+			symbols = symbols[:0]
+		default:
+			symbols = append(symbols, t.text)
+		}
+	}
+}
+
+// skipMacroDefinition skips NAME MACRO ::= BEGIN ... END, as found in the
+// SMI modules themselves
+func (p *parser) skipMacroDefinition() error {
+	start := p.next()
+	for !p.is(0, "END") {
+		if p.next().kind == eofToken {
+			return errors.Errorf("line %d: unterminated MACRO %s", start.line, start.text)
+		}
+	}
+	p.next()
+
+	return nil
+}
+
+// objectIdentifier parses name OBJECT IDENTIFIER ::= { parent arc }
+func (p *parser) objectIdentifier(m *Module) error {
+	d := &definition{name: p.next().text, kind: "OBJECT IDENTIFIER"}
+	p.pos += 3
+
+	if err := p.oidValue(d); err != nil {
+		return err
+	}
+	m.add(d)
+
+	return nil
+}
+
+// macro parses the clauses of an OBJECT-TYPE or similar macro followed by its
+// OID value
+func (p *parser) macro(m *Module) error {
+	d := &definition{name: p.next().text, kind: p.next().text}
+
+	for !p.is(0, "::=") {
+		t := p.peek(0)
+		switch {
+		case t.kind == eofToken:
+			return errors.Errorf("line %d: %s %s is missing its value", t.line, d.name, d.kind)
+		case p.is(0, "SYNTAX") && d.kind == "OBJECT-TYPE":
+			p.next()
+			s, err := p.syntax()
+			if err != nil {
+				return err
+			}
+			d.syntax = s
+		case p.is(0, "INDEX") && d.kind == "OBJECT-TYPE":
+			p.next()
+			index, err := p.indexParts()
+			if err != nil {
+				return err
+			}
+			d.index = index
+		case p.is(0, "AUGMENTS") && d.kind == "OBJECT-TYPE":
+			p.next()
+			augments, err := p.indexParts()
+			if err != nil {
+				return err
+			}
+			if len(augments) != 1 {
+				return errors.Errorf("line %d: AUGMENTS of %s must name a single entry", t.line, d.name)
+			}
+			d.augments = augments[0].Name
+		case p.is(0, "{"):
+			if err := p.skipBalanced("{", "}"); err != nil {
+				return err
+			}
+		case p.is(0, "("):
+			if err := p.skipBalanced("(", ")"); err != nil {
+				return err
+			}
+		default:
+			p.next()
+		}
+	}
+	p.next()
+
+	if d.kind == "TRAP-TYPE" {
+		// SMIv1 traps are numbered within their enterprise rather than
+		// assigned an OID
+		_, err := p.number()
+		return err
+	}
+
+	if err := p.oidValue(d); err != nil {
+		return err
+	}
+	m.add(d)
+
+	return nil
+}
+
+// oidValue parses an OID value such as { ifEntry 1 } or
+// { iso(1) org(3) dod(6) internet(1) }
+func (p *parser) oidValue(d *definition) error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+
+	for first := true; !p.is(0, "}"); first = false {
+		t := p.next()
+		switch t.kind {
+		case numberToken:
+			arc, err := strconv.ParseUint(t.text, 10, 32)
+			if err != nil {
+				return errors.Errorf("line %d: invalid arc %s", t.line, t.text)
+			}
+			d.arcs = append(d.arcs, uint32(arc))
+		case identToken:
+			if p.is(0, "(") {
+				p.next()
+				arc, err := p.number()
+				if err != nil {
+					return err
+				}
+				if err = p.expect(")"); err != nil {
+					return err
+				}
+				d.arcs = append(d.arcs, uint32(arc))
+				continue
+			}
+
+			// A module qualified parent, such as SNMPv2-SMI.enterprises
+			if p.is(0, ".") && p.peek(1).kind == identToken {
+				p.next()
+				t = p.next()
+			}
+
+			if !first {
+				return errors.Errorf("line %d: unexpected %s in OID value of %s", t.line, t.text, d.name)
+			}
+			d.parent = t.text
+		default:
+			return errors.Errorf("line %d: unexpected %q in OID value of %s", t.line, t.text, d.name)
+		}
+	}
+	p.next()
+
+	return nil
+}
+
+// typeAssignment parses Name ::= TEXTUAL-CONVENTION ... or Name ::= <syntax>
+func (p *parser) typeAssignment(m *Module) error {
+	td := &typeDefinition{name: p.next().text}
+	p.next()
+
+	if p.is(0, "TEXTUAL-CONVENTION") {
+		p.next()
+		for !p.is(0, "SYNTAX") {
+			t := p.next()
+			switch {
+			case t.kind == eofToken:
+				return errors.Errorf("line %d: textual convention %s is missing its SYNTAX", t.line, td.name)
+			case t.text == "DISPLAY-HINT" && t.kind == identToken:
+				hint := p.next()
+				if hint.kind != stringToken {
+					return errors.Errorf("line %d: invalid DISPLAY-HINT of %s", hint.line, td.name)
+				}
+				td.displayHint = hint.text
+			}
+		}
+		p.next()
+	}
+
+	s, err := p.syntax()
+	if err != nil {
+		return err
+	}
+	td.syntax = s
+	m.types[td.name] = td
+
+	return nil
+}
+
+// syntax parses a type such as INTEGER { up(1), down(2) },
+// OCTET STRING (SIZE (0..255)) or SEQUENCE OF IfEntry
+func (p *parser) syntax() (*Syntax, error) {
+	if p.is(0, "[") {
+		// Tagged types such as [APPLICATION 1] IMPLICIT INTEGER
+		if err := p.skipBalanced("[", "]"); err != nil {
+			return nil, err
+		}
+		if p.is(0, "IMPLICIT") || p.is(0, "EXPLICIT") {
+			p.next()
+		}
+	}
+
+	s := &Syntax{}
+	t := p.next()
+	switch {
+	case t.kind != identToken:
+		return nil, errors.Errorf("line %d: expected a type, found %q", t.line, t.text)
+	case t.text == "OCTET" && p.is(0, "STRING"):
+		p.next()
+		s.Type = "OCTET STRING"
+	case t.text == "OBJECT" && p.is(0, "IDENTIFIER"):
+		p.next()
+		s.Type = "OBJECT IDENTIFIER"
+	case t.text == "SEQUENCE" && p.is(0, "OF"):
+		p.next()
+		entry, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		s.Type = "SEQUENCE OF " + entry
+		return s, nil
+	case t.text == "SEQUENCE" || t.text == "CHOICE":
+		s.Type = t.text
+		return s, p.skipBalanced("{", "}")
+	default:
+		s.Type = t.text
+	}
+
+	if p.is(0, "{") {
+		enums, err := p.namedNumbers()
+		if err != nil {
+			return nil, err
+		}
+		s.Enums = enums
+	}
+
+	if p.is(0, "(") {
+		// Only a single fixed size is kept, as it changes how the value is
+		// encoded in table indexes
+		if p.is(1, "SIZE") && p.is(2, "(") && p.peek(3).kind == numberToken && p.is(4, ")") && p.is(5, ")") {
+			size, err := strconv.Atoi(p.peek(3).text)
+			if err != nil {
+				return nil, errors.Errorf("line %d: invalid size %s", p.peek(3).line, p.peek(3).text)
+			}
+			s.Size = size
+			p.pos += 6
+		} else if err := p.skipBalanced("(", ")"); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// indexParts parses { [IMPLIED] name, name }
+func (p *parser) indexParts() ([]IndexPart, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	parts := []IndexPart{}
+	for !p.is(0, "}") {
+		part := IndexPart{}
+		if p.is(0, "IMPLIED") {
+			p.next()
+			part.Implied = true
+		}
+
+		name, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		part.Name = name
+		parts = append(parts, part)
+
+		if p.is(0, ",") {
+			p.next()
+		}
+	}
+	p.next()
+
+	return parts, nil
+}
+
+// namedNumbers parses { name(1), other(2) }
+func (p *parser) namedNumbers() (map[int64]string, error) {
+	p.next()
+
+	enums := map[int64]string{}
+	for !p.is(0, "}") {
+		name, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("("); err != nil {
+			return nil, err
+		}
+		value, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		enums[value] = name
+
+		if p.is(0, ",") {
+			p.next()
+		}
+	}
+	p.next()
+
+	return enums, nil
+}
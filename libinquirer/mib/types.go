@@ -0,0 +1,83 @@
+package mib
+
+// Type is the syntax of an object resolved through any textual conventions
+// to its base type
+type Type struct {
+	// Name is the textual convention used by the object, such as MacAddress,
+	// or the base type when no textual convention is used
+	Name string
+	// Base is the SMI type the textual convention is derived from, such as
+	// OCTET STRING
+	Base string
+	// DisplayHint is the DISPLAY-HINT of the nearest textual convention
+	// which has one
+	DisplayHint string
+	// Enums are the named numbers of the object, or of the nearest textual
+	// convention when the object does not define its own
+	Enums map[int64]string
+	// Size is the length of fixed length strings, and zero otherwise
+	Size int
+}
+
+// Type resolves the syntax of an object. Nil is returned for nodes without a
+// SYNTAX clause
+func (m *MIB) Type(n *Node) *Type {
+	if n == nil || n.Syntax == nil {
+		return nil
+	}
+
+	t := &Type{Name: n.Syntax.Type, Enums: n.Syntax.Enums, Size: n.Syntax.Size}
+	syntax, module := n.Syntax, m.modules[n.Module]
+	for depth := 0; depth < maxResolveDepth; depth++ {
+		if baseTypes[syntax.Type] {
+			break
+		}
+
+		td, owner := m.findType(module, syntax.Type, 0)
+		if td == nil {
+			break
+		}
+
+		if t.DisplayHint == "" {
+			t.DisplayHint = td.displayHint
+		}
+		if t.Enums == nil {
+			t.Enums = td.syntax.Enums
+		}
+		if t.Size == 0 {
+			t.Size = td.syntax.Size
+		}
+		syntax, module = td.syntax, owner
+	}
+	t.Base = syntax.Type
+
+	return t
+}
+
+// findType finds the definition of a type as seen from a module, following
+// imports in the same way as OIDs are resolved
+func (m *MIB) findType(module *Module, name string, depth int) (*typeDefinition, *Module) {
+	if module == nil || depth > maxResolveDepth {
+		return nil, nil
+	}
+
+	if td, ok := module.types[name]; ok {
+		return td, module
+	}
+
+	if from, ok := module.Imports[name]; ok && m.modules[from] != nil {
+		if td, owner := m.findType(m.modules[from], name, depth+1); td != nil {
+			return td, owner
+		}
+	}
+
+	found := m.definingModule(module, func(other *Module) bool {
+		_, ok := other.types[name]
+		return ok
+	})
+	if found == nil {
+		return nil, nil
+	}
+
+	return found.types[name], found
+}
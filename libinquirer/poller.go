@@ -0,0 +1,321 @@
+package libinquirer
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	defaultWorkers     = 64
+	defaultHostTimeout = time.Duration(30) * time.Second
+)
+
+// Poller is used to poll many hosts at once using a bounded pool of workers
+type Poller struct {
+	// Workers is the maximum number of hosts polled at the same time
+	Workers int
+	// HostTimeout is the deadline for polling every OID of a single host
+	HostTimeout time.Duration
+	// RunTimeout is the deadline for polling every host. Zero disables it
+	RunTimeout time.Duration
+}
+
+// WalkResult contains the PDUs retrieved while walking a single OID
+type WalkResult struct {
+	OID  string
+	Name string
+	PDUs []gosnmp.SnmpPDU
+	Time time.Time
+	Err  error
+}
+
+// TableResult contains the walk of every column of a table
+type TableResult struct {
+	Name  string
+	Root  string
+	Walks []WalkResult
+}
+
+// HostResult contains the results of polling every OID of a single host
+type HostResult struct {
+	Config PollConfiguration
+	Walks  []WalkResult
+	Tables []TableResult
+	// Uptime is the host's sysUpTime in hundredths of a second, zero when it
+	// could not be retrieved
+	Uptime   uint32
+	Started  time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// NewPoller creates a new poller, falling back to sane defaults for any
+// values which are not set
+func NewPoller(workers int, hostTimeout, runTimeout time.Duration) *Poller {
+	if workers < 1 {
+		workers = defaultWorkers
+	}
+
+	if hostTimeout <= 0 {
+		hostTimeout = defaultHostTimeout
+	}
+
+	return &Poller{
+		Workers:     workers,
+		HostTimeout: hostTimeout,
+		RunTimeout:  runTimeout,
+	}
+}
+
+// Run polls every configuration provided and sends a result per host on the
+// returned channel. The channel is closed once every host has been polled or
+// the run deadline has passed
+func (p *Poller) Run(ctx context.Context, confs []PollConfiguration) <-chan *HostResult {
+	results := make(chan *HostResult, p.Workers)
+	jobs := make(chan PollConfiguration)
+
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if p.RunTimeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, p.RunTimeout)
+	} else {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.Workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for cfg := range jobs {
+				logrus.WithFields(logrus.Fields{
+					"worker": worker,
+					"host":   cfg.Host,
+				}).Debugln("Beginning poll process")
+				results <- p.Poll(runCtx, cfg)
+			}
+		}(i)
+	}
+
+	go func() {
+		defer cancel()
+		defer close(results)
+
+		skipped := []PollConfiguration{}
+	Dispatch:
+		for i, cfg := range confs {
+			select {
+			case jobs <- cfg:
+			case <-runCtx.Done():
+				logrus.WithError(runCtx.Err()).Warnln("Run deadline reached before every host was polled")
+				skipped = confs[i:]
+				break Dispatch
+			}
+		}
+		close(jobs)
+		wg.Wait()
+
+		for _, cfg := range skipped {
+			results <- &HostResult{
+				Config:  cfg,
+				Started: time.Now(),
+				Err:     errors.Wrap(runCtx.Err(), "run deadline reached before host was polled"),
+			}
+		}
+	}()
+
+	return results
+}
+
+// Poll walks every OID and table column configured for a single host,
+// stopping once the host deadline has passed
+func (p *Poller) Poll(ctx context.Context, cfg PollConfiguration) *HostResult {
+	client, err := CreateClientFromConfig(&cfg)
+	if err != nil {
+		return &HostResult{
+			Config:  cfg,
+			Started: time.Now(),
+			Err:     errors.Wrap(err, "failed to create SNMP client"),
+		}
+	}
+
+	result := p.PollClient(ctx, client, cfg)
+	if client.Conn != nil {
+		client.Conn.Close()
+	}
+
+	return result
+}
+
+// PollClient walks every OID and table column configured for a single host
+// using an existing client, connecting it first if required. The connection
+// is left open so the client may be reused for the next poll
+func (p *Poller) PollClient(ctx context.Context, client *gosnmp.GoSNMP, cfg PollConfiguration) *HostResult {
+	result := &HostResult{
+		Config:  cfg,
+		Started: time.Now(),
+	}
+	defer func() {
+		result.Duration = time.Since(result.Started)
+	}()
+
+	hostCtx, cancel := context.WithTimeout(ctx, p.HostTimeout)
+	defer cancel()
+
+	client.Context = hostCtx
+	if client.Timeout > p.HostTimeout {
+		client.Timeout = p.HostTimeout
+	}
+
+	connected := client.Conn == nil
+	if connected {
+		if err := ConnectClient(client); err != nil {
+			result.Err = errors.Wrap(err, "failed to open SNMP connection")
+			return result
+		}
+	}
+
+	result.Uptime = retrieveUptime(client)
+	if connected {
+		LogEngine(client)
+	}
+
+	if len(cfg.AutoProfiles) > 0 {
+		sysObjectID, err := retrieveSysObjectID(client)
+		if err != nil {
+			result.Err = errors.Wrap(err, "failed to retrieve sysObjectID to select profiles")
+			return result
+		}
+		cfg = SelectProfiles(cfg, sysObjectID)
+		result.Config = cfg
+	}
+
+	walked := map[string]WalkResult{}
+	for _, oid := range SortedOIDs(cfg.OIDs) {
+		walk, err := walkOID(hostCtx, client, oid, cfg.OIDs[oid])
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Walks = append(result.Walks, walk)
+		walked[oid] = walk
+	}
+
+	for _, table := range cfg.Tables {
+		tr := TableResult{Name: table.Name, Root: table.Root}
+		for _, column := range table.Resolved {
+			// Columns which are also configured as OIDs, directly or
+			// through a profile, reuse that walk rather than walking the
+			// column a second time
+			if walk, ok := walked[column.OID]; ok {
+				walk.Name = column.Name
+				tr.Walks = append(tr.Walks, walk)
+				continue
+			}
+
+			walk, err := walkOID(hostCtx, client, column.OID, column.Name)
+			if err != nil {
+				result.Tables = append(result.Tables, tr)
+				result.Err = err
+				return result
+			}
+			tr.Walks = append(tr.Walks, walk)
+			walked[column.OID] = walk
+		}
+		result.Tables = append(result.Tables, tr)
+	}
+
+	return result
+}
+
+// AllWalks returns the walks of every OID and table column of the result.
+// A column configured both as an OID and in a table, or in several tables,
+// is only returned once so that its values are not output twice
+func (r *HostResult) AllWalks() []WalkResult {
+	seen := map[string]bool{}
+	walks := []WalkResult{}
+	for _, walk := range r.Walks {
+		seen[walk.OID] = true
+		walks = append(walks, walk)
+	}
+
+	for _, table := range r.Tables {
+		for _, walk := range table.Walks {
+			if seen[walk.OID] {
+				continue
+			}
+			seen[walk.OID] = true
+			walks = append(walks, walk)
+		}
+	}
+
+	return walks
+}
+
+// walkOID bulk walks a single OID. An error is only returned when the host
+// deadline is reached, failures of the walk itself are part of the result
+func walkOID(ctx context.Context, client *gosnmp.GoSNMP, oid, name string) (WalkResult, error) {
+	if err := ctx.Err(); err != nil {
+		return WalkResult{}, errors.Wrap(err, "host deadline reached before every OID was walked")
+	}
+
+	pdus, err := client.BulkWalkAll(oid)
+	if err == context.DeadlineExceeded || ctx.Err() != nil {
+		return WalkResult{}, errors.Wrap(context.DeadlineExceeded, "host deadline reached before every OID was walked")
+	}
+
+	return WalkResult{
+		OID:  oid,
+		Name: name,
+		PDUs: pdus,
+		Time: time.Now(),
+		Err:  err,
+	}, nil
+}
+
+// retrieveUptime is used to get the sysUpTime of a host so that counter resets
+// may be detected
+func retrieveUptime(client *gosnmp.GoSNMP) uint32 {
+	packet, err := client.Get([]string{SysUpTimeOID})
+	if err != nil || len(packet.Variables) < 1 || packet.Variables[0].Type != gosnmp.TimeTicks {
+		logrus.WithError(err).WithField("host", client.Target).Debugln("Unable to retrieve sysUpTime")
+		return 0
+	}
+
+	return uint32(gosnmp.ToBigInt(packet.Variables[0].Value).Uint64())
+}
+
+// InterfaceIndex returns the last arc of a PDU's OID, which is the interface
+// index for tables indexed only by ifIndex, such as IF-MIB::ifTable
+func InterfaceIndex(oid string) string {
+	return oid[strings.LastIndex(oid, ".")+1:]
+}
+
+// InstanceIndex returns the arcs of a PDU's OID following the OID walked,
+// which is the whole index of tables with multi-arc indexes. The last arc is
+// returned when the PDU is the OID walked, such as a scalar retrieved by get
+func InstanceIndex(base, oid string) string {
+	if strings.HasPrefix(oid, base+".") {
+		return oid[len(base)+1:]
+	}
+
+	return InterfaceIndex(oid)
+}
+
+// SortedOIDs returns the OIDs of a configuration's OID map in a stable order
+func SortedOIDs(oids map[string]string) []string {
+	sorted := make([]string, 0, len(oids))
+	for oid := range oids {
+		sorted = append(sorted, oid)
+	}
+	sort.Strings(sorted)
+
+	return sorted
+}
@@ -0,0 +1,110 @@
+package libinquirer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewPollerDefaults(t *testing.T) {
+	p := NewPoller(0, 0, 0)
+	if p.Workers != defaultWorkers || p.HostTimeout != defaultHostTimeout {
+		logrus.WithField("poller", p).Errorln("Poller defaults were not applied")
+		t.Fail()
+	}
+}
+
+func TestPollerRunMultipleHosts(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	defer agent.Close()
+
+	confs := []PollConfiguration{}
+	for i := 0; i < 10; i++ {
+		confs = append(confs, testAgentConfig(agent.Port()))
+	}
+
+	p := NewPoller(4, 2*time.Second, 10*time.Second)
+	count := 0
+	for result := range p.Run(context.Background(), confs) {
+		count++
+		if result.Err != nil {
+			logrus.WithError(result.Err).Errorln("Failed to poll test agent")
+			t.Fail()
+			continue
+		}
+
+		if len(result.Walks) != 3 {
+			logrus.WithField("walks", len(result.Walks)).Errorln("Incorrect number of walks returned")
+			t.Fail()
+		}
+
+		for _, walk := range result.Walks {
+			if walk.Err != nil || len(walk.PDUs) == 0 {
+				logrus.WithError(walk.Err).WithField("oid", walk.OID).Errorln("Walk returned no PDUs")
+				t.Fail()
+			}
+		}
+	}
+
+	if count != len(confs) {
+		logrus.WithField("results", count).Errorln("Incorrect number of host results returned")
+		t.Fail()
+	}
+}
+
+func TestPollerHostTimeout(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	agent.setDelay(time.Second)
+	defer agent.Close()
+
+	p := NewPoller(1, 200*time.Millisecond, 0)
+	started := time.Now()
+	result := p.Poll(context.Background(), testAgentConfig(agent.Port()))
+	if time.Since(started) > 900*time.Millisecond {
+		logrus.WithField("duration", time.Since(started)).Errorln("Host deadline was not respected")
+		t.Fail()
+	}
+
+	failed := result.Err != nil
+	for _, walk := range result.Walks {
+		failed = failed || walk.Err != nil
+	}
+	if !failed {
+		logrus.Errorln("Slow host was polled without error")
+		t.Fail()
+	}
+}
+
+func TestPollerRunTimeout(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	agent.setDelay(time.Second)
+	defer agent.Close()
+
+	confs := []PollConfiguration{}
+	for i := 0; i < 5; i++ {
+		confs = append(confs, testAgentConfig(agent.Port()))
+	}
+
+	p := NewPoller(1, 5*time.Second, 300*time.Millisecond)
+	started := time.Now()
+	count := 0
+	for result := range p.Run(context.Background(), confs) {
+		count++
+		if result.Err == nil {
+			logrus.Errorln("Host was polled without error after the run deadline")
+			t.Fail()
+		}
+	}
+
+	if time.Since(started) > 2*time.Second {
+		logrus.WithField("duration", time.Since(started)).Errorln("Run deadline was not respected")
+		t.Fail()
+	}
+
+	if count != len(confs) {
+		logrus.WithField("results", count).Errorln("Skipped hosts were not reported")
+		t.Fail()
+	}
+}
@@ -0,0 +1,139 @@
+package libinquirer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+// ProbeHandler is an HTTP handler polling a single target per request, in
+// the style of the Prometheus snmp_exporter. Requests provide the target
+// and the name of a module whose settings and OIDs are used to poll it
+type ProbeHandler struct {
+	Poller  *Poller
+	Modules map[string]PollConfiguration
+	MIB     *mib.MIB
+}
+
+// NewProbeHandler creates a probe handler for the modules provided
+func NewProbeHandler(p *Poller, modules map[string]PollConfiguration, m *mib.MIB) *ProbeHandler {
+	return &ProbeHandler{
+		Poller:  p,
+		Modules: modules,
+		MIB:     m,
+	}
+}
+
+// ServeHTTP implements http.Handler for requests such as
+// /snmp?target=192.0.2.1&module=if_mib
+func (h *ProbeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "'target' parameter must be specified", http.StatusBadRequest)
+		return
+	}
+
+	name := r.URL.Query().Get("module")
+	if name == "" && len(h.Modules) == 1 {
+		for only := range h.Modules {
+			name = only
+		}
+	}
+
+	module, ok := h.Modules[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown module '%s', available modules are %v", name, h.moduleNames()), http.StatusBadRequest)
+		return
+	}
+
+	cfg, err := probeConfiguration(module, target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	client, err := CreateClientFromConfig(&cfg)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid module '%s': %s", name, err), http.StatusBadRequest)
+		return
+	}
+	defer func() {
+		if client.Conn != nil {
+			client.Conn.Close()
+		}
+	}()
+
+	logrus.WithFields(logrus.Fields{
+		"target": target,
+		"module": name,
+	}).Debugln("Probing target")
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&probeCollector{
+		ctx:    r.Context(),
+		poller: h.Poller,
+		client: client,
+		cfg:    cfg,
+		mib:    h.MIB,
+	})
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	}).ServeHTTP(w, r)
+}
+
+// probeCollector polls a single target using the client created for the
+// probe when it is scraped
+type probeCollector struct {
+	ctx    context.Context
+	poller *Poller
+	client *gosnmp.GoSNMP
+	cfg    PollConfiguration
+	mib    *mib.MIB
+}
+
+// Describe implements prometheus.Collector. Like the PrometheusCollector,
+// the collector is unchecked
+func (c *probeCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector, polling the target
+func (c *probeCollector) Collect(ch chan<- prometheus.Metric) {
+	CollectHostResult(c.poller.PollClient(c.ctx, c.client, c.cfg), ch, c.mib)
+}
+
+func (h *ProbeHandler) moduleNames() []string {
+	names := []string{}
+	for name := range h.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// probeConfiguration creates the poll configuration for a target from a
+// module. Targets may include a port, such as 192.0.2.1:1161
+func probeConfiguration(module PollConfiguration, target string) (PollConfiguration, error) {
+	cfg := module
+	cfg.Host = target
+
+	if host, port, err := net.SplitHostPort(target); err == nil {
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return cfg, errors.Errorf("Invalid port in target '%s'", target)
+		}
+		cfg.Host = host
+		cfg.Port = uint16(p)
+	}
+
+	return cfg, nil
+}
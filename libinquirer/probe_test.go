@@ -0,0 +1,79 @@
+package libinquirer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func probeModules() map[string]PollConfiguration {
+	module := testAgentConfig(0)
+	module.Host = ""
+	return map[string]PollConfiguration{"if_mib": module}
+}
+
+func probe(t *testing.T, h *ProbeHandler, query string) (int, string) {
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/snmp?" + query)
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to probe target")
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	return resp.StatusCode, string(body)
+}
+
+func TestProbeMissingTarget(t *testing.T) {
+	h := NewProbeHandler(NewPoller(1, time.Second, 0), probeModules(), nil)
+	if code, _ := probe(t, h, "module=if_mib"); code != http.StatusBadRequest {
+		logrus.WithField("code", code).Errorln("Probe without a target was accepted")
+		t.Fail()
+	}
+}
+
+func TestProbeUnknownModule(t *testing.T) {
+	h := NewProbeHandler(NewPoller(1, time.Second, 0), probeModules(), nil)
+	if code, _ := probe(t, h, "target=127.0.0.1&module=unknown"); code != http.StatusBadRequest {
+		logrus.WithField("code", code).Errorln("Probe with an unknown module was accepted")
+		t.Fail()
+	}
+}
+
+func TestProbeTarget(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	defer agent.Close()
+
+	h := NewProbeHandler(NewPoller(1, time.Second, 0), probeModules(), nil)
+	code, body := probe(t, h, fmt.Sprintf("target=%s:%d&module=if_mib", localhost, agent.Port()))
+	if code != http.StatusOK {
+		logrus.WithField("code", code).Errorln("Probe failed")
+		t.FailNow()
+	}
+
+	if !strings.Contains(body, `ifHCInOctets{host="127.0.0.1",interface_index="1"} 1000`) {
+		logrus.WithField("body", body).Errorln("Probe did not return the target's metrics")
+		t.Fail()
+	}
+}
+
+func TestProbeDefaultModule(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	defer agent.Close()
+
+	h := NewProbeHandler(NewPoller(1, time.Second, 0), probeModules(), nil)
+	code, _ := probe(t, h, fmt.Sprintf("target=%s:%d", localhost, agent.Port()))
+	if code != http.StatusOK {
+		logrus.WithField("code", code).Errorln("Probe without a module did not use the only module configured")
+		t.Fail()
+	}
+}
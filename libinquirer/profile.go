@@ -0,0 +1,214 @@
+package libinquirer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	// AutoProfile selects profiles for a host by its sysObjectID when polled
+	AutoProfile = "auto"
+
+	juniperEnterprise = ".1.3.6.1.4.1.2636"
+	ciscoEnterprise   = ".1.3.6.1.4.1.9"
+)
+
+// Profile is a named set of OIDs, objects and tables shared by many hosts
+type Profile struct {
+	// Enterprises are the sysObjectID prefixes of the devices the profile is
+	// selected for in auto mode, such as .1.3.6.1.4.1.2636 for Juniper.
+	// Profiles without enterprises are selected for every device
+	Enterprises []string             `json:"enterprises,omitempty"`
+	OIDs        map[string]string    `json:"oids,omitempty"`
+	Objects     []string             `json:"objects,omitempty"`
+	Tables      []TableConfiguration `json:"tables,omitempty"`
+}
+
+// BuiltinProfiles are available to every configuration. Profiles with the
+// same name in the configuration file replace them
+var BuiltinProfiles = map[string]Profile{
+	"generic-interfaces": {
+		OIDs: map[string]string{
+			".1.3.6.1.2.1.1.5.0":       "SNMPv2-MIB::sysName",
+			".1.3.6.1.2.1.2.2.1.1":     "IF-MIB::ifIndex",
+			".1.3.6.1.2.1.2.2.1.19":    "IF-MIB::ifOutDiscards",
+			".1.3.6.1.2.1.31.1.1.1.1":  "IF-MIB::ifName",
+			".1.3.6.1.2.1.31.1.1.1.6":  "IF-MIB::ifHCInOctets",
+			".1.3.6.1.2.1.31.1.1.1.7":  "IF-MIB::ifHCInUcastPkts",
+			".1.3.6.1.2.1.31.1.1.1.10": "IF-MIB::ifHCOutOctets",
+			".1.3.6.1.2.1.31.1.1.1.11": "IF-MIB::ifHCOutUcastPkts",
+			".1.3.6.1.2.1.31.1.1.1.18": "IF-MIB::ifAlias",
+		},
+	},
+	"juniper-firewall": {
+		Enterprises: []string{juniperEnterprise},
+		OIDs: map[string]string{
+			".1.3.6.1.4.1.2636.3.5.2.1.4": "JUNIPER-FIREWALL-MIB::jnxFWCounterPacketCount",
+			".1.3.6.1.4.1.2636.3.5.2.1.5": "JUNIPER-FIREWALL-MIB::jnxFWCounterByteCount",
+			".1.3.6.1.4.1.2636.3.5.2.1.6": "JUNIPER-FIREWALL-MIB::jnxFWCounterDisplayFilterName",
+			".1.3.6.1.4.1.2636.3.5.2.1.7": "JUNIPER-FIREWALL-MIB::jnxFWCounterDisplayName",
+		},
+	},
+	"cisco-cpu": {
+		Enterprises: []string{ciscoEnterprise},
+		OIDs: map[string]string{
+			".1.3.6.1.4.1.9.9.109.1.1.1.1.6": "CISCO-PROCESS-MIB::cpmCPUTotal5secRev",
+			".1.3.6.1.4.1.9.9.109.1.1.1.1.7": "CISCO-PROCESS-MIB::cpmCPUTotal1minRev",
+			".1.3.6.1.4.1.9.9.109.1.1.1.1.8": "CISCO-PROCESS-MIB::cpmCPUTotal5minRev",
+		},
+	},
+}
+
+// ResolveProfiles resolves the builtin profiles and those of a configuration
+// file in the same way as the OIDs of a poll configuration, returning them
+// by name
+func ResolveProfiles(configured map[string]Profile, m *mib.MIB) (map[string]Profile, error) {
+	profiles := map[string]Profile{}
+	for name, p := range BuiltinProfiles {
+		profiles[name] = p
+	}
+	for name, p := range configured {
+		if name == AutoProfile {
+			return nil, errors.Errorf("profile name %s is reserved", AutoProfile)
+		}
+		profiles[name] = p
+	}
+
+	for name, p := range profiles {
+		cfg := PollConfiguration{Host: "profile " + name, OIDs: p.OIDs, Objects: p.Objects}
+		cfg.Tables = append([]TableConfiguration{}, p.Tables...)
+		if err := ResolveOIDs(&cfg, m); err != nil {
+			return nil, errors.Wrapf(err, "invalid profile %s", name)
+		}
+
+		enterprises := []string{}
+		for _, e := range p.Enterprises {
+			oid, err := m.Resolve(e)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid enterprise for profile %s", name)
+			}
+			enterprises = append(enterprises, oid)
+		}
+
+		profiles[name] = Profile{
+			Enterprises: enterprises,
+			OIDs:        cfg.OIDs,
+			Tables:      cfg.Tables,
+		}
+	}
+
+	return profiles, nil
+}
+
+// ApplyProfiles adds the OIDs and tables of the profiles named by a poll
+// configuration. OIDs configured for the host keep their labels. When the
+// auto profile is named, every profile is kept in AutoProfiles so that they
+// may be selected by sysObjectID once the host is polled
+func ApplyProfiles(cfg *PollConfiguration, profiles map[string]Profile) error {
+	for _, name := range cfg.Profiles {
+		if name == AutoProfile {
+			cfg.AutoProfiles = profiles
+			continue
+		}
+
+		p, ok := profiles[name]
+		if !ok {
+			return errors.Errorf("unknown profile %s for host %s", name, cfg.Host)
+		}
+		mergeProfile(cfg, p)
+	}
+
+	return nil
+}
+
+// SelectProfiles returns a copy of a poll configuration with the auto
+// profiles matching a sysObjectID added. Profiles are applied in name order
+func SelectProfiles(cfg PollConfiguration, sysObjectID string) PollConfiguration {
+	names := make([]string, 0, len(cfg.AutoProfiles))
+	for name := range cfg.AutoProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	selected := []string{}
+	for _, name := range names {
+		p := cfg.AutoProfiles[name]
+		if p.Matches(sysObjectID) {
+			mergeProfile(&cfg, p)
+			selected = append(selected, name)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"host":          cfg.Host,
+		"sys_object_id": sysObjectID,
+		"profiles":      selected,
+	}).Debugln("Selected profiles by sysObjectID")
+
+	return cfg
+}
+
+// Matches reports whether a profile is selected in auto mode for a device
+// with the sysObjectID provided
+func (p Profile) Matches(sysObjectID string) bool {
+	if len(p.Enterprises) == 0 {
+		return true
+	}
+
+	for _, e := range p.Enterprises {
+		if sysObjectID == e || strings.HasPrefix(sysObjectID, e+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeProfile adds the OIDs and tables of a profile to a poll
+// configuration. The OIDs and tables are copied so that configurations
+// sharing them are not modified
+func mergeProfile(cfg *PollConfiguration, p Profile) {
+	oids := make(map[string]string, len(cfg.OIDs)+len(p.OIDs))
+	for oid, label := range p.OIDs {
+		oids[oid] = label
+	}
+	for oid, label := range cfg.OIDs {
+		oids[oid] = label
+	}
+	cfg.OIDs = oids
+
+	tables := append([]TableConfiguration{}, cfg.Tables...)
+	for _, t := range p.Tables {
+		exists := false
+		for _, existing := range tables {
+			if existing.Root == t.Root && existing.Name == t.Name {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			tables = append(tables, t)
+		}
+	}
+	cfg.Tables = tables
+}
+
+// retrieveSysObjectID is used to get the sysObjectID of a host so that its
+// profiles may be selected
+func retrieveSysObjectID(client *gosnmp.GoSNMP) (string, error) {
+	packet, err := client.Get([]string{sysObjectIDOID})
+	if err != nil {
+		return "", err
+	}
+	if len(packet.Variables) < 1 || packet.Variables[0].Type != gosnmp.ObjectIdentifier {
+		return "", errors.New("sysObjectID was not returned")
+	}
+
+	oid, _ := packet.Variables[0].Value.(string)
+	return oid, nil
+}
@@ -0,0 +1,124 @@
+package libinquirer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+func TestParseConfigFileProfiles(t *testing.T) {
+	cwd, _ := os.Getwd()
+	c, err := ParseConfigFile(fmt.Sprintf("%s/fixtures/profiles_inquirer.json", path.Dir(cwd)))
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to parse configuration file")
+		t.FailNow()
+	}
+
+	oids := c.Poll[0].OIDs
+	expected := map[string]string{
+		".1.3.6.1.2.1.31.1.1.1.6":     "IF-MIB::ifHCInOctets",
+		".1.3.6.1.4.1.2636.3.5.2.1.4": "JUNIPER-FIREWALL-MIB::jnxFWCounterPacketCount",
+		".1.3.6.1.2.1.1.3.0":          "SNMPv2-MIB::sysUpTime.0",
+		".1.3.6.1.2.1.31.1.1.1.18":    "description",
+	}
+	for oid, label := range expected {
+		if oids[oid] != label {
+			logrus.WithFields(logrus.Fields{"oid": oid, "label": oids[oid]}).Errorln("Profile OID was not applied")
+			t.Fail()
+		}
+	}
+	if _, ok := oids[".1.3.6.1.4.1.9.9.109.1.1.1.1.8"]; ok || c.Poll[0].AutoProfiles != nil {
+		logrus.Errorln("Profile which was not named was applied")
+		t.Fail()
+	}
+
+	auto := c.Poll[1]
+	routing, ok := auto.AutoProfiles["juniper-routing-engine"]
+	if len(auto.OIDs) != 0 || !ok || len(routing.Enterprises) != 1 || routing.Enterprises[0] != juniperEnterprise {
+		logrus.WithField("profiles", auto.AutoProfiles).Errorln("Auto profiles were not resolved")
+		t.Fail()
+	}
+}
+
+func TestApplyUnknownProfile(t *testing.T) {
+	cfg := PollConfiguration{Host: localhost, Profiles: []string{invalid}}
+	if err := ApplyProfiles(&cfg, BuiltinProfiles); err == nil {
+		logrus.Errorln("Unknown profile was accepted")
+		t.Fail()
+	}
+
+	if _, err := ResolveProfiles(map[string]Profile{AutoProfile: {}}, testMIB(t)); err == nil {
+		logrus.Errorln("Profile named auto was accepted")
+		t.Fail()
+	}
+}
+
+func TestSelectProfiles(t *testing.T) {
+	cfg := PollConfiguration{Host: localhost, OIDs: map[string]string{SysUpTimeOID: "uptime"}, AutoProfiles: BuiltinProfiles}
+
+	tests := map[string][]string{
+		".1.3.6.1.4.1.2636.1.1.1.2.29": {"generic-interfaces", "juniper-firewall"},
+		".1.3.6.1.4.1.9.1.1208":        {"generic-interfaces", "cisco-cpu"},
+		".1.3.6.1.4.1.26361":           {"generic-interfaces"},
+	}
+
+	for sysObjectID, expected := range tests {
+		selected := SelectProfiles(cfg, sysObjectID)
+
+		count := 1
+		for _, name := range expected {
+			count += len(BuiltinProfiles[name].OIDs)
+			for oid := range BuiltinProfiles[name].OIDs {
+				if _, ok := selected.OIDs[oid]; !ok {
+					logrus.WithFields(logrus.Fields{"sys_object_id": sysObjectID, "profile": name}).Errorln("Profile was not selected")
+					t.Fail()
+				}
+			}
+		}
+
+		if len(selected.OIDs) != count || selected.OIDs[SysUpTimeOID] != "uptime" {
+			logrus.WithFields(logrus.Fields{"sys_object_id": sysObjectID, "oids": selected.OIDs}).Errorln("Profiles were selected incorrectly")
+			t.Fail()
+		}
+	}
+
+	if len(cfg.OIDs) != 1 {
+		logrus.Errorln("Selecting profiles modified the original configuration")
+		t.Fail()
+	}
+}
+
+func TestPollAutoProfiles(t *testing.T) {
+	agent := startTestAgent(t, append(testAgentPDUs(),
+		gosnmp.SnmpPDU{Name: sysObjectIDOID, Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.2636.1.1.1.2.29"},
+		gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.2636.3.5.2.1.4.1", Type: gosnmp.Counter64, Value: uint64(10)},
+	))
+	defer agent.Close()
+
+	cfg := testAgentConfig(agent.Port())
+	cfg.OIDs = map[string]string{}
+	cfg.AutoProfiles = BuiltinProfiles
+
+	result := NewPoller(1, 5*time.Second, 0).Poll(context.Background(), cfg)
+	if result.Err != nil {
+		logrus.WithError(result.Err).Errorln("Failed to poll host using auto profiles")
+		t.FailNow()
+	}
+
+	found := false
+	for _, walk := range result.Walks {
+		if walk.OID == ".1.3.6.1.4.1.2636.3.5.2.1.4" && len(walk.PDUs) == 1 {
+			found = true
+		}
+	}
+	if !found || result.Config.OIDs[".1.3.6.1.4.1.9.9.109.1.1.1.1.8"] != "" {
+		logrus.WithField("oids", result.Config.OIDs).Errorln("Profiles were not selected by sysObjectID")
+		t.Fail()
+	}
+}
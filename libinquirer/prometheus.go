@@ -0,0 +1,165 @@
+package libinquirer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+var (
+	invalidMetricChars = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	invalidLabelChars  = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
+
+var (
+	scrapeUpDesc = prometheus.NewDesc(
+		"snmp_up",
+		"Whether every OID of the host was walked successfully",
+		[]string{"host"}, nil,
+	)
+	scrapeDurationDesc = prometheus.NewDesc(
+		"snmp_scrape_duration_seconds",
+		"Time taken to poll the host",
+		[]string{"host"}, nil,
+	)
+)
+
+// PrometheusCollector polls hosts each time it is scraped and exposes the
+// retrieved values as Prometheus metrics. When a MIB is provided, the
+// components of table indexes are added as labels
+type PrometheusCollector struct {
+	Poller *Poller
+	Confs  []PollConfiguration
+	MIB    *mib.MIB
+}
+
+// NewPrometheusCollector creates a collector polling the hosts provided
+func NewPrometheusCollector(p *Poller, confs []PollConfiguration, m *mib.MIB) *PrometheusCollector {
+	return &PrometheusCollector{
+		Poller: p,
+		Confs:  confs,
+		MIB:    m,
+	}
+}
+
+// Describe implements prometheus.Collector. Metrics depend on what each host
+// returns, so the collector is unchecked and describes nothing up front
+func (c *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector, polling every host
+func (c *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	for result := range c.Poller.Run(context.Background(), c.Confs) {
+		CollectHostResult(result, ch, c.MIB)
+	}
+}
+
+// CollectHostResult converts the result of polling a host to Prometheus
+// metrics. Series are labelled with the instance index after the walked OID,
+// and with the decoded components of table indexes when a MIB is provided
+func CollectHostResult(result *HostResult, ch chan<- prometheus.Metric, m *mib.MIB) {
+	host := result.Config.Host
+	up := 1.0
+	if result.Err != nil {
+		logrus.WithError(result.Err).WithField("host", host).Errorln("Failed to poll host")
+		up = 0
+	}
+
+	for _, walk := range result.AllWalks() {
+		if walk.Err != nil {
+			logrus.WithError(walk.Err).WithField("host", host).Errorln("Failed to execute bulk walk request")
+			up = 0
+			continue
+		}
+
+		name := PrometheusMetricName(walk.OID, walk.Name)
+		for _, pdu := range walk.PDUs {
+			metric, err := prometheusMetric(name, host, walk.OID, pdu, m)
+			if err != nil {
+				logrus.WithError(err).WithField("oid", pdu.Name).Debugln("Skipping PDU which can not be converted to a metric")
+				continue
+			}
+			if metric != nil {
+				ch <- metric
+			}
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(scrapeUpDesc, prometheus.GaugeValue, up, host)
+	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, result.Duration.Seconds(), host)
+}
+
+// PrometheusMetricName converts a MIB name such as IF-MIB::ifHCInOctets to a
+// valid Prometheus metric name, ifHCInOctets. OIDs without a name are
+// converted to snmp_1_3_6_...
+func PrometheusMetricName(oid, mibName string) string {
+	name := mibName
+	if i := strings.LastIndex(name, "::"); i >= 0 {
+		name = name[i+2:]
+	}
+
+	if name == "" {
+		name = "snmp_" + strings.Trim(oid, ".")
+	}
+
+	name = invalidMetricChars.ReplaceAllString(name, "_")
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+
+	return name
+}
+
+func prometheusMetric(name, host, oid string, pdu gosnmp.SnmpPDU, m *mib.MIB) (prometheus.Metric, error) {
+	labels := []string{"host", "interface_index"}
+	values := []string{host, InstanceIndex(oid, pdu.Name)}
+	if _, indexValues, ok := TableIndex(m, pdu.Name); ok {
+		// Components named like an existing label are left to the full
+		// index, as label names must be unique
+		reserved := map[string]bool{"host": true, "interface_index": true, "value": true}
+		for _, v := range indexValues {
+			label := invalidLabelChars.ReplaceAllString(v.Name, "_")
+			if label == "" || reserved[label] || (label[0] >= '0' && label[0] <= '9') {
+				continue
+			}
+			reserved[label] = true
+			labels = append(labels, label)
+			values = append(values, v.String())
+		}
+	}
+
+	var valueType prometheus.ValueType
+	var value float64
+	switch pdu.Type {
+	case gosnmp.Counter32, gosnmp.Counter64:
+		valueType = prometheus.CounterValue
+		value, _ = new(big.Float).SetInt(gosnmp.ToBigInt(pdu.Value)).Float64()
+	case gosnmp.Gauge32, gosnmp.Integer, gosnmp.TimeTicks, gosnmp.Uinteger32:
+		valueType = prometheus.GaugeValue
+		value, _ = new(big.Float).SetInt(gosnmp.ToBigInt(pdu.Value)).Float64()
+	case gosnmp.OpaqueFloat:
+		valueType = prometheus.GaugeValue
+		value = float64(pdu.Value.(float32))
+	case gosnmp.OpaqueDouble:
+		valueType = prometheus.GaugeValue
+		value = pdu.Value.(float64)
+	case gosnmp.OctetString, gosnmp.IPAddress, gosnmp.ObjectIdentifier:
+		// String values are exposed as an info style metric with the value
+		// as a label
+		valueType = prometheus.GaugeValue
+		value = 1
+		labels = append(labels, "value")
+		values = append(values, fmt.Sprint(ReadableValue(pdu.Type, DecodeValue(pdu))))
+	default:
+		return nil, nil
+	}
+
+	desc := prometheus.NewDesc(name, fmt.Sprintf("SNMP object %s", strings.Trim(oid, ".")), labels, nil)
+	return prometheus.NewConstMetric(desc, valueType, value, values...)
+}
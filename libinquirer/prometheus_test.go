@@ -0,0 +1,106 @@
+package libinquirer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+func TestPrometheusMetricName(t *testing.T) {
+	tests := map[string][2]string{
+		"ifHCInOctets":              {".1.3.6.1.2.1.31.1.1.1.6", "IF-MIB::ifHCInOctets"},
+		"jnxFWCounterByteCount":     {".1.3.6.1.4.1.2636.3.5.2.1.5", "Juniper-MIB::jnxFWCounterByteCount"},
+		"snmp_1_3_6_1_2_1_2_2_1_19": {".1.3.6.1.2.1.2.2.1.19", ""},
+		"my_metric":                 {".1.3.6.1", "my-metric"},
+		"_1st":                      {".1.3.6.1", "1st"},
+	}
+
+	for expected, args := range tests {
+		if name := PrometheusMetricName(args[0], args[1]); name != expected {
+			logrus.WithFields(logrus.Fields{
+				"expected": expected,
+				"name":     name,
+			}).Errorln("Incorrect metric name generated")
+			t.Fail()
+		}
+	}
+}
+
+func TestPrometheusCollector(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	defer agent.Close()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewPrometheusCollector(NewPoller(1, time.Second, 0), []PollConfiguration{testAgentConfig(agent.Port())}, nil))
+	server := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to scrape collector")
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	expected := []string{
+		"# TYPE ifHCInOctets counter",
+		`ifHCInOctets{host="127.0.0.1",interface_index="1"} 1000`,
+		`ifHCInOctets{host="127.0.0.1",interface_index="2"} 2000`,
+		`ifName{host="127.0.0.1",interface_index="2",value="ge-0/0/1"} 1`,
+		`sysName{host="127.0.0.1",interface_index="0",value="test-agent"} 1`,
+		`snmp_up{host="127.0.0.1"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(string(body), line) {
+			logrus.WithField("line", line).Errorln("Expected metric was not exposed")
+			t.Fail()
+		}
+	}
+}
+
+func TestPrometheusCollectorTableIndex(t *testing.T) {
+	// Both rows end in the same arc, so only the full index keeps them apart
+	agent := startTestAgent(t, []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.4.1.2636.3.5.2.1.4.3.97.101.48.5.100.114.111.112.115.3", Type: gosnmp.Counter64, Value: uint64(42)},
+		{Name: ".1.3.6.1.4.1.2636.3.5.2.1.4.3.97.101.49.5.100.114.111.112.115.3", Type: gosnmp.Counter64, Value: uint64(7)},
+	})
+	defer agent.Close()
+
+	cfg := testAgentConfig(agent.Port())
+	cfg.OIDs = map[string]string{".1.3.6.1.4.1.2636.3.5.2.1.4": "JUNIPER-FIREWALL-MIB::jnxFWCounterPacketCount"}
+
+	tests := map[string]*mib.MIB{
+		`jnxFWCounterPacketCount{host="127.0.0.1",interface_index="3.97.101.49.5.100.114.111.112.115.3"} 7`:                                                                                  nil,
+		`jnxFWCounterPacketCount{host="127.0.0.1",interface_index="3.97.101.49.5.100.114.111.112.115.3",jnxFWCounterFilterName="ae1",jnxFWCounterName="drops",jnxFWCounterType="policer"} 7`: testMIB(t),
+	}
+	for line, m := range tests {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(NewPrometheusCollector(NewPoller(1, time.Second, 0), []PollConfiguration{cfg}, m))
+
+		if _, err := registry.Gather(); err != nil {
+			logrus.WithError(err).Errorln("Failed to gather table metrics")
+			t.Fail()
+			continue
+		}
+
+		recorder := httptest.NewRecorder()
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if body := recorder.Body.String(); !strings.Contains(body, line) {
+			logrus.WithFields(logrus.Fields{
+				"line":    line,
+				"metrics": body,
+			}).Errorln("Expected table metric was not exposed")
+			t.Fail()
+		}
+	}
+}
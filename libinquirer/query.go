@@ -0,0 +1,53 @@
+package libinquirer
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/soniah/gosnmp"
+)
+
+// Get retrieves OIDs from a host using a single request, returning a walk
+// result per OID so that the values may be output in the same way as the
+// results of a poll. Names label each OID and may be shorter than oids
+func Get(client *gosnmp.GoSNMP, oids, names []string) ([]WalkResult, error) {
+	packet, err := client.Get(oids)
+	if err != nil {
+		return nil, err
+	}
+	if packet.Error != gosnmp.NoError {
+		return nil, errors.Errorf("request failed with error status %s at index %d", packet.Error, packet.ErrorIndex)
+	}
+
+	now := time.Now()
+	results := []WalkResult{}
+	for i, pdu := range packet.Variables {
+		walk := WalkResult{OID: pdu.Name, PDUs: []gosnmp.SnmpPDU{pdu}, Time: now}
+		if i < len(names) {
+			walk.Name = names[i]
+		}
+		results = append(results, walk)
+	}
+
+	return results, nil
+}
+
+// Walk retrieves every OID below an OID from a host. Bulk requests are used
+// except for v1, which does not support them
+func Walk(client *gosnmp.GoSNMP, oid, name string) WalkResult {
+	var pdus []gosnmp.SnmpPDU
+	var err error
+	if client.Version == gosnmp.Version1 {
+		pdus, err = client.WalkAll(oid)
+	} else {
+		pdus, err = client.BulkWalkAll(oid)
+	}
+
+	return WalkResult{
+		OID:  oid,
+		Name: name,
+		PDUs: pdus,
+		Time: time.Now(),
+		Err:  err,
+	}
+}
@@ -0,0 +1,65 @@
+package libinquirer
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+func testQueryClient(t *testing.T, agent *testAgent, version string) *gosnmp.GoSNMP {
+	client, err := CreateClient(localhost, testCommunity, 0, NewVersion(version), nil)
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to create client")
+		t.FailNow()
+	}
+	client.Port = agent.Port()
+
+	if err = client.Connect(); err != nil {
+		logrus.WithError(err).Errorln("Failed to connect to test agent")
+		t.FailNow()
+	}
+
+	return client
+}
+
+func TestGet(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	defer agent.Close()
+
+	client := testQueryClient(t, agent, v2)
+	defer client.Conn.Close()
+
+	walks, err := Get(client, []string{".1.3.6.1.2.1.1.5.0", ".1.3.6.1.2.1.31.1.1.1.1.2"}, []string{"SNMPv2-MIB::sysName.0"})
+	if err != nil || len(walks) != 2 {
+		logrus.WithError(err).Errorln("Failed to get OIDs")
+		t.FailNow()
+	}
+
+	if walks[0].Name != "SNMPv2-MIB::sysName.0" || string(walks[0].PDUs[0].Value.([]byte)) != "test-agent" {
+		logrus.WithField("walk", walks[0]).Errorln("Get returned an invalid value")
+		t.Fail()
+	}
+
+	if walks[1].Name != "" || walks[1].OID != ".1.3.6.1.2.1.31.1.1.1.1.2" || string(walks[1].PDUs[0].Value.([]byte)) != "ge-0/0/1" {
+		logrus.WithField("walk", walks[1]).Errorln("Get returned an invalid value")
+		t.Fail()
+	}
+}
+
+func TestWalk(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	defer agent.Close()
+
+	for _, version := range []string{v1, v2} {
+		client := testQueryClient(t, agent, version)
+
+		walk := Walk(client, ".1.3.6.1.2.1.31.1.1.1.1", "IF-MIB::ifName")
+		if walk.Err != nil || len(walk.PDUs) != 2 || walk.Name != "IF-MIB::ifName" {
+			logrus.WithError(walk.Err).WithField("version", version).Errorln("Failed to walk OID")
+			t.Fail()
+		}
+
+		client.Conn.Close()
+	}
+}
@@ -0,0 +1,149 @@
+package libinquirer
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+// SysUpTimeOID is the OID of SNMPv2-MIB::sysUpTime.0, used to detect devices
+// which have restarted between polls
+const SysUpTimeOID = ".1.3.6.1.2.1.1.3.0"
+
+// Sample is a single counter value retrieved from a host
+type Sample struct {
+	Value uint64         `json:"value"`
+	Type  gosnmp.Asn1BER `json:"type"`
+	// Uptime is the host's sysUpTime, in hundredths of a second, when the
+	// sample was taken. Zero when the uptime could not be retrieved
+	Uptime uint32    `json:"uptime"`
+	Time   time.Time `json:"time"`
+}
+
+// Rate is the change of a counter between two samples
+type Rate struct {
+	Delta     uint64
+	Elapsed   time.Duration
+	PerSecond float64
+	Wrapped   bool
+}
+
+// RateTracker keeps the previous sample of every counter by host, OID and
+// index so that per-second rates can be calculated between polls
+type RateTracker struct {
+	sync.Mutex
+	samples map[string]Sample
+	// rates holds the rate returned for the latest sample of each counter,
+	// so that observing the same sample again returns the same rate
+	rates map[string]*Rate
+}
+
+// NewRateTracker creates a new, empty, rate tracker
+func NewRateTracker() *RateTracker {
+	return &RateTracker{samples: map[string]Sample{}, rates: map[string]*Rate{}}
+}
+
+// SampleKey is used to identify a single counter on a single host
+func SampleKey(host, oid, index string) string {
+	return strings.Join([]string{host, oid, index}, "|")
+}
+
+// NewSample creates a sample from a counter PDU. False is returned when the
+// PDU is not a counter
+func NewSample(pdu gosnmp.SnmpPDU, uptime uint32, t time.Time) (Sample, bool) {
+	if pdu.Type != gosnmp.Counter32 && pdu.Type != gosnmp.Counter64 {
+		return Sample{}, false
+	}
+
+	return Sample{
+		Value:  gosnmp.ToBigInt(pdu.Value).Uint64(),
+		Type:   pdu.Type,
+		Uptime: uptime,
+		Time:   t,
+	}, true
+}
+
+// Observe records a sample and returns the rate since the previous sample of
+// the same counter. Nil is returned for the first sample of a counter or when
+// the counter was reset because the host restarted. Observing the latest
+// sample again, such as a column output both as a record and in a table row,
+// returns the same rate rather than a rate against itself
+func (r *RateTracker) Observe(key string, s Sample) *Rate {
+	r.Lock()
+	defer r.Unlock()
+
+	prev, ok := r.samples[key]
+	if ok && prev == s {
+		return r.rates[key]
+	}
+	r.samples[key] = s
+
+	var rate *Rate
+	if ok {
+		rate = CalculateRate(prev, s)
+	}
+	r.rates[key] = rate
+
+	return rate
+}
+
+// Samples returns a copy of the latest sample of every counter tracked
+func (r *RateTracker) Samples() map[string]Sample {
+	r.Lock()
+	defer r.Unlock()
+
+	samples := make(map[string]Sample, len(r.samples))
+	for k, v := range r.samples {
+		samples[k] = v
+	}
+
+	return samples
+}
+
+// Load adds previously recorded samples to the tracker, such as those read
+// from a state store at the start of a run
+func (r *RateTracker) Load(samples map[string]Sample) {
+	r.Lock()
+	defer r.Unlock()
+
+	for k, v := range samples {
+		r.samples[k] = v
+	}
+}
+
+// CalculateRate returns the rate between two samples of the same counter,
+// accounting for 32-bit and 64-bit counter wraps. Nil is returned when the
+// host's sysUpTime went backwards, indicating the counter was reset
+func CalculateRate(prev, cur Sample) *Rate {
+	elapsed := cur.Time.Sub(prev.Time)
+	if elapsed <= 0 || prev.Type != cur.Type {
+		return nil
+	}
+
+	if prev.Uptime != 0 && cur.Uptime != 0 && cur.Uptime < prev.Uptime {
+		logrus.WithFields(logrus.Fields{
+			"previous_uptime": prev.Uptime,
+			"current_uptime":  cur.Uptime,
+		}).Debugln("sysUpTime went backwards, counter reset detected")
+		return nil
+	}
+
+	rate := &Rate{Elapsed: elapsed}
+	switch {
+	case cur.Value >= prev.Value:
+		rate.Delta = cur.Value - prev.Value
+	case cur.Type == gosnmp.Counter32:
+		rate.Delta = cur.Value + (math.MaxUint32 - prev.Value) + 1
+		rate.Wrapped = true
+	default:
+		rate.Delta = cur.Value + (math.MaxUint64 - prev.Value) + 1
+		rate.Wrapped = true
+	}
+	rate.PerSecond = float64(rate.Delta) / elapsed.Seconds()
+
+	return rate
+}
@@ -0,0 +1,82 @@
+package libinquirer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+func TestNewSampleIgnoresNonCounters(t *testing.T) {
+	_, ok := NewSample(gosnmp.SnmpPDU{Type: gosnmp.Gauge32, Value: uint(10)}, 0, time.Now())
+	if ok {
+		logrus.Errorln("Gauge was incorrectly treated as a counter")
+		t.Fail()
+	}
+}
+
+func TestCalculateRate(t *testing.T) {
+	now := time.Now()
+	prev := Sample{Value: 1000, Type: gosnmp.Counter64, Uptime: 100, Time: now}
+	cur := Sample{Value: 3000, Type: gosnmp.Counter64, Uptime: 1100, Time: now.Add(10 * time.Second)}
+
+	rate := CalculateRate(prev, cur)
+	if rate == nil || rate.Delta != 2000 || rate.PerSecond != 200 || rate.Wrapped {
+		logrus.WithField("rate", rate).Errorln("Incorrect rate calculated")
+		t.Fail()
+	}
+}
+
+func TestCalculateRateCounter32Wrap(t *testing.T) {
+	now := time.Now()
+	prev := Sample{Value: math.MaxUint32 - 9, Type: gosnmp.Counter32, Time: now}
+	cur := Sample{Value: 10, Type: gosnmp.Counter32, Time: now.Add(time.Second)}
+
+	rate := CalculateRate(prev, cur)
+	if rate == nil || rate.Delta != 20 || !rate.Wrapped {
+		logrus.WithField("rate", rate).Errorln("Incorrect 32-bit wrap calculated")
+		t.Fail()
+	}
+}
+
+func TestCalculateRateCounter64Wrap(t *testing.T) {
+	now := time.Now()
+	prev := Sample{Value: math.MaxUint64 - 4, Type: gosnmp.Counter64, Time: now}
+	cur := Sample{Value: 5, Type: gosnmp.Counter64, Time: now.Add(time.Second)}
+
+	rate := CalculateRate(prev, cur)
+	if rate == nil || rate.Delta != 10 || !rate.Wrapped {
+		logrus.WithField("rate", rate).Errorln("Incorrect 64-bit wrap calculated")
+		t.Fail()
+	}
+}
+
+func TestCalculateRateCounterReset(t *testing.T) {
+	now := time.Now()
+	prev := Sample{Value: 5000, Type: gosnmp.Counter32, Uptime: 90000, Time: now}
+	cur := Sample{Value: 10, Type: gosnmp.Counter32, Uptime: 500, Time: now.Add(time.Minute)}
+
+	if rate := CalculateRate(prev, cur); rate != nil {
+		logrus.WithField("rate", rate).Errorln("Counter reset was treated as a wrap")
+		t.Fail()
+	}
+}
+
+func TestRateTrackerObserve(t *testing.T) {
+	now := time.Now()
+	r := NewRateTracker()
+	key := SampleKey(localhost, ".1.3.6.1.2.1.31.1.1.1.6", "1")
+
+	if rate := r.Observe(key, Sample{Value: 100, Type: gosnmp.Counter64, Time: now}); rate != nil {
+		logrus.Errorln("Rate returned for the first sample")
+		t.Fail()
+	}
+
+	rate := r.Observe(key, Sample{Value: 160, Type: gosnmp.Counter64, Time: now.Add(time.Minute)})
+	if rate == nil || rate.PerSecond != 1 {
+		logrus.WithField("rate", rate).Errorln("Incorrect rate observed")
+		t.Fail()
+	}
+}
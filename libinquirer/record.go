@@ -0,0 +1,225 @@
+package libinquirer
+
+import (
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+var pduTypeNames = map[gosnmp.Asn1BER]string{
+	gosnmp.UnknownType:       "UnknownType",
+	gosnmp.Boolean:           "Boolean",
+	gosnmp.Integer:           "Integer",
+	gosnmp.BitString:         "BitString",
+	gosnmp.OctetString:       "OctetString",
+	gosnmp.Null:              "Null",
+	gosnmp.ObjectIdentifier:  "ObjectIdentifier",
+	gosnmp.ObjectDescription: "ObjectDescription",
+	gosnmp.IPAddress:         "IPAddress",
+	gosnmp.Counter32:         "Counter32",
+	gosnmp.Gauge32:           "Gauge32",
+	gosnmp.TimeTicks:         "TimeTicks",
+	gosnmp.Opaque:            "Opaque",
+	gosnmp.NsapAddress:       "NsapAddress",
+	gosnmp.Counter64:         "Counter64",
+	gosnmp.Uinteger32:        "Uinteger32",
+	gosnmp.OpaqueFloat:       "OpaqueFloat",
+	gosnmp.OpaqueDouble:      "OpaqueDouble",
+	gosnmp.NoSuchObject:      "NoSuchObject",
+	gosnmp.NoSuchInstance:    "NoSuchInstance",
+	gosnmp.EndOfMibView:      "EndOfMibView",
+}
+
+// Record is a single typed value retrieved from a host
+type Record struct {
+	Host    string
+	FullOID string
+	BaseOID string
+	MIBName string
+	// Index is the instance of the object, every arc following the OID
+	// walked
+	Index string
+	// IndexValues are the components of the index decoded using the INDEX
+	// clause of the table, nil when the table is not known
+	IndexValues []mib.IndexValue
+	PDUType     gosnmp.Asn1BER
+	// Value is the decoded value of the PDU. Strings are used for octet
+	// strings, OIDs and IP addresses, uint64 for counters, gauges and time
+	// ticks, int64 for integers and float64 for floating point values.
+	// Octet strings hold the raw octets, see ReadableValue for text outputs
+	Value interface{}
+	// Display is the value rendered using the MIB definition of the object,
+	// such as up for ifOperStatus or 00:1a:2b:3c:4d:5e for a MAC address.
+	// It is empty when no MIB definition applies
+	Display string
+	// Rate is the change since the previous poll of a counter, nil when no
+	// previous sample was available
+	Rate      *Rate
+	Timestamp time.Time
+}
+
+// PDUTypeName returns the name of a PDU type, such as Counter64
+func PDUTypeName(t gosnmp.Asn1BER) string {
+	return pduTypeNames[t]
+}
+
+// DecodeValue converts the value of a PDU to a Go type suitable for output
+func DecodeValue(pdu gosnmp.SnmpPDU) interface{} {
+	switch pdu.Type {
+	case gosnmp.OctetString, gosnmp.BitString, gosnmp.Opaque:
+		if b, ok := pdu.Value.([]byte); ok {
+			return string(b)
+		}
+		return pdu.Value
+	case gosnmp.ObjectIdentifier, gosnmp.IPAddress, gosnmp.ObjectDescription:
+		return pdu.Value
+	case gosnmp.Counter32, gosnmp.Counter64, gosnmp.Gauge32, gosnmp.TimeTicks, gosnmp.Uinteger32:
+		return gosnmp.ToBigInt(pdu.Value).Uint64()
+	case gosnmp.Integer:
+		return gosnmp.ToBigInt(pdu.Value).Int64()
+	case gosnmp.OpaqueFloat:
+		if f, ok := pdu.Value.(float32); ok {
+			return float64(f)
+		}
+		return pdu.Value
+	case gosnmp.OpaqueDouble:
+		return pdu.Value
+	case gosnmp.Null, gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView:
+		return nil
+	default:
+		return gosnmp.ToBigInt(pdu.Value)
+	}
+}
+
+// ReadableValue returns a decoded value in a form suitable for text outputs.
+// Octet strings which are not printable, such as MAC addresses without a MIB
+// definition, are hex encoded with a 0x prefix. Other values are unchanged
+func ReadableValue(t gosnmp.Asn1BER, v interface{}) interface{} {
+	switch t {
+	case gosnmp.OctetString, gosnmp.BitString, gosnmp.Opaque:
+		if s, ok := v.(string); ok {
+			return mib.RawOctets([]byte(s))
+		}
+	}
+
+	return v
+}
+
+// Readable returns the value of the record for text outputs
+func (r Record) Readable() interface{} {
+	return ReadableValue(r.PDUType, r.Value)
+}
+
+// DisplayValue renders the value of a PDU using the MIB definition of its
+// object. Enumerations are decoded to their labels and DISPLAY-HINTs are
+// applied. An empty string is returned when no definition applies
+func DisplayValue(m *mib.MIB, pdu gosnmp.SnmpPDU) string {
+	if m == nil {
+		return ""
+	}
+
+	node, _ := m.Lookup(pdu.Name)
+	if node == nil || node.Kind != "OBJECT-TYPE" {
+		return ""
+	}
+
+	t := m.Type(node)
+	if t == nil {
+		return ""
+	}
+
+	switch pdu.Type {
+	case gosnmp.Integer, gosnmp.Gauge32, gosnmp.Uinteger32:
+		v := gosnmp.ToBigInt(pdu.Value).Int64()
+		if label, ok := t.Enums[v]; ok {
+			return label
+		}
+		if t.DisplayHint != "" {
+			if s, err := mib.FormatInteger(t.DisplayHint, v); err == nil {
+				return s
+			}
+		}
+	case gosnmp.OctetString:
+		b, ok := pdu.Value.([]byte)
+		if !ok {
+			return ""
+		}
+		if t.Name == "InetAddress" {
+			return mib.FormatInetAddress(b)
+		}
+		if t.DisplayHint != "" {
+			if s, err := mib.FormatOctets(t.DisplayHint, b); err == nil {
+				return s
+			}
+		}
+	}
+
+	return ""
+}
+
+// TableIndex decodes the index of a table column instance using the INDEX
+// clause of its entry. False is returned when the OID is not a column of a
+// table with a known INDEX or when the index does not match it
+func TableIndex(m *mib.MIB, oid string) (string, []mib.IndexValue, bool) {
+	if m == nil {
+		return "", nil, false
+	}
+
+	node, index := m.Lookup(oid)
+	entry := m.Entry(node)
+	if entry == nil || index == "" {
+		return "", nil, false
+	}
+
+	values, err := m.DecodeIndex(entry, index)
+	if err != nil {
+		logrus.WithError(err).WithField("oid", oid).Debugln("Failed to decode table index")
+		return "", nil, false
+	}
+
+	return index, values, true
+}
+
+// NewRecord creates a record for a PDU retrieved while walking an OID
+func NewRecord(host string, walk WalkResult, pdu gosnmp.SnmpPDU) Record {
+	return Record{
+		Host:      host,
+		FullOID:   pdu.Name,
+		BaseOID:   walk.OID,
+		MIBName:   walk.Name,
+		Index:     InstanceIndex(walk.OID, pdu.Name),
+		PDUType:   pdu.Type,
+		Value:     DecodeValue(pdu),
+		Timestamp: walk.Time,
+	}
+}
+
+// Records creates a record for every PDU retrieved from a host. When a rate
+// tracker is provided, the rate of each counter is included. When a MIB is
+// provided, values are also rendered using the definition of their object
+func Records(result *HostResult, rates *RateTracker, m *mib.MIB) []Record {
+	records := []Record{}
+	for _, walk := range result.Walks {
+		if walk.Err != nil {
+			continue
+		}
+
+		for _, pdu := range walk.PDUs {
+			record := NewRecord(result.Config.Host, walk, pdu)
+			record.Display = DisplayValue(m, pdu)
+			if index, values, ok := TableIndex(m, pdu.Name); ok {
+				record.Index, record.IndexValues = index, values
+			}
+			if rates != nil {
+				if sample, ok := NewSample(pdu, result.Uptime, walk.Time); ok {
+					record.Rate = rates.Observe(SampleKey(record.Host, record.BaseOID, record.Index), sample)
+				}
+			}
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
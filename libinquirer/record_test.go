@@ -0,0 +1,236 @@
+package libinquirer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+const testDisplayMIB = `
+TEST-DISPLAY-MIB DEFINITIONS ::= BEGIN
+IMPORTS
+    OBJECT-TYPE, Integer32, enterprises FROM SNMPv2-SMI
+    TEXTUAL-CONVENTION, MacAddress, DateAndTime, TruthValue FROM SNMPv2-TC
+    InetAddress FROM INET-ADDRESS-MIB;
+
+testDisplay OBJECT IDENTIFIER ::= { enterprises 99999 }
+
+Temperature ::= TEXTUAL-CONVENTION
+    DISPLAY-HINT "d-1"
+    STATUS       current
+    DESCRIPTION  "A temperature in tenths of a degree."
+    SYNTAX       Integer32
+
+testMac OBJECT-TYPE
+    SYNTAX      MacAddress
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "A MAC address."
+    ::= { testDisplay 1 }
+
+testTime OBJECT-TYPE
+    SYNTAX      DateAndTime
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "A date and time."
+    ::= { testDisplay 2 }
+
+testAddress OBJECT-TYPE
+    SYNTAX      InetAddress
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "An address."
+    ::= { testDisplay 3 }
+
+testStatus OBJECT-TYPE
+    SYNTAX      INTEGER { up(1), down(2) }
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "A status."
+    ::= { testDisplay 4 }
+
+testEnabled OBJECT-TYPE
+    SYNTAX      TruthValue
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "A flag."
+    ::= { testDisplay 5 }
+
+testTemperature OBJECT-TYPE
+    SYNTAX      Temperature
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "A temperature."
+    ::= { testDisplay 6 }
+END
+`
+
+func TestPDUTypeName(t *testing.T) {
+	if PDUTypeName(gosnmp.Counter64) != "Counter64" {
+		logrus.Errorln("Incorrect PDU type name returned")
+		t.Fail()
+	}
+}
+
+func TestDecodeValue(t *testing.T) {
+	tests := []struct {
+		pdu      gosnmp.SnmpPDU
+		expected interface{}
+	}{
+		{gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte("ge-0/0/0")}, "ge-0/0/0"},
+		{gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte{0x00, 0x1a, 0x2b, 0xff}}, "\x00\x1a\x2b\xff"},
+		{gosnmp.SnmpPDU{Type: gosnmp.Counter32, Value: uint(42)}, uint64(42)},
+		{gosnmp.SnmpPDU{Type: gosnmp.Counter64, Value: uint64(1 << 40)}, uint64(1 << 40)},
+		{gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: -5}, int64(-5)},
+		{gosnmp.SnmpPDU{Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1"}, ".1.3.6.1"},
+		{gosnmp.SnmpPDU{Type: gosnmp.NoSuchObject}, nil},
+	}
+
+	for _, test := range tests {
+		if v := DecodeValue(test.pdu); v != test.expected {
+			logrus.WithFields(logrus.Fields{
+				"expected": test.expected,
+				"value":    v,
+			}).Errorln("Incorrect value decoded")
+			t.Fail()
+		}
+	}
+}
+
+func TestReadableValue(t *testing.T) {
+	tests := []struct {
+		t        gosnmp.Asn1BER
+		value    interface{}
+		expected interface{}
+	}{
+		{gosnmp.OctetString, "ge-0/0/0", "ge-0/0/0"},
+		{gosnmp.OctetString, "\x00\x1a\x2b\xff", "0x001a2bff"},
+		{gosnmp.Counter64, uint64(42), uint64(42)},
+		{gosnmp.ObjectIdentifier, ".1.3.6.1", ".1.3.6.1"},
+	}
+
+	for _, test := range tests {
+		if v := ReadableValue(test.t, test.value); v != test.expected {
+			logrus.WithFields(logrus.Fields{
+				"expected": test.expected,
+				"value":    v,
+			}).Errorln("Incorrect readable value")
+			t.Fail()
+		}
+	}
+}
+
+func TestDisplayValue(t *testing.T) {
+	m := mib.New()
+	if err := m.Parse(strings.NewReader(testDisplayMIB)); err != nil {
+		logrus.WithError(err).Errorln("Failed to parse test MIB")
+		t.FailNow()
+	}
+
+	tests := []struct {
+		pdu      gosnmp.SnmpPDU
+		expected string
+	}{
+		{gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.1.1", Type: gosnmp.OctetString, Value: []byte{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}}, "00:1a:2b:3c:4d:5e"},
+		{gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.2.0", Type: gosnmp.OctetString, Value: []byte{0x07, 0xea, 10, 18, 13, 30, 15, 0, '+', 2, 0}}, "2026-10-18,13:30:15.0,+2:0"},
+		{gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.3.0", Type: gosnmp.OctetString, Value: []byte{192, 0, 2, 1}}, "192.0.2.1"},
+		{gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.3.0", Type: gosnmp.OctetString, Value: []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}}, "2001:db8::1"},
+		{gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.4.7", Type: gosnmp.Integer, Value: 2}, "down"},
+		{gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.4.7", Type: gosnmp.Integer, Value: 9}, ""},
+		{gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.5.0", Type: gosnmp.Integer, Value: 1}, "true"},
+		{gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.6.0", Type: gosnmp.Integer, Value: 215}, "21.5"},
+		{gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.88888.1.0", Type: gosnmp.Integer, Value: 1}, ""},
+	}
+
+	for _, test := range tests {
+		if v := DisplayValue(m, test.pdu); v != test.expected {
+			logrus.WithFields(logrus.Fields{
+				"oid":      test.pdu.Name,
+				"expected": test.expected,
+				"display":  v,
+			}).Errorln("Incorrect value rendered")
+			t.Fail()
+		}
+	}
+
+	if v := DisplayValue(nil, tests[0].pdu); v != "" {
+		logrus.WithField("display", v).Errorln("Value rendered without a MIB")
+		t.Fail()
+	}
+}
+
+func TestRecords(t *testing.T) {
+	now := time.Now()
+	result := &HostResult{
+		Config: PollConfiguration{Host: localhost},
+		Walks: []WalkResult{{
+			OID:  ".1.3.6.1.2.1.31.1.1.1.6",
+			Name: "IF-MIB::ifHCInOctets",
+			Time: now,
+			PDUs: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.31.1.1.1.6.3", Type: gosnmp.Counter64, Value: uint64(100)}},
+		}},
+	}
+
+	rates := NewRateTracker()
+	records := Records(result, rates, nil)
+	if len(records) != 1 || records[0].Index != "3" || records[0].MIBName != "IF-MIB::ifHCInOctets" || records[0].Rate != nil {
+		logrus.WithField("records", records).Errorln("Incorrect records created")
+		t.FailNow()
+	}
+
+	result.Walks[0].Time = now.Add(10 * time.Second)
+	result.Walks[0].PDUs[0].Value = uint64(200)
+	records = Records(result, rates, nil)
+	if records[0].Rate == nil || records[0].Rate.PerSecond != 10 {
+		logrus.WithField("records", records).Errorln("Rate was not included in record")
+		t.Fail()
+	}
+}
+
+func TestRecordsTableIndex(t *testing.T) {
+	result := &HostResult{
+		Config: PollConfiguration{Host: localhost},
+		Walks: []WalkResult{{
+			OID:  ".1.3.6.1.4.1.2636.3.5.2.1.4",
+			Name: "JUNIPER-FIREWALL-MIB::jnxFWCounterPacketCount",
+			PDUs: []gosnmp.SnmpPDU{
+				{Name: ".1.3.6.1.4.1.2636.3.5.2.1.4.3.97.101.48.5.100.114.111.112.115.3", Type: gosnmp.Counter64, Value: uint64(42)},
+			},
+		}},
+	}
+
+	records := Records(result, nil, testMIB(t))
+	if len(records) != 1 || records[0].Index != "3.97.101.48.5.100.114.111.112.115.3" || len(records[0].IndexValues) != 3 {
+		logrus.WithField("records", records).Errorln("Table index was not decoded")
+		t.FailNow()
+	}
+
+	values := records[0].IndexValues
+	if values[0].Value != "ae0" || values[1].Value != "drops" || values[2].Value != int64(3) || values[2].String() != "policer" {
+		logrus.WithField("index", values).Errorln("Table index components are invalid")
+		t.Fail()
+	}
+
+	// Without a MIB the whole index is kept, so rows whose indexes end in
+	// the same arc remain separate, including their rates
+	result.Walks[0].PDUs = append(result.Walks[0].PDUs, gosnmp.SnmpPDU{
+		Name: ".1.3.6.1.4.1.2636.3.5.2.1.4.3.97.101.49.5.100.114.111.112.115.3", Type: gosnmp.Counter64, Value: uint64(7),
+	})
+	rates := NewRateTracker()
+	result.Walks[0].Time = time.Now()
+	Records(result, rates, nil)
+	result.Walks[0].Time = result.Walks[0].Time.Add(10 * time.Second)
+	records = Records(result, rates, nil)
+	if len(records) != 2 || records[0].Index != "3.97.101.48.5.100.114.111.112.115.3" || records[1].Index != "3.97.101.49.5.100.114.111.112.115.3" || records[0].IndexValues != nil {
+		logrus.WithField("records", records).Errorln("Table index was not kept without a MIB")
+		t.Fail()
+	}
+	if records[0].Rate == nil || records[0].Rate.Delta != 0 || records[1].Rate == nil || records[1].Rate.Delta != 0 {
+		logrus.WithField("records", records).Errorln("Rates were calculated between different rows")
+		t.Fail()
+	}
+}
@@ -0,0 +1,584 @@
+package libinquirer
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	// UDPDestination forwards traps to another receiver as v2c traps
+	UDPDestination = "udp"
+	// WebhookDestination posts traps as JSON to an HTTP endpoint
+	WebhookDestination = "webhook"
+
+	snmpTrapAddressOID    = ".1.3.6.1.6.3.18.1.3.0"
+	snmpTrapEnterpriseOID = ".1.3.6.1.6.3.1.1.4.3.0"
+	defaultCommunity      = "public"
+	defaultQueueSize      = 1000
+)
+
+// RelayConfiguration configures the rules and destinations of the trap-relay
+// command
+type RelayConfiguration struct {
+	// SeverityOID is the variable holding the severity of a trap, which rules
+	// may rewrite, such as a vendor specific severity object
+	SeverityOID  string             `json:"severity_oid"`
+	Destinations []RelayDestination `json:"destinations"`
+	Rules        []RelayRule        `json:"rules"`
+}
+
+// RelayDestination is a receiver traps are forwarded to
+type RelayDestination struct {
+	Name string `json:"name"`
+	// Type is udp or webhook
+	Type string `json:"type"`
+	// Target is a host:port for udp destinations and a URL for webhooks
+	Target string `json:"target"`
+	// Community used when forwarding to udp destinations, defaulting to the
+	// community the trap was received with
+	Community Secret `json:"community"`
+	// QueueSize is the number of traps waiting to be forwarded to the
+	// destination, defaulting to 1000. Traps are dropped while it is full
+	QueueSize int `json:"queue_size"`
+}
+
+// RelayRule applies actions to the traps it matches. Rules are evaluated in
+// order, stopping at the first rule which matches unless it continues
+type RelayRule struct {
+	Name  string     `json:"name"`
+	Match RelayMatch `json:"match"`
+	// Drop discards matching traps
+	Drop bool `json:"drop"`
+	// Dedupe discards traps repeating a trap matched by the rule within the
+	// window in seconds. Traps repeat when their host, OID and variables are
+	// the same
+	Dedupe int `json:"dedupe"`
+	// Severity rewrites the variable named by the relay's severity_oid
+	Severity string `json:"severity"`
+	// Forward names the destinations matching traps are sent to
+	Forward []string `json:"forward"`
+	// Continue evaluates later rules after this rule has matched
+	Continue bool `json:"continue"`
+}
+
+// RelayMatch selects traps. Every condition provided must hold, so an empty
+// match selects every trap
+type RelayMatch struct {
+	// Hosts are the addresses or CIDR ranges traps are received from
+	Hosts []string `json:"hosts"`
+	// TrapOIDs are trap OIDs or names, matching traps at or below them
+	TrapOIDs []string `json:"trap_oids"`
+	// Varbinds maps variable OIDs or names to regular expressions, which
+	// must match the rendered or decoded value of an instance of the variable
+	Varbinds map[string]string `json:"varbinds"`
+}
+
+// relayRule is a rule with its match compiled
+type relayRule struct {
+	RelayRule
+	networks []*net.IPNet
+	oids     []string
+	varbinds map[string]*regexp.Regexp
+}
+
+// trapForwarder sends traps to a single destination
+type trapForwarder interface {
+	Forward(t *Trap) error
+	Close() error
+}
+
+// Relay applies rules to traps, forwarding those which are not dropped to
+// the destinations of the rules they match
+type Relay struct {
+	mib          *mib.MIB
+	rules        []relayRule
+	destinations map[string]trapForwarder
+	severityOID  string
+
+	sync.Mutex
+	seen     map[string]time.Time
+	expiries dedupeQueue
+	now      func() time.Time
+}
+
+// dedupeEntry is a trap seen by a deduplicating rule and when its window
+// expires
+type dedupeEntry struct {
+	key     string
+	expires time.Time
+}
+
+// dedupeQueue is a heap of the traps seen, ordered by expiry, so that expired
+// traps are found without scanning every trap seen
+type dedupeQueue []dedupeEntry
+
+func (q dedupeQueue) Len() int            { return len(q) }
+func (q dedupeQueue) Less(i, j int) bool  { return q[i].expires.Before(q[j].expires) }
+func (q dedupeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *dedupeQueue) Push(x interface{}) { *q = append(*q, x.(dedupeEntry)) }
+
+func (q *dedupeQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return e
+}
+
+// NewRelay compiles the rules of a relay, resolving names using the MIB, and
+// creates its destinations
+func NewRelay(cfg RelayConfiguration, m *mib.MIB) (*Relay, error) {
+	if m == nil {
+		m = mib.New()
+	}
+
+	r := &Relay{
+		mib:          m,
+		destinations: map[string]trapForwarder{},
+		seen:         map[string]time.Time{},
+		now:          time.Now,
+	}
+
+	if cfg.SeverityOID != "" {
+		oid, err := m.Resolve(cfg.SeverityOID)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid severity OID")
+		}
+		r.severityOID = oid
+	}
+
+	for _, d := range cfg.Destinations {
+		if _, ok := r.destinations[d.Name]; ok {
+			r.Close()
+			return nil, errors.Errorf("relay destination %s is configured more than once", d.Name)
+		}
+
+		forwarder, err := newTrapForwarder(d)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.destinations[d.Name] = newQueuedForwarder(d, forwarder)
+	}
+
+	for i, rule := range cfg.Rules {
+		if rule.Name == "" {
+			rule.Name = strconv.Itoa(i + 1)
+		}
+
+		compiled, err := r.compile(rule)
+		if err != nil {
+			r.Close()
+			return nil, errors.Wrapf(err, "invalid relay rule %s", rule.Name)
+		}
+		r.rules = append(r.rules, compiled)
+	}
+
+	return r, nil
+}
+
+func (r *Relay) compile(rule RelayRule) (relayRule, error) {
+	c := relayRule{RelayRule: rule, varbinds: map[string]*regexp.Regexp{}}
+
+	for _, host := range rule.Match.Hosts {
+		if !strings.Contains(host, "/") {
+			if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+				host += "/32"
+			} else {
+				host += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(host)
+		if err != nil {
+			return c, errors.Errorf("invalid host %s", host)
+		}
+		c.networks = append(c.networks, network)
+	}
+
+	for _, name := range rule.Match.TrapOIDs {
+		oid, err := r.mib.Resolve(name)
+		if err != nil {
+			return c, err
+		}
+		c.oids = append(c.oids, oid)
+	}
+
+	for name, pattern := range rule.Match.Varbinds {
+		oid, err := r.mib.Resolve(name)
+		if err != nil {
+			return c, err
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return c, errors.Wrapf(err, "invalid pattern for %s", name)
+		}
+		c.varbinds[oid] = re
+	}
+
+	for _, name := range rule.Forward {
+		if _, ok := r.destinations[name]; !ok {
+			return c, errors.Errorf("unknown destination %s", name)
+		}
+	}
+
+	if rule.Severity != "" && r.severityOID == "" {
+		return c, errors.Errorf("severity requires a severity_oid")
+	}
+
+	return c, nil
+}
+
+// Process applies the rules to a trap and queues it for its destinations,
+// which forward traps in the background. False is returned when the trap was
+// dropped, either by a rule or as a duplicate, and an error when the queue of
+// a destination is full
+func (r *Relay) Process(t *Trap) (bool, error) {
+	forward := []string{}
+	for _, rule := range r.rules {
+		if !rule.matches(t) {
+			continue
+		}
+
+		logger := logrus.WithFields(logrus.Fields{"rule": rule.Name, "host": t.Host, "trap_oid": t.OID})
+		if rule.Drop {
+			logger.Debugln("Trap dropped by relay rule")
+			return false, nil
+		}
+
+		if rule.Dedupe > 0 && r.duplicate(rule.Name, t, time.Duration(rule.Dedupe)*time.Second) {
+			logger.Debugln("Duplicate trap dropped by relay rule")
+			return false, nil
+		}
+
+		if rule.Severity != "" {
+			r.setSeverity(t, rule.Severity)
+		}
+
+		forward = append(forward, rule.Forward...)
+		if !rule.Continue {
+			break
+		}
+	}
+
+	var first error
+	sent := map[string]bool{}
+	for _, name := range forward {
+		if sent[name] {
+			continue
+		}
+		sent[name] = true
+
+		if err := r.destinations[name].Forward(t); err != nil && first == nil {
+			first = errors.Wrapf(err, "failed to queue trap for %s", name)
+		}
+	}
+
+	return true, first
+}
+
+// matches reports whether every condition of the rule holds for a trap
+func (rule relayRule) matches(t *Trap) bool {
+	if len(rule.networks) > 0 {
+		ip := net.ParseIP(t.Host)
+		found := false
+		for _, network := range rule.networks {
+			if ip != nil && network.Contains(ip) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(rule.oids) > 0 {
+		found := false
+		for _, oid := range rule.oids {
+			if t.OID == oid || strings.HasPrefix(t.OID, oid+".") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for oid, re := range rule.varbinds {
+		found := false
+		for _, v := range t.Varbinds {
+			if v.FullOID != oid && !strings.HasPrefix(v.FullOID, oid+".") {
+				continue
+			}
+			if (v.Display != "" && re.MatchString(v.Display)) || re.MatchString(fmt.Sprint(v.Value)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// duplicate reports whether a trap repeats one seen by a rule within the
+// window, recording it otherwise. Expired entries are removed in order of
+// expiry as traps are seen
+func (r *Relay) duplicate(rule string, t *Trap, window time.Duration) bool {
+	parts := []string{rule, t.Host, t.OID}
+	for _, v := range t.Varbinds {
+		parts = append(parts, v.FullOID+"="+fmt.Sprint(v.Value))
+	}
+	key := strings.Join(parts, "|")
+
+	r.Lock()
+	defer r.Unlock()
+
+	now := r.now()
+	for len(r.expiries) > 0 && !now.Before(r.expiries[0].expires) {
+		e := heap.Pop(&r.expiries).(dedupeEntry)
+		if expires, ok := r.seen[e.key]; ok && !now.Before(expires) {
+			delete(r.seen, e.key)
+		}
+	}
+
+	if _, ok := r.seen[key]; ok {
+		return true
+	}
+	r.seen[key] = now.Add(window)
+	heap.Push(&r.expiries, dedupeEntry{key: key, expires: r.seen[key]})
+
+	return false
+}
+
+// setSeverity rewrites the severity variable of a trap, adding it when the
+// trap does not have one. Integer variables remain integers when the
+// severity is numeric
+func (r *Relay) setSeverity(t *Trap, severity string) {
+	pdu := gosnmp.SnmpPDU{Name: r.severityOID, Type: gosnmp.OctetString, Value: []byte(severity)}
+
+	index := -1
+	for i, v := range t.Variables {
+		if v.Name == r.severityOID || strings.HasPrefix(v.Name, r.severityOID+".") {
+			index = i
+			pdu.Name = v.Name
+			if n, err := strconv.Atoi(severity); err == nil && v.Type == gosnmp.Integer {
+				pdu.Type, pdu.Value = gosnmp.Integer, n
+			}
+			break
+		}
+	}
+
+	varbind := NewVarbind(t.Host, pdu, r.mib, t.Timestamp)
+	if index < 0 {
+		t.Variables = append(t.Variables, pdu)
+		t.Varbinds = append(t.Varbinds, varbind)
+		return
+	}
+
+	t.Variables[index] = pdu
+	t.Varbinds[index] = varbind
+}
+
+// Close closes every destination once the traps queued for it have been
+// forwarded
+func (r *Relay) Close() error {
+	names := make([]string, 0, len(r.destinations))
+	for name := range r.destinations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var first error
+	for _, name := range names {
+		if err := r.destinations[name].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+func newTrapForwarder(d RelayDestination) (trapForwarder, error) {
+	if d.Name == "" {
+		return nil, errors.Errorf("relay destination %s has no name", d.Target)
+	}
+
+	switch d.Type {
+	case UDPDestination:
+		conn, err := net.Dial("udp", d.Target)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid relay destination %s", d.Name)
+		}
+		return &udpForwarder{conn: conn, community: d.Community.Reveal()}, nil
+	case WebhookDestination:
+		if !strings.HasPrefix(d.Target, "http://") && !strings.HasPrefix(d.Target, "https://") {
+			return nil, errors.Errorf("relay destination %s requires an http or https URL", d.Name)
+		}
+		return &webhookForwarder{url: d.Target, client: &http.Client{Timeout: time.Duration(10) * time.Second}}, nil
+	default:
+		return nil, errors.Errorf("Invalid relay destination type %s. Please select udp or webhook", d.Type)
+	}
+}
+
+// queuedForwarder forwards traps to a destination from its own goroutine, so
+// that a slow destination delays neither the reception of traps nor other
+// destinations
+type queuedForwarder struct {
+	name      string
+	forwarder trapForwarder
+	queue     chan *Trap
+	done      chan struct{}
+}
+
+func newQueuedForwarder(d RelayDestination, forwarder trapForwarder) *queuedForwarder {
+	size := d.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+
+	f := &queuedForwarder{
+		name:      d.Name,
+		forwarder: forwarder,
+		queue:     make(chan *Trap, size),
+		done:      make(chan struct{}),
+	}
+	go f.run()
+
+	return f
+}
+
+func (f *queuedForwarder) run() {
+	defer close(f.done)
+	for t := range f.queue {
+		if err := f.forwarder.Forward(t); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"destination": f.name,
+				"host":        t.Host,
+				"trap_oid":    t.OID,
+			}).Errorln("Failed to forward trap")
+		}
+	}
+}
+
+// Forward queues a trap, dropping it when the queue is full
+func (f *queuedForwarder) Forward(t *Trap) error {
+	select {
+	case f.queue <- t:
+		return nil
+	default:
+		return errors.Errorf("queue of %d traps is full, trap dropped", cap(f.queue))
+	}
+}
+
+// Close waits for queued traps to be forwarded and closes the destination
+func (f *queuedForwarder) Close() error {
+	close(f.queue)
+	<-f.done
+
+	return f.forwarder.Close()
+}
+
+// udpForwarder sends traps to another receiver as v2c traps. v1 traps are
+// converted as described in RFC 3584 section 3.1
+type udpForwarder struct {
+	sync.Mutex
+	conn      net.Conn
+	community string
+}
+
+func (f *udpForwarder) Forward(t *Trap) error {
+	community := f.community
+	if community == "" {
+		community = t.Community
+	}
+	if community == "" {
+		community = defaultCommunity
+	}
+
+	variables := []gosnmp.SnmpPDU{
+		{Name: sysUpTimeOID, Type: gosnmp.TimeTicks, Value: t.Uptime},
+		{Name: snmpTrapOID, Type: gosnmp.ObjectIdentifier, Value: t.OID},
+	}
+	variables = append(variables, t.Variables...)
+	if t.AgentAddress != "" {
+		variables = append(variables, gosnmp.SnmpPDU{Name: snmpTrapAddressOID, Type: gosnmp.IPAddress, Value: t.AgentAddress})
+	}
+	if t.Enterprise != "" {
+		variables = append(variables, gosnmp.SnmpPDU{Name: snmpTrapEnterpriseOID, Type: gosnmp.ObjectIdentifier, Value: t.Enterprise})
+	}
+
+	packet := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: community,
+		PDUType:   gosnmp.SNMPv2Trap,
+		Variables: variables,
+	}
+	b, err := packet.MarshalMsg()
+	if err != nil {
+		return err
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	_, err = f.conn.Write(b)
+
+	return err
+}
+
+func (f *udpForwarder) Close() error {
+	return f.conn.Close()
+}
+
+// webhookForwarder posts traps to an HTTP endpoint, using the same JSON
+// representation as the json output
+type webhookForwarder struct {
+	url    string
+	client *http.Client
+}
+
+func (f *webhookForwarder) Forward(t *Trap) error {
+	body, err := json.Marshal(newJSONTrap(*t))
+	if err != nil {
+		return err
+	}
+
+	resp, err := f.client.Post(f.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf("webhook failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+func (f *webhookForwarder) Close() error {
+	return nil
+}
@@ -0,0 +1,402 @@
+package libinquirer
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+const testSeverityOID = ".1.3.6.1.4.1.99999.2.0"
+
+// testStandIn listens for traps forwarded by a relay in place of a receiver
+func testStandIn(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", localhost+":0")
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to listen for forwarded traps")
+		t.FailNow()
+	}
+
+	return conn
+}
+
+// receiveForwarded decodes the next trap received by a stand-in receiver,
+// returning nil when none arrives
+func receiveForwarded(t *testing.T, conn net.PacketConn) *gosnmp.SnmpPacket {
+	b := make([]byte, 65535)
+	conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	n, _, err := conn.ReadFrom(b)
+	if err != nil {
+		return nil
+	}
+
+	packet, err := (&gosnmp.GoSNMP{Version: gosnmp.Version2c}).SnmpDecodePacket(b[:n])
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to decode forwarded trap")
+		t.FailNow()
+	}
+
+	return packet
+}
+
+func testRelay(t *testing.T, cfg RelayConfiguration) *Relay {
+	r, err := NewRelay(cfg, testMIB(t))
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to create relay")
+		t.FailNow()
+	}
+
+	return r
+}
+
+func testRelayTrap(t *testing.T, host string, variables []gosnmp.SnmpPDU) *Trap {
+	packet := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: testCommunity,
+		PDUType:   gosnmp.SNMPv2Trap,
+		Variables: append([]gosnmp.SnmpPDU{{Name: sysUpTimeOID, Type: gosnmp.TimeTicks, Value: uint32(1200)}}, variables...),
+	}
+
+	return NewTrap(host, packet, testMIB(t), time.Now())
+}
+
+func udpDestination(name string, conn net.PacketConn) RelayDestination {
+	return RelayDestination{Name: name, Type: UDPDestination, Target: conn.LocalAddr().String()}
+}
+
+func TestRelayForward(t *testing.T) {
+	conn := testStandIn(t)
+	defer conn.Close()
+
+	r := testRelay(t, RelayConfiguration{
+		Destinations: []RelayDestination{udpDestination("nms", conn)},
+		Rules: []RelayRule{{
+			Match: RelayMatch{
+				Hosts:    []string{"127.0.0.0/8"},
+				TrapOIDs: []string{"IF-MIB::linkDown"},
+				Varbinds: map[string]string{"ifOperStatus": "^down$"},
+			},
+			Forward: []string{"nms"},
+		}},
+	})
+	defer r.Close()
+
+	forwarded, err := r.Process(testRelayTrap(t, localhost, testLinkDown()))
+	if err != nil || !forwarded {
+		logrus.WithError(err).Errorln("Failed to relay trap")
+		t.FailNow()
+	}
+
+	packet := receiveForwarded(t, conn)
+	if packet == nil || packet.PDUType != gosnmp.SNMPv2Trap || packet.Community != testCommunity || len(packet.Variables) != 4 {
+		logrus.WithField("packet", packet).Errorln("Trap was not forwarded")
+		t.FailNow()
+	}
+
+	if packet.Variables[0].Name != sysUpTimeOID || packet.Variables[1].Value != ".1.3.6.1.6.3.1.1.5.3" || packet.Variables[3].Name != ".1.3.6.1.2.1.2.2.1.8.3" {
+		logrus.WithField("variables", packet.Variables).Errorln("Forwarded trap has invalid variables")
+		t.Fail()
+	}
+
+	// Traps from other hosts, or with other values, match no rule
+	for _, trap := range []*Trap{
+		testRelayTrap(t, "192.0.2.1", testLinkDown()),
+		testRelayTrap(t, localhost, []gosnmp.SnmpPDU{
+			{Name: snmpTrapOID, Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.3"},
+			{Name: ".1.3.6.1.2.1.2.2.1.8.3", Type: gosnmp.Integer, Value: 1},
+		}),
+	} {
+		r.Process(trap)
+		if packet := receiveForwarded(t, conn); packet != nil {
+			logrus.WithField("host", trap.Host).Errorln("Trap matching no rule was forwarded")
+			t.Fail()
+		}
+	}
+}
+
+func TestRelayForwardV1(t *testing.T) {
+	conn := testStandIn(t)
+	defer conn.Close()
+
+	r := testRelay(t, RelayConfiguration{
+		Destinations: []RelayDestination{udpDestination("nms", conn)},
+		Rules:        []RelayRule{{Forward: []string{"nms"}}},
+	})
+	defer r.Close()
+
+	trap := NewTrap(localhost, &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version1,
+		Community: testCommunity,
+		PDUType:   gosnmp.Trap,
+		Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.2.2.1.1.3", Type: gosnmp.Integer, Value: 3}},
+		SnmpTrap: gosnmp.SnmpTrap{
+			Enterprise:   ".1.3.6.1.4.1.2636",
+			AgentAddress: "192.0.2.1",
+			GenericTrap:  2,
+			Timestamp:    500,
+		},
+	}, testMIB(t), time.Now())
+	if _, err := r.Process(trap); err != nil {
+		logrus.WithError(err).Errorln("Failed to relay v1 trap")
+		t.FailNow()
+	}
+
+	// RFC 3584 section 3.1 appends snmpTrapAddress.0 and snmpTrapEnterprise.0
+	packet := receiveForwarded(t, conn)
+	if packet == nil || len(packet.Variables) != 5 {
+		logrus.WithField("packet", packet).Errorln("v1 trap was not forwarded")
+		t.FailNow()
+	}
+
+	address, enterprise := packet.Variables[3], packet.Variables[4]
+	if packet.Variables[1].Value != ".1.3.6.1.6.3.1.1.5.3" || address.Name != snmpTrapAddressOID || address.Value != "192.0.2.1" ||
+		enterprise.Name != snmpTrapEnterpriseOID || enterprise.Type != gosnmp.ObjectIdentifier || enterprise.Value != ".1.3.6.1.4.1.2636" {
+		logrus.WithField("variables", packet.Variables).Errorln("v1 trap was converted incorrectly")
+		t.Fail()
+	}
+}
+
+func TestRelayDrop(t *testing.T) {
+	conn := testStandIn(t)
+	defer conn.Close()
+
+	r := testRelay(t, RelayConfiguration{
+		Destinations: []RelayDestination{udpDestination("nms", conn)},
+		Rules: []RelayRule{
+			{Name: "link-flaps", Match: RelayMatch{TrapOIDs: []string{"IF-MIB::linkDown", "IF-MIB::linkUp"}}, Drop: true},
+			{Name: "default", Forward: []string{"nms"}},
+		},
+	})
+	defer r.Close()
+
+	if forwarded, err := r.Process(testRelayTrap(t, localhost, testLinkDown())); err != nil || forwarded {
+		logrus.WithError(err).Errorln("Link flap was not dropped")
+		t.Fail()
+	}
+	if packet := receiveForwarded(t, conn); packet != nil {
+		logrus.Errorln("Dropped trap was forwarded")
+		t.Fail()
+	}
+
+	coldStart := []gosnmp.SnmpPDU{{Name: snmpTrapOID, Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.1"}}
+	if forwarded, err := r.Process(testRelayTrap(t, localhost, coldStart)); err != nil || !forwarded {
+		logrus.WithError(err).Errorln("Trap matching the default rule was not relayed")
+		t.Fail()
+	}
+	if packet := receiveForwarded(t, conn); packet == nil {
+		logrus.Errorln("Trap matching the default rule was not forwarded")
+		t.Fail()
+	}
+}
+
+func TestRelayDedupe(t *testing.T) {
+	conn := testStandIn(t)
+	defer conn.Close()
+
+	r := testRelay(t, RelayConfiguration{
+		Destinations: []RelayDestination{udpDestination("nms", conn)},
+		Rules:        []RelayRule{{Dedupe: 60, Forward: []string{"nms"}}},
+	})
+	defer r.Close()
+
+	now := time.Unix(1500000000, 0)
+	r.now = func() time.Time { return now }
+
+	expected := []bool{true, false, true}
+	for i, offset := range []time.Duration{0, 30 * time.Second, 90 * time.Second} {
+		now = time.Unix(1500000000, 0).Add(offset)
+		forwarded, err := r.Process(testRelayTrap(t, localhost, testLinkDown()))
+		if err != nil || forwarded != expected[i] {
+			logrus.WithError(err).WithField("offset", offset).Errorln("Duplicate trap was handled incorrectly")
+			t.Fail()
+		}
+
+		if packet := receiveForwarded(t, conn); (packet != nil) != expected[i] {
+			logrus.WithField("offset", offset).Errorln("Duplicate trap was forwarded incorrectly")
+			t.Fail()
+		}
+	}
+
+	// Traps from another host are not duplicates
+	if forwarded, _ := r.Process(testRelayTrap(t, "192.0.2.1", testLinkDown())); !forwarded {
+		logrus.Errorln("Trap from another host was treated as a duplicate")
+		t.Fail()
+	}
+
+	// Traps are forgotten once their window has passed
+	now = now.Add(60 * time.Second)
+	r.Process(testRelayTrap(t, "192.0.2.2", testLinkDown()))
+	if len(r.seen) != 1 || len(r.expiries) != 1 {
+		logrus.WithField("seen", r.seen).Errorln("Expired traps were not removed")
+		t.Fail()
+	}
+}
+
+func TestRelaySeverity(t *testing.T) {
+	conn := testStandIn(t)
+	defer conn.Close()
+
+	r := testRelay(t, RelayConfiguration{
+		SeverityOID:  testSeverityOID,
+		Destinations: []RelayDestination{udpDestination("nms", conn)},
+		Rules: []RelayRule{
+			{Match: RelayMatch{Varbinds: map[string]string{testSeverityOID: "^[0-9]+$"}}, Severity: "2", Forward: []string{"nms"}},
+			{Severity: "minor", Forward: []string{"nms"}},
+		},
+	})
+	defer r.Close()
+
+	// Severities which are integers remain integers
+	trap := testRelayTrap(t, localhost, append(testLinkDown(), gosnmp.SnmpPDU{Name: testSeverityOID, Type: gosnmp.Integer, Value: 5}))
+	r.Process(trap)
+	packet := receiveForwarded(t, conn)
+	if packet == nil || len(packet.Variables) != 5 || packet.Variables[4].Type != gosnmp.Integer || packet.Variables[4].Value != 2 {
+		logrus.WithField("packet", packet).Errorln("Integer severity was not rewritten")
+		t.Fail()
+	}
+
+	// Traps without a severity have one added
+	trap = testRelayTrap(t, localhost, testLinkDown())
+	r.Process(trap)
+	if last := trap.Varbinds[len(trap.Varbinds)-1]; last.FullOID != testSeverityOID || last.Value != "minor" {
+		logrus.WithField("varbind", last).Errorln("Severity was not added to trap")
+		t.Fail()
+	}
+
+	packet = receiveForwarded(t, conn)
+	if packet == nil || len(packet.Variables) != 5 || string(packet.Variables[4].Value.([]byte)) != "minor" {
+		logrus.WithField("packet", packet).Errorln("Added severity was not forwarded")
+		t.Fail()
+	}
+}
+
+func TestRelayWebhook(t *testing.T) {
+	received := make(chan jsonTrap, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var trap jsonTrap
+		if err := json.NewDecoder(req.Body).Decode(&trap); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received <- trap
+	}))
+	defer server.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	r := testRelay(t, RelayConfiguration{
+		Destinations: []RelayDestination{
+			{Name: "webhook", Type: WebhookDestination, Target: server.URL},
+			{Name: "failing", Type: WebhookDestination, Target: failing.URL},
+		},
+		Rules: []RelayRule{
+			{Match: RelayMatch{Hosts: []string{localhost}}, Forward: []string{"webhook", "webhook"}},
+			{Forward: []string{"failing"}},
+		},
+	})
+	defer r.Close()
+
+	if _, err := r.Process(testRelayTrap(t, localhost, testLinkDown())); err != nil {
+		logrus.WithError(err).Errorln("Failed to post trap to webhook")
+		t.FailNow()
+	}
+
+	trap := <-received
+	if trap.TrapName != "IF-MIB::linkDown" || trap.Host != localhost || len(trap.Varbinds) != 2 {
+		logrus.WithField("trap", trap).Errorln("Webhook received an invalid trap")
+		t.Fail()
+	}
+	if len(received) != 0 {
+		logrus.Errorln("Trap was posted to a webhook more than once")
+		t.Fail()
+	}
+
+	// Failures are logged by the destination rather than returned
+	if _, err := r.Process(testRelayTrap(t, "192.0.2.1", testLinkDown())); err != nil {
+		logrus.WithError(err).Errorln("Failed to queue trap for failing webhook")
+		t.Fail()
+	}
+}
+
+func TestRelaySlowDestination(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	defer slow.Close()
+
+	conn := testStandIn(t)
+	defer conn.Close()
+
+	r := testRelay(t, RelayConfiguration{
+		Destinations: []RelayDestination{
+			{Name: "slow", Type: WebhookDestination, Target: slow.URL, QueueSize: 1},
+			udpDestination("nms", conn),
+		},
+		Rules: []RelayRule{{Forward: []string{"slow", "nms"}}},
+	})
+
+	start := time.Now()
+	r.Process(testRelayTrap(t, localhost, testLinkDown()))
+	<-started
+
+	// The first trap is being posted, the second waits in the queue and the
+	// third is dropped for the slow destination only
+	if _, err := r.Process(testRelayTrap(t, localhost, testLinkDown())); err != nil {
+		logrus.WithError(err).Errorln("Failed to queue trap behind slow destination")
+		t.Fail()
+	}
+	if _, err := r.Process(testRelayTrap(t, localhost, testLinkDown())); err == nil {
+		logrus.Errorln("Trap was queued beyond the size of the queue")
+		t.Fail()
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		logrus.WithField("elapsed", elapsed).Errorln("Slow destination delayed processing")
+		t.Fail()
+	}
+
+	for i := 0; i < 3; i++ {
+		if packet := receiveForwarded(t, conn); packet == nil {
+			logrus.WithField("trap", i).Errorln("Slow destination delayed other destinations")
+			t.Fail()
+		}
+	}
+
+	close(release)
+	r.Close()
+	if len(started) != 1 {
+		logrus.WithField("posted", len(started)+1).Errorln("Queued trap was not posted before closing")
+		t.Fail()
+	}
+}
+
+func TestNewRelayInvalid(t *testing.T) {
+	destinations := []RelayDestination{{Name: "webhook", Type: WebhookDestination, Target: "http://" + localhost}}
+	configs := []RelayConfiguration{
+		{Destinations: []RelayDestination{{Name: "nms", Type: invalid, Target: localhost + ":162"}}},
+		{Destinations: []RelayDestination{{Name: "webhook", Type: WebhookDestination, Target: localhost}}},
+		{Destinations: destinations, Rules: []RelayRule{{Forward: []string{invalid}}}},
+		{Rules: []RelayRule{{Match: RelayMatch{Hosts: []string{invalid}}}}},
+		{Rules: []RelayRule{{Match: RelayMatch{TrapOIDs: []string{invalid}}}}},
+		{Rules: []RelayRule{{Match: RelayMatch{Varbinds: map[string]string{"ifOperStatus": "("}}}}},
+		{Rules: []RelayRule{{Severity: "major"}}},
+		{Destinations: append(destinations, destinations[0])},
+	}
+
+	for i, cfg := range configs {
+		if _, err := NewRelay(cfg, testMIB(t)); err == nil {
+			logrus.WithField("config", i).Errorln("Invalid relay configuration was accepted")
+			t.Fail()
+		}
+	}
+}
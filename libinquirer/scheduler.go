@@ -0,0 +1,149 @@
+package libinquirer
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+const defaultInterval = time.Duration(60) * time.Second
+
+// Scheduler is used to poll each host on its own interval for as long as the
+// process runs. Clients are kept alive between polls so SNMP v3 keys and
+// sockets are only created once per host
+type Scheduler struct {
+	// Poller is used to poll each host and bounds how many hosts are polled
+	// at the same time
+	Poller *Poller
+	// Interval is used for any host which does not configure an interval in
+	// seconds of its own
+	Interval time.Duration
+	// Jitter is the maximum random delay added to each interval to avoid
+	// polling every host at the same moment
+	Jitter time.Duration
+	// Handler receives the result of every poll. It may be called from
+	// multiple goroutines at once
+	Handler func(*HostResult)
+
+	slots chan struct{}
+}
+
+// NewScheduler creates a new scheduler which sends every poll result to the
+// handler provided
+func NewScheduler(p *Poller, interval, jitter time.Duration, handler func(*HostResult)) *Scheduler {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	if jitter < 0 {
+		jitter = 0
+	}
+
+	return &Scheduler{
+		Poller:   p,
+		Interval: interval,
+		Jitter:   jitter,
+		Handler:  handler,
+		slots:    make(chan struct{}, p.Workers),
+	}
+}
+
+// Run schedules every configuration provided and blocks until the context is
+// cancelled and all in-flight polls have completed. Cancelling the context
+// only stops new polls, those in flight run to completion or their host
+// timeout and are passed to the handler
+func (s *Scheduler) Run(ctx context.Context, confs []PollConfiguration) {
+	var wg sync.WaitGroup
+	for _, cfg := range confs {
+		wg.Add(1)
+		go func(cfg PollConfiguration) {
+			defer wg.Done()
+			s.schedule(ctx, cfg)
+		}(cfg)
+	}
+
+	wg.Wait()
+	logrus.Debugln("Scheduler stopped")
+}
+
+// IntervalFor returns the polling interval to use for a configuration
+func (s *Scheduler) IntervalFor(cfg PollConfiguration) time.Duration {
+	if cfg.Interval > 0 {
+		return time.Duration(cfg.Interval) * time.Second
+	}
+
+	return s.Interval
+}
+
+func (s *Scheduler) schedule(ctx context.Context, cfg PollConfiguration) {
+	interval := s.IntervalFor(cfg)
+	logrus.WithFields(logrus.Fields{
+		"host":     cfg.Host,
+		"interval": interval,
+	}).Debugln("Scheduling host")
+
+	var client *gosnmp.GoSNMP
+	defer func() {
+		if client != nil && client.Conn != nil {
+			client.Conn.Close()
+		}
+	}()
+
+	// Stagger the first poll of each host across the interval
+	var delay time.Duration
+	if s.Jitter > 0 {
+		delay = s.jitter(interval)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		if client == nil {
+			var err error
+			client, err = CreateClientFromConfig(&cfg)
+			if err != nil {
+				s.Handler(&HostResult{Config: cfg, Started: time.Now(), Err: err})
+				delay = interval + s.jitter(s.Jitter)
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case s.slots <- struct{}{}:
+		}
+		// Polls in flight are not interrupted by shutdown, so their results
+		// are still delivered. The poller bounds them by its host timeout
+		result := s.Poller.PollClient(context.Background(), client, cfg)
+		<-s.slots
+
+		if result.Err != nil && client.Conn != nil {
+			// Start afresh next time in case the socket is no longer usable
+			client.Conn.Close()
+			client.Conn = nil
+		}
+		s.Handler(result)
+
+		delay = interval - result.Duration
+		if delay < 0 {
+			delay = 0
+		}
+		delay += s.jitter(s.Jitter)
+	}
+}
+
+func (s *Scheduler) jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(max)))
+}
@@ -0,0 +1,92 @@
+package libinquirer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewSchedulerDefaults(t *testing.T) {
+	s := NewScheduler(NewPoller(1, 0, 0), 0, -1, func(*HostResult) {})
+	if s.Interval != defaultInterval || s.Jitter != 0 {
+		logrus.WithField("scheduler", s).Errorln("Scheduler defaults were not applied")
+		t.Fail()
+	}
+}
+
+func TestSchedulerIntervalFor(t *testing.T) {
+	s := NewScheduler(NewPoller(1, 0, 0), time.Minute, 0, func(*HostResult) {})
+	if s.IntervalFor(PollConfiguration{}) != time.Minute {
+		logrus.Errorln("Default interval was not used")
+		t.Fail()
+	}
+
+	if s.IntervalFor(PollConfiguration{Interval: 10}) != 10*time.Second {
+		logrus.Errorln("Configured interval was not used")
+		t.Fail()
+	}
+}
+
+func TestSchedulerRun(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	defer agent.Close()
+
+	var lock sync.Mutex
+	results := []*HostResult{}
+	s := NewScheduler(NewPoller(2, time.Second, 0), 100*time.Millisecond, 10*time.Millisecond, func(r *HostResult) {
+		lock.Lock()
+		defer lock.Unlock()
+		results = append(results, r)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 550*time.Millisecond)
+	defer cancel()
+	s.Run(ctx, []PollConfiguration{testAgentConfig(agent.Port()), testAgentConfig(agent.Port())})
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(results) < 6 {
+		logrus.WithField("results", len(results)).Errorln("Hosts were not polled on their interval")
+		t.Fail()
+	}
+
+	for _, r := range results {
+		if r.Err != nil {
+			logrus.WithError(r.Err).Errorln("Scheduled poll failed")
+			t.Fail()
+		}
+	}
+}
+
+func TestSchedulerRunCompletesPollOnCancel(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	defer agent.Close()
+	agent.setDelay(100 * time.Millisecond)
+
+	results := make(chan *HostResult, 1)
+	s := NewScheduler(NewPoller(1, 5*time.Second, 0), time.Minute, 0, func(r *HostResult) {
+		results <- r
+	})
+
+	cfg := testAgentConfig(agent.Port())
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	s.Run(ctx, []PollConfiguration{cfg})
+
+	select {
+	case r := <-results:
+		if r.Err != nil || len(r.Walks) != len(cfg.OIDs) {
+			logrus.WithError(r.Err).Errorln("Poll in flight was interrupted by shutdown")
+			t.Fail()
+		}
+	default:
+		logrus.Errorln("Result of poll in flight was not delivered")
+		t.Fail()
+	}
+}
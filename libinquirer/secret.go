@@ -0,0 +1,66 @@
+package libinquirer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// redacted replaces the value of secrets in output
+const redacted = "[REDACTED]"
+
+// Secret is a string, such as a community or passphrase, which redacts itself
+// when formatted using fmt, which logrus uses for fields, or marshaled to
+// JSON. Reveal returns the value for use in requests. An empty secret is
+// formatted as an empty string so that missing secrets remain visible
+type Secret string
+
+// Reveal returns the value of the secret
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// String redacts the secret
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+// GoString redacts the secret when formatted using %#v
+func (s Secret) GoString() string {
+	return fmt.Sprintf("%q", s.String())
+}
+
+// Format redacts the secret for every verb, including those which are not
+// valid for strings and would otherwise print its value
+func (s Secret) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('#'):
+		fmt.Fprint(f, s.GoString())
+	case verb == 'q':
+		fmt.Fprintf(f, "%q", s.String())
+	default:
+		fmt.Fprint(f, s.String())
+	}
+}
+
+// MarshalJSON redacts the secret
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Secrets converts strings, such as communities provided as flags, to secrets
+func Secrets(values []string) []Secret {
+	if values == nil {
+		return nil
+	}
+
+	s := make([]Secret, 0, len(values))
+	for _, v := range values {
+		s = append(s, Secret(v))
+	}
+
+	return s
+}
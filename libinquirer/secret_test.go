@@ -0,0 +1,70 @@
+package libinquirer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSecretRedaction(t *testing.T) {
+	cfg := PollConfiguration{Host: localhost, Community: testCommunity}
+	cfg.AuthPassword = "auth-passphrase"
+	cfg.PrivKey = "6695febc9288e36282235fc7151f1284"
+	a, _ := NewAuth("user", authpriv, "auth-passphrase", sha, "priv-passphrase", aes)
+
+	formatted := []string{
+		fmt.Sprint(cfg.Community),
+		fmt.Sprintf("%v %+v %#v %s %q %x %d", cfg, cfg, cfg, cfg.Community, cfg.Community, cfg.Community, cfg.Community),
+		fmt.Sprintf("%v %+v %#v", a, a, *a),
+	}
+
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to marshal configuration")
+		t.FailNow()
+	}
+	formatted = append(formatted, string(b))
+
+	for _, formatter := range []logrus.Formatter{&logrus.TextFormatter{DisableColors: true}, &logrus.JSONFormatter{}} {
+		var buf bytes.Buffer
+		logger := logrus.New()
+		logger.Out, logger.Formatter = &buf, formatter
+		logger.WithFields(logrus.Fields{"community": cfg.Community, "auth": a, "config": cfg}).Infoln("Polling")
+		formatted = append(formatted, buf.String())
+	}
+
+	for _, s := range formatted {
+		for _, secret := range []string{testCommunity, "passphrase", "6695febc", "5465737"} {
+			if strings.Contains(s, secret) {
+				logrus.WithField("secret", secret).Errorln("Secret was not redacted")
+				t.Fail()
+			}
+		}
+		if !strings.Contains(s, redacted) {
+			logrus.Errorln("Output does not contain a redacted secret")
+			t.Fail()
+		}
+	}
+
+	if cfg.Community.Reveal() != testCommunity || Secret("").String() != "" {
+		logrus.Errorln("Secret was not revealed")
+		t.Fail()
+	}
+}
+
+func TestParseSecret(t *testing.T) {
+	var cfg PollConfiguration
+	if err := json.Unmarshal([]byte(`{"host": "127.0.0.1", "community": "Test", "auth_password": "auth-passphrase"}`), &cfg); err != nil {
+		logrus.WithError(err).Errorln("Failed to parse configuration")
+		t.FailNow()
+	}
+
+	if cfg.Community.Reveal() != testCommunity || cfg.AuthPassword.Reveal() != "auth-passphrase" {
+		logrus.Errorln("Secrets were not parsed")
+		t.Fail()
+	}
+}
@@ -0,0 +1,312 @@
+package libinquirer
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// VaultSecretProvider is the type of secret provider reading secrets
+	// from the HTTP API of a Vault server
+	VaultSecretProvider = "vault"
+
+	defaultSecretTimeout = time.Duration(10) * time.Second
+)
+
+// SecretProvider resolves references to secrets held outside of the
+// configuration file. The reference is the part of a secret after the name
+// of its provider, such as SNMP_COMMUNITY in env:SNMP_COMMUNITY
+type SecretProvider interface {
+	Resolve(ref string) (string, error)
+}
+
+// SecretProviders are the providers of secret references by name. Secrets
+// starting with a name followed by a colon are resolved by the provider,
+// other secrets are used as they are
+var SecretProviders = map[string]SecretProvider{
+	"env":  EnvProvider{},
+	"file": FileProvider{},
+	"exec": ExecProvider{Timeout: defaultSecretTimeout},
+}
+
+// SecretProviderConfiguration configures an additional secret provider,
+// named by the key of the configuration in secret_providers
+type SecretProviderConfiguration struct {
+	Type string `json:"type"`
+	// Address is the URL of the server holding secrets
+	Address string `json:"address"`
+	// Token authenticates to the server. It may itself be a reference to a
+	// secret, such as env:VAULT_TOKEN
+	Token Secret `json:"token"`
+	// Timeout is the number of seconds to wait for a secret
+	Timeout int `json:"timeout"`
+}
+
+// NewSecretProvider creates the secret provider of a configuration
+func NewSecretProvider(c SecretProviderConfiguration) (SecretProvider, error) {
+	timeout := defaultSecretTimeout
+	if c.Timeout > 0 {
+		timeout = time.Duration(c.Timeout) * time.Second
+	}
+
+	switch c.Type {
+	case VaultSecretProvider:
+		if c.Address == "" {
+			return nil, errors.New("vault secret provider requires an address")
+		}
+		return &VaultProvider{
+			Address: strings.TrimSuffix(c.Address, "/"),
+			Token:   c.Token,
+			Client:  &http.Client{Timeout: timeout},
+		}, nil
+	default:
+		return nil, errors.Errorf("Invalid secret provider type %s", c.Type)
+	}
+}
+
+// ResolveSecret resolves a secret which refers to one of the providers,
+// returning other secrets unchanged
+func ResolveSecret(s Secret, providers map[string]SecretProvider) (Secret, error) {
+	parts := strings.SplitN(s.Reveal(), ":", 2)
+	provider, ok := providers[parts[0]]
+	if len(parts) < 2 || !ok {
+		return s, nil
+	}
+
+	value, err := provider.Resolve(parts[1])
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve %s secret", parts[0])
+	}
+
+	return Secret(value), nil
+}
+
+// resolveSecrets resolves every secret of a configuration. The tokens of
+// configured providers may refer to the default SecretProviders
+func resolveSecrets(conf *Configuration) error {
+	providers := map[string]SecretProvider{}
+	for name, provider := range SecretProviders {
+		providers[name] = provider
+	}
+
+	for name, c := range conf.SecretProviders {
+		token, err := ResolveSecret(c.Token, SecretProviders)
+		if err != nil {
+			return errors.Wrapf(err, "invalid secret provider %s", name)
+		}
+		c.Token = token
+
+		if providers[name], err = NewSecretProvider(c); err != nil {
+			return errors.Wrapf(err, "invalid secret provider %s", name)
+		}
+	}
+
+	resolve := func(secrets ...*Secret) error {
+		for _, s := range secrets {
+			resolved, err := ResolveSecret(*s, providers)
+			if err != nil {
+				return err
+			}
+			*s = resolved
+		}
+		return nil
+	}
+	resolveAuth := func(a *auth) error {
+		return resolve(&a.AuthPassword, &a.PrivPassword, &a.AuthKey, &a.PrivKey)
+	}
+
+	for i := range conf.Poll {
+		cfg := &conf.Poll[i]
+		if err := resolve(&cfg.Community); err != nil {
+			return errors.Wrapf(err, "invalid community for host %s", cfg.Host)
+		}
+		if err := resolveAuth(&cfg.auth); err != nil {
+			return errors.Wrapf(err, "invalid v3 user for host %s", cfg.Host)
+		}
+	}
+
+	for name, module := range conf.Modules {
+		if err := resolve(&module.Community); err != nil {
+			return errors.Wrapf(err, "invalid community for module %s", name)
+		}
+		if err := resolveAuth(&module.auth); err != nil {
+			return errors.Wrapf(err, "invalid v3 user for module %s", name)
+		}
+		conf.Modules[name] = module
+	}
+
+	for i := range conf.Traps.Communities {
+		if err := resolve(&conf.Traps.Communities[i]); err != nil {
+			return errors.Wrap(err, "invalid trap community")
+		}
+	}
+	for i := range conf.Traps.Users {
+		if err := resolveAuth(&conf.Traps.Users[i]); err != nil {
+			return errors.Wrapf(err, "invalid trap user %s", conf.Traps.Users[i].Username)
+		}
+	}
+
+	for i := range conf.Relay.Destinations {
+		if err := resolve(&conf.Relay.Destinations[i].Community); err != nil {
+			return errors.Wrapf(err, "invalid community for relay destination %s", conf.Relay.Destinations[i].Name)
+		}
+	}
+
+	// Discovered configurations are written with the references the
+	// credentials they answered were resolved from
+	conf.Discovery.references = map[Secret]Secret{}
+	resolveDiscovery := func(secrets ...*Secret) error {
+		for _, s := range secrets {
+			ref := *s
+			if err := resolve(s); err != nil {
+				return err
+			}
+			if *s != ref {
+				conf.Discovery.references[*s] = ref
+			}
+		}
+		return nil
+	}
+
+	for i := range conf.Discovery.Communities {
+		if err := resolveDiscovery(&conf.Discovery.Communities[i]); err != nil {
+			return errors.Wrap(err, "invalid discovery community")
+		}
+	}
+	for i := range conf.Discovery.Users {
+		u := &conf.Discovery.Users[i]
+		if err := resolveDiscovery(&u.AuthPassword, &u.PrivPassword, &u.AuthKey, &u.PrivKey); err != nil {
+			return errors.Wrapf(err, "invalid discovery user %s", u.Username)
+		}
+	}
+
+	return nil
+}
+
+// EnvProvider resolves secrets from environment variables, such as
+// env:SNMP_COMMUNITY
+type EnvProvider struct{}
+
+// Resolve returns the value of an environment variable, which must be set
+func (EnvProvider) Resolve(ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", errors.Errorf("environment variable %s is not set", ref)
+	}
+
+	return value, nil
+}
+
+// FileProvider resolves secrets from files, such as
+// file:/run/secrets/core-rw
+type FileProvider struct{}
+
+// Resolve returns the contents of a file without its trailing newline
+func (FileProvider) Resolve(ref string) (string, error) {
+	b, err := ioutil.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// ExecProvider resolves secrets by running a command, such as
+// exec:/usr/local/bin/get-secret core1. The command and its arguments are
+// separated by whitespace and are not interpreted by a shell
+type ExecProvider struct {
+	Timeout time.Duration
+}
+
+// Resolve returns the output of a command without its trailing newline
+func (p ExecProvider) Resolve(ref string) (string, error) {
+	args := strings.Fields(ref)
+	if len(args) == 0 {
+		return "", errors.New("no command was provided")
+	}
+
+	ctx := context.Background()
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "command %s failed", args[0])
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// VaultProvider resolves secrets from the HTTP API of a Vault server. The
+// reference is the path of the secret and the name of the field within it,
+// such as vault:secret/data/snmp/core1#community. Both version 1 and
+// version 2 key/value secrets engines are supported
+type VaultProvider struct {
+	Address string
+	Token   Secret
+	Client  *http.Client
+}
+
+// Resolve reads a field of a secret from the server
+func (p *VaultProvider) Resolve(ref string) (string, error) {
+	parts := strings.SplitN(ref, "#", 2)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", errors.Errorf("reference %s must be a path and a field separated by #", ref)
+	}
+	path, field := strings.TrimPrefix(parts[0], "/"), parts[1]
+
+	req, err := http.NewRequest(http.MethodGet, p.Address+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	if p.Token != "" {
+		req.Header.Set("X-Vault-Token", p.Token.Reveal())
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("reading secret %s failed with status %s", path, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", errors.Wrapf(err, "invalid response reading secret %s", path)
+	}
+
+	// Version 2 engines nest the fields of the secret within its metadata
+	data := body.Data
+	var nested map[string]json.RawMessage
+	if err = json.Unmarshal(body.Data["data"], &nested); err == nil && nested != nil {
+		data = nested
+	}
+
+	raw, ok := data[field]
+	if !ok {
+		return "", errors.Errorf("secret %s has no field %s", path, field)
+	}
+
+	var value string
+	if err = json.Unmarshal(raw, &value); err != nil {
+		return "", errors.Errorf("field %s of secret %s is not a string", field, path)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,152 @@
+package libinquirer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+const testVaultToken = "s.test-token"
+
+func startTestVault(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != testVaultToken {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/snmp/core1":
+			fmt.Fprint(w, `{"data": {"data": {"community": "core-rw", "auth_password": "auth-passphrase"}, "metadata": {"version": 3}}}`)
+		case "/v1/kv/snmp":
+			fmt.Fprint(w, `{"data": {"community": "core-ro"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestResolveSecret(t *testing.T) {
+	os.Setenv("INQUIRER_TEST_COMMUNITY", "env-community")
+	defer os.Unsetenv("INQUIRER_TEST_COMMUNITY")
+
+	dir, _ := ioutil.TempDir("", "secrets")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "core-rw")
+	ioutil.WriteFile(path, []byte("file-community\n"), 0600)
+
+	tests := map[Secret]string{
+		testCommunity:                     testCommunity,
+		"env:INQUIRER_TEST_COMMUNITY":     "env-community",
+		Secret("file:" + path):            "file-community",
+		"exec:echo exec-community":        "exec-community",
+		"unknown:value":                   "unknown:value",
+		"http://example.com/not-a-scheme": "http://example.com/not-a-scheme",
+	}
+	for s, expected := range tests {
+		resolved, err := ResolveSecret(s, SecretProviders)
+		if err != nil || resolved.Reveal() != expected {
+			logrus.WithError(err).WithField("expected", expected).Errorln("Secret was resolved incorrectly")
+			t.Fail()
+		}
+	}
+
+	for _, s := range []Secret{"env:INQUIRER_TEST_UNSET", Secret("file:" + filepath.Join(dir, invalid)), "exec:false", "exec:"} {
+		if _, err := ResolveSecret(s, SecretProviders); err == nil {
+			logrus.WithField("secret", s.Reveal()).Errorln("Unresolvable secret was accepted")
+			t.Fail()
+		}
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := startTestVault(t)
+	defer server.Close()
+
+	provider, err := NewSecretProvider(SecretProviderConfiguration{Type: VaultSecretProvider, Address: server.URL + "/", Token: testVaultToken})
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to create vault provider")
+		t.FailNow()
+	}
+
+	tests := map[string]string{
+		"secret/data/snmp/core1#community": "core-rw",
+		"/kv/snmp#community":               "core-ro",
+	}
+	for ref, expected := range tests {
+		value, err := provider.Resolve(ref)
+		if err != nil || value != expected {
+			logrus.WithError(err).WithField("ref", ref).Errorln("Vault secret was resolved incorrectly")
+			t.Fail()
+		}
+	}
+
+	for _, ref := range []string{"secret/data/snmp/core1", "secret/data/snmp/core1#metadata", "secret/data/snmp/core2#community"} {
+		if _, err := provider.Resolve(ref); err == nil {
+			logrus.WithField("ref", ref).Errorln("Invalid vault reference was resolved")
+			t.Fail()
+		}
+	}
+
+	unauthorized, _ := NewSecretProvider(SecretProviderConfiguration{Type: VaultSecretProvider, Address: server.URL})
+	if _, err := unauthorized.Resolve("kv/snmp#community"); err == nil {
+		logrus.Errorln("Secret was read without a token")
+		t.Fail()
+	}
+
+	if _, err := NewSecretProvider(SecretProviderConfiguration{Type: invalid}); err == nil {
+		logrus.Errorln("Invalid secret provider type was accepted")
+		t.Fail()
+	}
+}
+
+func TestParseConfigFileSecrets(t *testing.T) {
+	server := startTestVault(t)
+	defer server.Close()
+
+	os.Setenv("INQUIRER_TEST_VAULT_TOKEN", testVaultToken)
+	defer os.Unsetenv("INQUIRER_TEST_VAULT_TOKEN")
+
+	dir, _ := ioutil.TempDir("", "secrets")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "inquirer.json")
+	ioutil.WriteFile(path, []byte(fmt.Sprintf(`{
+  "secret_providers": {
+    "vault": {"type": "vault", "address": %q, "token": "env:INQUIRER_TEST_VAULT_TOKEN"}
+  },
+  "poll": [{
+    "host": "127.0.0.1",
+    "version": "v3",
+    "community": "vault:secret/data/snmp/core1#community",
+    "username": "user",
+    "security_level": "AuthNoPriv",
+    "auth_protocol": "SHA",
+    "auth_password": "vault:secret/data/snmp/core1#auth_password",
+    "oids": {}
+  }],
+  "traps": {"communities": ["exec:echo trap-community"]}
+}`, server.URL)), 0600)
+
+	c, err := ParseConfigFile(path)
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to parse configuration file")
+		t.FailNow()
+	}
+
+	if c.Poll[0].Community.Reveal() != "core-rw" || c.Poll[0].AuthPassword.Reveal() != "auth-passphrase" || c.Traps.Communities[0].Reveal() != "trap-community" {
+		logrus.Errorln("Secrets were not resolved")
+		t.Fail()
+	}
+
+	os.Unsetenv("INQUIRER_TEST_VAULT_TOKEN")
+	if _, err = ParseConfigFile(path); err == nil {
+		logrus.Errorln("Configuration with unresolvable secrets was accepted")
+		t.Fail()
+	}
+}
@@ -0,0 +1,293 @@
+package libinquirer
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/pkg/errors"
+	"github.com/soniah/gosnmp"
+)
+
+// InferType is the type of a varbind whose type is taken from the MIB
+// definition of its object
+const InferType = "="
+
+// varbindTypes maps the type names accepted for varbinds, which include the
+// single letter types of net-snmp's snmpset, to their ASN.1 types
+var varbindTypes = map[string]gosnmp.Asn1BER{
+	"i":         gosnmp.Integer,
+	"integer":   gosnmp.Integer,
+	"u":         gosnmp.Gauge32,
+	"unsigned":  gosnmp.Gauge32,
+	"gauge32":   gosnmp.Gauge32,
+	"c":         gosnmp.Counter32,
+	"counter32": gosnmp.Counter32,
+	"C":         gosnmp.Counter64,
+	"counter64": gosnmp.Counter64,
+	"t":         gosnmp.TimeTicks,
+	"timeticks": gosnmp.TimeTicks,
+	"a":         gosnmp.IPAddress,
+	"ipaddress": gosnmp.IPAddress,
+	"o":         gosnmp.ObjectIdentifier,
+	"oid":       gosnmp.ObjectIdentifier,
+	"s":         gosnmp.OctetString,
+	"string":    gosnmp.OctetString,
+	"x":         gosnmp.OctetString,
+	"hex":       gosnmp.OctetString,
+}
+
+// baseVarbindTypes maps the SMI base types of objects to the type of their
+// varbinds, used when the type is inferred
+var baseVarbindTypes = map[string]string{
+	"INTEGER":           "integer",
+	"Integer32":         "integer",
+	"Unsigned32":        "unsigned",
+	"Gauge32":           "gauge32",
+	"Counter32":         "counter32",
+	"Counter64":         "counter64",
+	"TimeTicks":         "timeticks",
+	"IpAddress":         "ipaddress",
+	"OBJECT IDENTIFIER": "oid",
+	"OCTET STRING":      "string",
+}
+
+// settableTypes are the types gosnmp allows as the first varbind of a set
+var settableTypes = map[gosnmp.Asn1BER]bool{
+	gosnmp.Integer:     true,
+	gosnmp.OctetString: true,
+	gosnmp.Gauge32:     true,
+	gosnmp.IPAddress:   true,
+}
+
+// SetResult is the outcome of setting varbinds on a single host
+type SetResult struct {
+	Host string
+	// DryRun is true when the varbinds were not set
+	DryRun bool
+	// Requested are the varbinds which were, or would have been, set
+	Requested []gosnmp.SnmpPDU
+	// Current are the values of the varbinds read before a dry run
+	Current []gosnmp.SnmpPDU
+	// Variables are the varbinds of the response to the request
+	Variables []gosnmp.SnmpPDU
+	// ErrorStatus and ErrorIndex are the error-status and error-index of the
+	// response. The index of the first varbind is 1
+	ErrorStatus gosnmp.SNMPError
+	ErrorIndex  uint8
+	Err         error
+}
+
+// ParseVarbind builds a varbind from an OID or name, a type and a value, in
+// the same way as the arguments of snmpset. The type = uses the MIB
+// definition of the object, and integers may be given as enumeration labels,
+// such as down for IF-MIB::ifAdminStatus
+func ParseVarbind(m *mib.MIB, name, kind, value string) (gosnmp.SnmpPDU, error) {
+	oid, err := m.Resolve(name)
+	if err != nil {
+		return gosnmp.SnmpPDU{}, err
+	}
+
+	var t *mib.Type
+	if node, _ := m.Lookup(oid); node != nil {
+		t = m.Type(node)
+	}
+
+	if kind == InferType {
+		if t == nil || baseVarbindTypes[t.Base] == "" {
+			return gosnmp.SnmpPDU{}, errors.Errorf("the type of %s cannot be inferred", name)
+		}
+		kind = baseVarbindTypes[t.Base]
+	}
+
+	asn1, ok := varbindTypes[kind]
+	if !ok {
+		asn1, ok = varbindTypes[strings.ToLower(kind)]
+	}
+	if !ok {
+		return gosnmp.SnmpPDU{}, errors.Errorf("invalid type %s for %s", kind, name)
+	}
+
+	pdu := gosnmp.SnmpPDU{Name: oid, Type: asn1}
+	switch asn1 {
+	case gosnmp.Integer:
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil && t != nil {
+			for number, label := range t.Enums {
+				if label == value {
+					n, err = number, nil
+				}
+			}
+		}
+		if err != nil {
+			return pdu, errors.Errorf("invalid integer %s for %s", value, name)
+		}
+		pdu.Value = int(n)
+	case gosnmp.Gauge32, gosnmp.Counter32, gosnmp.TimeTicks:
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return pdu, errors.Errorf("invalid unsigned integer %s for %s", value, name)
+		}
+		pdu.Value = uint32(n)
+	case gosnmp.Counter64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return pdu, errors.Errorf("invalid unsigned integer %s for %s", value, name)
+		}
+		pdu.Value = n
+	case gosnmp.IPAddress:
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() == nil {
+			return pdu, errors.Errorf("invalid IPv4 address %s for %s", value, name)
+		}
+		pdu.Value = ip.To4().String()
+	case gosnmp.ObjectIdentifier:
+		value, err := m.Resolve(value)
+		if err != nil {
+			return pdu, errors.Wrapf(err, "invalid OID value for %s", name)
+		}
+		pdu.Value = value
+	case gosnmp.OctetString:
+		if kind == "x" || kind == "hex" {
+			b, err := hex.DecodeString(strings.NewReplacer(" ", "", ":", "").Replace(value))
+			if err != nil {
+				return pdu, errors.Errorf("invalid hex string %s for %s", value, name)
+			}
+			pdu.Value = b
+		} else {
+			pdu.Value = []byte(value)
+		}
+	}
+
+	return pdu, nil
+}
+
+// ParseVarbinds builds varbinds from arguments in groups of OID, type and
+// value
+func ParseVarbinds(m *mib.MIB, args []string) ([]gosnmp.SnmpPDU, error) {
+	if len(args) == 0 || len(args)%3 != 0 {
+		return nil, errors.Errorf("varbinds must be provided as OID TYPE VALUE")
+	}
+
+	pdus := []gosnmp.SnmpPDU{}
+	for i := 0; i < len(args); i += 3 {
+		pdu, err := ParseVarbind(m, args[i], args[i+1], args[i+2])
+		if err != nil {
+			return nil, err
+		}
+		pdus = append(pdus, pdu)
+	}
+
+	return pdus, nil
+}
+
+// ParseBatch builds varbinds from a batch file, which has a varbind per line
+// as an OID, a type and a value separated by whitespace. The value is the
+// rest of the line, so may contain spaces. Blank lines and lines starting
+// with # are ignored
+func ParseBatch(m *mib.MIB, r io.Reader) ([]gosnmp.SnmpPDU, error) {
+	pdus := []gosnmp.SnmpPDU{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 3 {
+			return nil, errors.Errorf("line %d must contain an OID, type and value", line)
+		}
+		rest := strings.TrimSpace(text[len(fields[0]):])
+		value := strings.TrimSpace(rest[len(fields[1]):])
+
+		pdu, err := ParseVarbind(m, fields[0], fields[1], value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+		pdus = append(pdus, pdu)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(pdus) == 0 {
+		return nil, errors.Errorf("batch contains no varbinds")
+	}
+
+	return pdus, nil
+}
+
+// Set sends varbinds to a host in a single request. A dry run reads the
+// current values of the varbinds instead of setting them
+func Set(client *gosnmp.GoSNMP, pdus []gosnmp.SnmpPDU, dryRun bool) *SetResult {
+	result := &SetResult{Host: client.Target, DryRun: dryRun, Requested: pdus}
+	if len(pdus) == 0 {
+		result.Err = errors.New("no varbinds to set")
+		return result
+	}
+
+	// gosnmp only sends sets whose first varbind has one of a few types, so
+	// a varbind of one of those types is moved to the front. The varbinds
+	// and error-index of the response are returned in the order requested
+	swap := 0
+	for i, pdu := range pdus {
+		if settableTypes[pdu.Type] {
+			swap = i
+			break
+		}
+	}
+	reorder := func(vars []gosnmp.SnmpPDU) []gosnmp.SnmpPDU {
+		reordered := append([]gosnmp.SnmpPDU{}, vars...)
+		if swap < len(reordered) {
+			reordered[0], reordered[swap] = reordered[swap], reordered[0]
+		}
+		return reordered
+	}
+
+	var packet *gosnmp.SnmpPacket
+	var err error
+	if dryRun {
+		oids := make([]string, 0, len(pdus))
+		for _, pdu := range pdus {
+			oids = append(oids, pdu.Name)
+		}
+		packet, err = client.Get(oids)
+	} else if !settableTypes[pdus[swap].Type] {
+		err = errors.New("at least one varbind must be an Integer, OctetString, Gauge32 or IpAddress")
+	} else {
+		packet, err = client.Set(reorder(pdus))
+		if err == nil {
+			packet.Variables = reorder(packet.Variables)
+			switch int(packet.ErrorIndex) {
+			case 1:
+				packet.ErrorIndex = uint8(swap + 1)
+			case swap + 1:
+				packet.ErrorIndex = 1
+			}
+		}
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Variables = packet.Variables
+	result.ErrorStatus, result.ErrorIndex = packet.Error, packet.ErrorIndex
+	if dryRun {
+		result.Current = packet.Variables
+	}
+
+	if packet.Error != gosnmp.NoError {
+		result.Err = errors.Errorf("request failed with error status %s at index %d", packet.Error, packet.ErrorIndex)
+		if i := int(packet.ErrorIndex) - 1; i >= 0 && i < len(pdus) {
+			result.Err = errors.Wrapf(result.Err, "failed to set %s", pdus[i].Name)
+		}
+	}
+
+	return result
+}
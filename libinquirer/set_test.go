@@ -0,0 +1,144 @@
+package libinquirer
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+func TestParseVarbind(t *testing.T) {
+	m := testMIB(t)
+	tests := []struct {
+		name, kind, value string
+		expected          gosnmp.SnmpPDU
+	}{
+		{"IF-MIB::ifAdminStatus.3", "=", "down", gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.7.3", Type: gosnmp.Integer, Value: 2}},
+		{"ifAdminStatus.3", "i", "1", gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.7.3", Type: gosnmp.Integer, Value: 1}},
+		{"sysLocation.0", "=", "Rack 4", gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.1.6.0", Type: gosnmp.OctetString, Value: []byte("Rack 4")}},
+		{".1.3.6.1.2.1.1.4.0", "s", "noc@example.com", gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.1.4.0", Type: gosnmp.OctetString, Value: []byte("noc@example.com")}},
+		{".1.3.6.1.4.1.99999.1.0", "x", "00:1a:2B", gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.1.0", Type: gosnmp.OctetString, Value: []byte{0x00, 0x1a, 0x2b}}},
+		{".1.3.6.1.4.1.99999.2.0", "a", "192.0.2.1", gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.2.0", Type: gosnmp.IPAddress, Value: "192.0.2.1"}},
+		{".1.3.6.1.4.1.99999.3.0", "o", "IF-MIB::ifName", gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.3.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.2.1.31.1.1.1.1"}},
+		{".1.3.6.1.4.1.99999.4.0", "u", "42", gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.4.0", Type: gosnmp.Gauge32, Value: uint32(42)}},
+		{".1.3.6.1.4.1.99999.5.0", "timeticks", "100", gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.5.0", Type: gosnmp.TimeTicks, Value: uint32(100)}},
+		{".1.3.6.1.4.1.99999.6.0", "C", "5000000000", gosnmp.SnmpPDU{Name: ".1.3.6.1.4.1.99999.6.0", Type: gosnmp.Counter64, Value: uint64(5000000000)}},
+	}
+
+	for _, test := range tests {
+		pdu, err := ParseVarbind(m, test.name, test.kind, test.value)
+		if err != nil || !reflect.DeepEqual(pdu, test.expected) {
+			logrus.WithError(err).WithFields(logrus.Fields{"name": test.name, "pdu": pdu}).Errorln("Varbind was parsed incorrectly")
+			t.Fail()
+		}
+	}
+
+	invalidTests := [][]string{
+		{"ifAdminStatus.3", "i", "sideways"},
+		{"ifAdminStatus.3", invalid, "1"},
+		{".1.3.6.1.4.1.99999.1.0", "=", "1"},
+		{".1.3.6.1.4.1.99999.1.0", "a", "2001:db8::1"},
+		{".1.3.6.1.4.1.99999.1.0", "x", "zz"},
+		{".1.3.6.1.4.1.99999.1.0", "u", "-1"},
+		{invalid, "s", "value"},
+	}
+	for _, args := range invalidTests {
+		if _, err := ParseVarbind(m, args[0], args[1], args[2]); err == nil {
+			logrus.WithField("args", args).Errorln("Invalid varbind was accepted")
+			t.Fail()
+		}
+	}
+
+	if _, err := ParseVarbinds(m, []string{"sysLocation.0", "s"}); err == nil {
+		logrus.Errorln("Incomplete varbind arguments were accepted")
+		t.Fail()
+	}
+}
+
+func TestParseBatch(t *testing.T) {
+	batch := `# Relocated switches
+sysLocation.0 s Building 2, Rack 4
+
+IF-MIB::ifAdminStatus.3 = down
+`
+	pdus, err := ParseBatch(testMIB(t), strings.NewReader(batch))
+	if err != nil || len(pdus) != 2 {
+		logrus.WithError(err).Errorln("Failed to parse batch")
+		t.FailNow()
+	}
+
+	if !bytes.Equal(pdus[0].Value.([]byte), []byte("Building 2, Rack 4")) || pdus[1].Value != 2 {
+		logrus.WithField("pdus", pdus).Errorln("Batch was parsed incorrectly")
+		t.Fail()
+	}
+
+	for _, invalidBatch := range []string{"sysLocation.0 s\n", "# empty\n", "sysLocation.0 s one\nifAdminStatus.3 i sideways\n"} {
+		if _, err := ParseBatch(testMIB(t), strings.NewReader(invalidBatch)); err == nil {
+			logrus.WithField("batch", invalidBatch).Errorln("Invalid batch was accepted")
+			t.Fail()
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	defer agent.Close()
+
+	client := testQueryClient(t, agent, v2)
+	defer client.Conn.Close()
+
+	pdus := []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.OctetString, Value: []byte("renamed")}}
+
+	// A dry run reads the current value without setting it
+	result := Set(client, pdus, true)
+	if result.Err != nil || !result.DryRun || len(result.Current) != 1 || string(result.Current[0].Value.([]byte)) != "test-agent" {
+		logrus.WithError(result.Err).WithField("result", result).Errorln("Dry run did not read the current value")
+		t.Fail()
+	}
+
+	result = Set(client, pdus, false)
+	if result.Err != nil || result.ErrorStatus != gosnmp.NoError {
+		logrus.WithError(result.Err).Errorln("Failed to set value")
+		t.FailNow()
+	}
+
+	walks, _ := Get(client, []string{".1.3.6.1.2.1.1.5.0"}, nil)
+	if len(walks) != 1 || string(walks[0].PDUs[0].Value.([]byte)) != "renamed" {
+		logrus.WithField("walks", walks).Errorln("Value was not set")
+		t.Fail()
+	}
+}
+
+func TestSetErrorStatus(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	defer agent.Close()
+	agent.setReadOnly(".1.3.6.1.4.1.99999.3.0")
+
+	client := testQueryClient(t, agent, v2)
+	defer client.Conn.Close()
+
+	// The OID varbind is sent second, as gosnmp does not allow sets to start
+	// with one, but its error is reported at its requested index
+	pdus := []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.4.1.99999.3.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.2.1.31.1.1.1.1"},
+		{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.OctetString, Value: []byte("renamed")},
+	}
+	result := Set(client, pdus, false)
+	if result.Err == nil || result.ErrorStatus != gosnmp.NotWritable || result.ErrorIndex != 1 {
+		logrus.WithError(result.Err).WithField("result", result).Errorln("Error status was not reported")
+		t.Fail()
+	}
+	if len(result.Variables) != 2 || result.Variables[0].Name != pdus[0].Name {
+		logrus.WithField("variables", result.Variables).Errorln("Response varbinds were not in the order requested")
+		t.Fail()
+	}
+
+	only := []gosnmp.SnmpPDU{{Name: ".1.3.6.1.4.1.99999.5.0", Type: gosnmp.TimeTicks, Value: uint32(100)}}
+	if result := Set(client, only, false); result.Err == nil {
+		logrus.Errorln("Set without a supported first varbind was sent")
+		t.Fail()
+	}
+}
@@ -0,0 +1,126 @@
+package libinquirer
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// LogrusOutput is the output type which logs records using logrus
+	LogrusOutput = "logrus"
+)
+
+// Sink receives the records retrieved from hosts. Records are written in
+// batches, usually one batch per host polled. Table rows are written
+// separately, one batch of rows per host, as are traps received from agents
+type Sink interface {
+	Write(records []Record) error
+	WriteRows(rows []Row) error
+	WriteTraps(traps []Trap) error
+	Close() error
+}
+
+// OutputConfiguration represents a single output the results of polling are
+// written to
+type OutputConfiguration struct {
+	Type string `json:"type"`
+	// Target is the destination of the output, such as a file path or URL,
+	// when the output type supports one
+	Target  string            `json:"target"`
+	Options map[string]string `json:"options"`
+}
+
+// ParseOutput parses an output flag in the form type or type=target
+func ParseOutput(s string) OutputConfiguration {
+	parts := strings.SplitN(s, "=", 2)
+	o := OutputConfiguration{Type: parts[0]}
+	if len(parts) > 1 {
+		o.Target = parts[1]
+	}
+
+	return o
+}
+
+// NewSink creates the sink for an output configuration
+func NewSink(o OutputConfiguration) (Sink, error) {
+	switch o.Type {
+	case LogrusOutput, "":
+		return NewLogrusSink(), nil
+	case InfluxOutput:
+		return NewInfluxSink(o)
+	case GraphiteOutput:
+		return NewGraphiteSink(o)
+	case JSONOutput:
+		return NewJSONSink(o)
+	default:
+		return nil, errors.Errorf("Invalid output type %s", o.Type)
+	}
+}
+
+// MultiSink writes records to every sink it contains
+type MultiSink []Sink
+
+// NewMultiSink creates a sink for each output configuration provided
+func NewMultiSink(outputs []OutputConfiguration) (MultiSink, error) {
+	sinks := MultiSink{}
+	for _, o := range outputs {
+		sink, err := NewSink(o)
+		if err != nil {
+			sinks.Close()
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return sinks, nil
+}
+
+// Write writes records to every sink, returning the first error encountered
+func (m MultiSink) Write(records []Record) error {
+	var first error
+	for _, sink := range m {
+		if err := sink.Write(records); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// WriteRows writes rows to every sink, returning the first error encountered
+func (m MultiSink) WriteRows(rows []Row) error {
+	var first error
+	for _, sink := range m {
+		if err := sink.WriteRows(rows); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// WriteTraps writes traps to every sink, returning the first error
+// encountered
+func (m MultiSink) WriteTraps(traps []Trap) error {
+	var first error
+	for _, sink := range m {
+		if err := sink.WriteTraps(traps); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// Close closes every sink, returning the first error encountered
+func (m MultiSink) Close() error {
+	var first error
+	for _, sink := range m {
+		if err := sink.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
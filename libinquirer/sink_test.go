@@ -0,0 +1,86 @@
+package libinquirer
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type testSink struct {
+	records []Record
+	rows    []Row
+	traps   []Trap
+	err     error
+	closed  bool
+}
+
+func (s *testSink) Write(records []Record) error {
+	s.records = append(s.records, records...)
+	return s.err
+}
+
+func (s *testSink) WriteRows(rows []Row) error {
+	s.rows = append(s.rows, rows...)
+	return s.err
+}
+
+func (s *testSink) WriteTraps(traps []Trap) error {
+	s.traps = append(s.traps, traps...)
+	return s.err
+}
+
+func (s *testSink) Close() error {
+	s.closed = true
+	return s.err
+}
+
+func TestParseOutput(t *testing.T) {
+	o := ParseOutput("influx=http://localhost:8086/write?db=snmp")
+	if o.Type != "influx" || o.Target != "http://localhost:8086/write?db=snmp" {
+		logrus.WithField("output", o).Errorln("Incorrectly parsed output")
+		t.Fail()
+	}
+
+	o = ParseOutput(LogrusOutput)
+	if o.Type != LogrusOutput || o.Target != "" {
+		logrus.WithField("output", o).Errorln("Incorrectly parsed output")
+		t.Fail()
+	}
+}
+
+func TestNewSinkLogrus(t *testing.T) {
+	if _, err := NewSink(OutputConfiguration{Type: LogrusOutput}); err != nil {
+		logrus.WithError(err).Errorln("Failed to create logrus sink")
+		t.Fail()
+	}
+}
+
+func TestNewSinkInvalid(t *testing.T) {
+	if _, err := NewSink(OutputConfiguration{Type: invalid}); err == nil {
+		logrus.Errorln("Created sink for an invalid output type")
+		t.Fail()
+	}
+}
+
+func TestMultiSink(t *testing.T) {
+	failing := &testSink{err: errors.New("failed")}
+	working := &testSink{}
+	m := MultiSink{failing, working}
+
+	if err := m.Write([]Record{{Host: localhost}}); err == nil {
+		logrus.Errorln("Sink error was not returned")
+		t.Fail()
+	}
+
+	if len(working.records) != 1 {
+		logrus.Errorln("Records were not written to every sink")
+		t.Fail()
+	}
+
+	m.Close()
+	if !failing.closed || !working.closed {
+		logrus.Errorln("Sinks were not closed")
+		t.Fail()
+	}
+}
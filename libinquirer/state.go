@@ -0,0 +1,161 @@
+package libinquirer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	stateFileName = "inquirer2.db"
+	samplesBucket = "samples"
+
+	defaultStateLockTimeout = time.Duration(10) * time.Second
+	defaultStateMaxAge      = time.Duration(24) * time.Hour
+)
+
+// StateStore is an on-disk store of counter samples, allowing rates to be
+// calculated across separate runs of the poll command. The store is a single
+// embedded database file which is locked while open so overlapping runs can
+// not corrupt it
+type StateStore struct {
+	Path        string
+	LockTimeout time.Duration
+	// MaxAge is how long a sample is kept without being updated, so that
+	// samples of hosts and instances which are no longer polled expire
+	MaxAge time.Duration
+}
+
+// NewStateStore creates a state store within the directory provided,
+// creating the directory if it does not exist
+func NewStateStore(dir string) (*StateStore, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		logrus.WithError(err).Debugln("Could not create state directory")
+		return nil, err
+	}
+
+	return &StateStore{
+		Path:        filepath.Join(dir, stateFileName),
+		LockTimeout: defaultStateLockTimeout,
+		MaxAge:      defaultStateMaxAge,
+	}, nil
+}
+
+func (s *StateStore) open() (*bolt.DB, error) {
+	db, err := bolt.Open(s.Path, 0600, &bolt.Options{Timeout: s.LockTimeout})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open state store %s", s.Path)
+	}
+
+	return db, nil
+}
+
+// LoadSamples reads every counter sample held in the store
+func (s *StateStore) LoadSamples() (map[string]Sample, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	samples := map[string]Sample{}
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(samplesBucket))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var sample Sample
+			if err := json.Unmarshal(v, &sample); err != nil {
+				logrus.WithError(err).WithField("key", string(k)).Debugln("Skipping unreadable sample")
+				return nil
+			}
+			samples[string(k)] = sample
+			return nil
+		})
+	})
+
+	return samples, err
+}
+
+// SaveSamples writes counter samples to the store. Samples already held in
+// the store which are newer than those provided are kept, so a slower run
+// finishing last does not replace the results of a faster one. Samples older
+// than MaxAge are removed
+func (s *StateStore) SaveSamples(samples map[string]Sample) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	maxAge := s.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultStateMaxAge
+	}
+	expired := time.Now().Add(-maxAge)
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(samplesBucket))
+		if err != nil {
+			return err
+		}
+
+		for k, sample := range samples {
+			if sample.Time.Before(expired) {
+				continue
+			}
+
+			if existing := b.Get([]byte(k)); existing != nil {
+				var stored Sample
+				if json.Unmarshal(existing, &stored) == nil && stored.Time.After(sample.Time) {
+					continue
+				}
+			}
+
+			v, err := json.Marshal(sample)
+			if err != nil {
+				return err
+			}
+
+			if err = b.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+
+		return pruneSamples(b, expired)
+	})
+}
+
+// pruneSamples removes samples taken before the time provided, along with
+// any which can not be read
+func pruneSamples(b *bolt.Bucket, expired time.Time) error {
+	keys := [][]byte{}
+	err := b.ForEach(func(k, v []byte) error {
+		var sample Sample
+		if err := json.Unmarshal(v, &sample); err != nil || sample.Time.Before(expired) {
+			keys = append(keys, append([]byte{}, k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err = b.Delete(k); err != nil {
+			return err
+		}
+	}
+	if len(keys) > 0 {
+		logrus.WithField("samples", len(keys)).Debugln("Removed expired samples from state store")
+	}
+
+	return nil
+}
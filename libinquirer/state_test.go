@@ -0,0 +1,117 @@
+package libinquirer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+func TestStateStoreRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inquirer2")
+	if err != nil {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewStateStore(dir)
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to create state store")
+		t.FailNow()
+	}
+
+	samples, err := s.LoadSamples()
+	if err != nil || len(samples) != 0 {
+		logrus.WithError(err).Errorln("Empty state store returned samples")
+		t.Fail()
+	}
+
+	key := SampleKey(localhost, ".1.3.6.1.2.1.31.1.1.1.6", "1")
+	now := time.Now().Round(0)
+	err = s.SaveSamples(map[string]Sample{
+		key: {Value: 42, Type: gosnmp.Counter64, Uptime: 100, Time: now},
+	})
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to save samples")
+		t.FailNow()
+	}
+
+	samples, err = s.LoadSamples()
+	if err != nil || samples[key].Value != 42 || !samples[key].Time.Equal(now) {
+		logrus.WithError(err).WithField("samples", samples).Errorln("Saved samples were not loaded")
+		t.Fail()
+	}
+}
+
+func TestStateStoreKeepsNewerSamples(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inquirer2")
+	if err != nil {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	s, _ := NewStateStore(dir)
+	key := SampleKey(localhost, ".1.3.6.1.2.1.31.1.1.1.6", "1")
+	now := time.Now()
+	s.SaveSamples(map[string]Sample{key: {Value: 2, Type: gosnmp.Counter64, Time: now}})
+	s.SaveSamples(map[string]Sample{key: {Value: 1, Type: gosnmp.Counter64, Time: now.Add(-time.Minute)}})
+
+	samples, _ := s.LoadSamples()
+	if samples[key].Value != 2 {
+		logrus.WithField("samples", samples).Errorln("Older sample replaced a newer one")
+		t.Fail()
+	}
+}
+
+func TestStateStoreExpiresSamples(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inquirer2")
+	if err != nil {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	s, _ := NewStateStore(dir)
+	s.MaxAge = time.Hour
+	stale := SampleKey("192.0.2.1", ".1.3.6.1.2.1.31.1.1.1.6", "1")
+	current := SampleKey(localhost, ".1.3.6.1.2.1.31.1.1.1.6", "1")
+	now := time.Now()
+	s.SaveSamples(map[string]Sample{stale: {Value: 1, Type: gosnmp.Counter64, Time: now.Add(-30 * time.Minute)}})
+
+	// The stale sample is no longer provided, as its host is not polled
+	s.MaxAge = 10 * time.Minute
+	s.SaveSamples(map[string]Sample{
+		current: {Value: 2, Type: gosnmp.Counter64, Time: now},
+		"old":   {Value: 3, Type: gosnmp.Counter64, Time: now.Add(-2 * time.Hour)},
+	})
+
+	samples, _ := s.LoadSamples()
+	if len(samples) != 1 || samples[current].Value != 2 {
+		logrus.WithField("samples", samples).Errorln("Expired samples were kept")
+		t.Fail()
+	}
+}
+
+func TestStateStoreLocked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inquirer2")
+	if err != nil {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	s, _ := NewStateStore(dir)
+	db, err := s.open()
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to open state store")
+		t.FailNow()
+	}
+	defer db.Close()
+
+	s.LockTimeout = 100 * time.Millisecond
+	if _, err = s.LoadSamples(); err == nil {
+		logrus.Errorln("State store was opened while locked by another user")
+		t.Fail()
+	}
+}
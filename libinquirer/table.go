@@ -0,0 +1,136 @@
+package libinquirer
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/soniah/gosnmp"
+)
+
+// Cell is the value of a single column of a table row
+type Cell struct {
+	Name    string
+	OID     string
+	PDUType gosnmp.Asn1BER
+	Value   interface{}
+	Display string
+	Rate    *Rate
+}
+
+// Readable returns the value of the cell for text outputs
+func (c Cell) Readable() interface{} {
+	return ReadableValue(c.PDUType, c.Value)
+}
+
+// Row is a single row of a table, holding every column retrieved for its
+// index in the order the columns were configured
+type Row struct {
+	Host  string
+	Table string
+	Index string
+	// IndexValues are the components of the index decoded using the INDEX
+	// clause of the table, nil when the table is not known
+	IndexValues []mib.IndexValue
+	Cells       []Cell
+	Timestamp   time.Time
+}
+
+// Rows joins the columns of every table retrieved from a host into one row
+// per index. When a rate tracker is provided, the rate of each counter is
+// included. When a MIB is provided, values are also rendered using the
+// definition of their column
+func Rows(result *HostResult, rates *RateTracker, m *mib.MIB) []Row {
+	host := result.Config.Host
+	rows := []Row{}
+	for _, table := range result.Tables {
+		byIndex := map[string]*Row{}
+		indexes := []string{}
+
+		for _, walk := range table.Walks {
+			if walk.Err != nil {
+				continue
+			}
+
+			for _, pdu := range walk.PDUs {
+				if !strings.HasPrefix(pdu.Name, walk.OID+".") {
+					continue
+				}
+				index := pdu.Name[len(walk.OID)+1:]
+
+				row, ok := byIndex[index]
+				if !ok {
+					row = &Row{
+						Host:      host,
+						Table:     table.Name,
+						Index:     index,
+						Timestamp: walk.Time,
+					}
+					if _, values, ok := TableIndex(m, pdu.Name); ok {
+						row.IndexValues = values
+					}
+					byIndex[index] = row
+					indexes = append(indexes, index)
+				}
+
+				cell := Cell{
+					Name:    walk.Name,
+					OID:     walk.OID,
+					PDUType: pdu.Type,
+					Value:   DecodeValue(pdu),
+					Display: DisplayValue(m, pdu),
+				}
+				if rates != nil {
+					if sample, ok := NewSample(pdu, result.Uptime, walk.Time); ok {
+						cell.Rate = rates.Observe(SampleKey(host, walk.OID, index), sample)
+					}
+				}
+				row.Cells = append(row.Cells, cell)
+			}
+		}
+
+		sort.Slice(indexes, func(i, j int) bool {
+			return compareIndexes(indexes[i], indexes[j]) < 0
+		})
+		for _, index := range indexes {
+			rows = append(rows, *byIndex[index])
+		}
+	}
+
+	return rows
+}
+
+// Cell returns the cell of a column by name
+func (r Row) Cell(name string) (Cell, bool) {
+	for _, c := range r.Cells {
+		if c.Name == name {
+			return c, true
+		}
+	}
+
+	return Cell{}, false
+}
+
+// compareIndexes orders indexes arc by arc numerically, so that 2 sorts
+// before 10
+func compareIndexes(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, errX := strconv.ParseUint(as[i], 10, 64)
+		y, errY := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case errX != nil || errY != nil:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+
+	return len(as) - len(bs)
+}
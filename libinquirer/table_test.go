@@ -0,0 +1,228 @@
+package libinquirer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+func testMIB(t *testing.T) *mib.MIB {
+	cwd, _ := os.Getwd()
+	m := mib.New()
+	if err := m.LoadDirs(fmt.Sprintf("%s/fixtures/mibs", path.Dir(cwd))); err != nil {
+		logrus.WithError(err).Errorln("Failed to load test MIBs")
+		t.FailNow()
+	}
+
+	return m
+}
+
+func TestResolveTable(t *testing.T) {
+	table := TableConfiguration{
+		Root:    "IF-MIB::ifXTable",
+		Columns: []string{"ifName", "6", "IF-MIB::ifAlias", ".1.3.6.1.2.1.2.2.1.8"},
+	}
+	if err := ResolveTable(&table, testMIB(t)); err != nil {
+		logrus.WithError(err).Errorln("Failed to resolve table")
+		t.FailNow()
+	}
+
+	expected := []TableColumn{
+		{Name: "ifName", OID: ".1.3.6.1.2.1.31.1.1.1.1"},
+		{Name: "ifHCInOctets", OID: ".1.3.6.1.2.1.31.1.1.1.6"},
+		{Name: "ifAlias", OID: ".1.3.6.1.2.1.31.1.1.1.18"},
+		{Name: "ifOperStatus", OID: ".1.3.6.1.2.1.2.2.1.8"},
+	}
+
+	if table.Name != "ifXTable" || table.Root != ".1.3.6.1.2.1.31.1.1" || len(table.Resolved) != len(expected) {
+		logrus.WithField("table", table).Errorln("Resolved table is invalid")
+		t.FailNow()
+	}
+
+	for i, column := range expected {
+		if table.Resolved[i] != column {
+			logrus.WithFields(logrus.Fields{"column": table.Resolved[i], "expected": column}).Errorln("Resolved column is invalid")
+			t.Fail()
+		}
+	}
+}
+
+func TestResolveTableEntryRoot(t *testing.T) {
+	table := TableConfiguration{Name: "interfaces", Root: "IF-MIB::ifEntry", Columns: []string{"2"}}
+	if err := ResolveTable(&table, testMIB(t)); err != nil {
+		logrus.WithError(err).Errorln("Failed to resolve table")
+		t.FailNow()
+	}
+
+	if table.Name != "interfaces" || table.Resolved[0].Name != "ifDescr" {
+		logrus.WithField("table", table).Errorln("Columns of an entry root resolved incorrectly")
+		t.Fail()
+	}
+}
+
+func TestResolveTableInvalid(t *testing.T) {
+	tables := []TableConfiguration{
+		{Root: "IF-MIB::ifXTable"},
+		{Root: "IF-MIB::ifUnknownTable", Columns: []string{"1"}},
+		{Root: "IF-MIB::ifXTable", Columns: []string{"ifUnknownColumn"}},
+	}
+
+	for _, table := range tables {
+		if err := ResolveTable(&table, testMIB(t)); err == nil {
+			logrus.WithField("table", table).Errorln("Resolved invalid table")
+			t.Fail()
+		}
+	}
+}
+
+func TestRows(t *testing.T) {
+	now := time.Now()
+	result := &HostResult{
+		Config: PollConfiguration{Host: localhost},
+		Tables: []TableResult{{
+			Name: "ifXTable",
+			Walks: []WalkResult{
+				{
+					OID:  ".1.3.6.1.2.1.31.1.1.1.1",
+					Name: "ifName",
+					Time: now,
+					PDUs: []gosnmp.SnmpPDU{
+						{Name: ".1.3.6.1.2.1.31.1.1.1.1.10", Type: gosnmp.OctetString, Value: []byte("ge-0/0/9")},
+						{Name: ".1.3.6.1.2.1.31.1.1.1.1.2", Type: gosnmp.OctetString, Value: []byte("ge-0/0/1")},
+					},
+				},
+				{
+					OID:  ".1.3.6.1.2.1.31.1.1.1.6",
+					Name: "ifHCInOctets",
+					Time: now,
+					PDUs: []gosnmp.SnmpPDU{
+						{Name: ".1.3.6.1.2.1.31.1.1.1.6.2", Type: gosnmp.Counter64, Value: uint64(100)},
+						{Name: ".1.3.6.1.2.1.31.1.1.1.6.3", Type: gosnmp.Counter64, Value: uint64(300)},
+					},
+				},
+			},
+		}},
+	}
+
+	rows := Rows(result, nil, nil)
+	if len(rows) != 3 {
+		logrus.WithField("rows", rows).Errorln("Incorrect number of rows joined")
+		t.FailNow()
+	}
+
+	for i, index := range []string{"2", "3", "10"} {
+		if rows[i].Index != index || rows[i].Table != "ifXTable" || rows[i].Host != localhost {
+			logrus.WithField("row", rows[i]).Errorln("Rows are not ordered by index")
+			t.Fail()
+		}
+	}
+
+	name, ok := rows[0].Cell("ifName")
+	octets, ok2 := rows[0].Cell("ifHCInOctets")
+	if !ok || !ok2 || name.Value != "ge-0/0/1" || octets.Value != uint64(100) {
+		logrus.WithField("row", rows[0]).Errorln("Columns were not joined by index")
+		t.Fail()
+	}
+
+	if _, ok = rows[1].Cell("ifName"); ok || len(rows[1].Cells) != 1 {
+		logrus.WithField("row", rows[1]).Errorln("Row contains a column which was not retrieved")
+		t.Fail()
+	}
+}
+
+func TestPollTable(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	defer agent.Close()
+
+	cfg := testAgentConfig(agent.Port())
+	cfg.OIDs = nil
+	cfg.Tables = []TableConfiguration{{Root: ".1.3.6.1.2.1.31.1.1", Columns: []string{"1", "6"}}}
+	if err := ResolveOIDs(&cfg, mib.New()); err != nil {
+		logrus.WithError(err).Errorln("Failed to resolve table")
+		t.FailNow()
+	}
+
+	p := NewPoller(1, 2*time.Second, 0)
+	result := p.Poll(context.Background(), cfg)
+	if result.Err != nil {
+		logrus.WithError(result.Err).Errorln("Failed to poll test agent")
+		t.FailNow()
+	}
+
+	rows := Rows(result, nil, nil)
+	if len(rows) != 2 {
+		logrus.WithField("rows", rows).Errorln("Incorrect number of rows polled")
+		t.FailNow()
+	}
+
+	name, _ := rows[1].Cell(".1.3.6.1.2.1.31.1.1.1.1")
+	octets, _ := rows[1].Cell(".1.3.6.1.2.1.31.1.1.1.6")
+	if rows[1].Index != "2" || name.Value != "ge-0/0/1" || octets.Value != uint64(2000) {
+		logrus.WithField("row", rows[1]).Errorln("Polled row is invalid")
+		t.Fail()
+	}
+}
+
+func TestPollTableColumnInOIDs(t *testing.T) {
+	agent := startTestAgent(t, testAgentPDUs())
+	defer agent.Close()
+
+	// ifHCInOctets is configured both as an OID and as a table column
+	cfg := testAgentConfig(agent.Port())
+	cfg.Tables = []TableConfiguration{{Root: ".1.3.6.1.2.1.31.1.1", Columns: []string{"1", "6"}}}
+	if err := ResolveOIDs(&cfg, mib.New()); err != nil {
+		logrus.WithError(err).Errorln("Failed to resolve table")
+		t.FailNow()
+	}
+
+	p := NewPoller(1, 2*time.Second, 0)
+	rates := NewRateTracker()
+	var records []Record
+	var rows []Row
+	for i := 0; i < 2; i++ {
+		result := p.Poll(context.Background(), cfg)
+		if result.Err != nil {
+			logrus.WithError(result.Err).Errorln("Failed to poll test agent")
+			t.FailNow()
+		}
+
+		if walks := result.AllWalks(); len(walks) != 3 {
+			logrus.WithField("walks", walks).Errorln("Column was returned more than once")
+			t.Fail()
+		}
+
+		records = Records(result, rates, nil)
+		rows = Rows(result, rates, nil)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var recordRate *Rate
+	for _, record := range records {
+		if record.FullOID == ".1.3.6.1.2.1.31.1.1.1.6.2" {
+			recordRate = record.Rate
+		}
+	}
+	octets, _ := rows[1].Cell(".1.3.6.1.2.1.31.1.1.1.6")
+	if recordRate == nil || octets.Rate != recordRate {
+		logrus.WithFields(logrus.Fields{
+			"record_rate": recordRate,
+			"cell_rate":   octets.Rate,
+		}).Errorln("Column rate was not shared between the record and the row")
+		t.Fail()
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewPrometheusCollector(p, []PollConfiguration{cfg}, nil))
+	if _, err := registry.Gather(); err != nil {
+		logrus.WithError(err).Errorln("Column polled as an OID and in a table was exposed twice")
+		t.Fail()
+	}
+}
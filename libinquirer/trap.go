@@ -0,0 +1,164 @@
+package libinquirer
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	sysUpTimeOID   = ".1.3.6.1.2.1.1.3.0"
+	snmpTrapOID    = ".1.3.6.1.6.3.1.1.4.1.0"
+	snmpTrapsOID   = ".1.3.6.1.6.3.1.1.5"
+	enterpriseTrap = 6
+)
+
+// Trap is a notification received from an agent, either a trap or an inform
+type Trap struct {
+	// Host is the address the notification was received from
+	Host string
+	// Version is the SNMP version of the notification, such as v2c
+	Version string
+	// Community is the community of v1 and v2c notifications
+	Community string
+	// Username is the USM user of v3 notifications
+	Username string
+	// Inform is true for informs, which have been acknowledged
+	Inform bool
+	// OID identifies the notification. The OID of v1 traps is derived from
+	// the enterprise and trap numbers as described in RFC 3584
+	OID string
+	// Name is the OID resolved using the MIB, such as IF-MIB::linkDown. It is
+	// empty when no MIB definition applies
+	Name string
+	// Uptime is the sysUpTime of the agent in hundredths of a second
+	Uptime uint32
+	// AgentAddress is the address of the agent included in v1 traps
+	AgentAddress string
+	// Enterprise is the enterprise OID included in v1 traps
+	Enterprise string
+	// Varbinds are the variables of the notification, excluding sysUpTime and
+	// snmpTrapOID which are provided as Uptime and OID
+	Varbinds []Record
+	// Variables are the PDUs of Varbinds as received, used when relaying
+	Variables []gosnmp.SnmpPDU
+	Timestamp time.Time
+}
+
+// NewTrap converts a decoded notification to a trap. Variables are resolved
+// and rendered using the MIB when one is provided
+func NewTrap(host string, packet *gosnmp.SnmpPacket, m *mib.MIB, now time.Time) *Trap {
+	t := &Trap{
+		Host:      host,
+		Community: packet.Community,
+		Inform:    packet.PDUType == gosnmp.InformRequest,
+		Timestamp: now,
+	}
+
+	switch packet.Version {
+	case gosnmp.Version1:
+		t.Version = v1
+	case gosnmp.Version2c:
+		t.Version = v2
+	case gosnmp.Version3:
+		t.Version = v3
+		t.Community = ""
+		if usm, ok := packet.SecurityParameters.(*gosnmp.UsmSecurityParameters); ok {
+			t.Username = usm.UserName
+		}
+	}
+
+	if packet.PDUType == gosnmp.Trap {
+		t.OID = V1TrapOID(packet.Enterprise, packet.GenericTrap, packet.SpecificTrap)
+		t.Uptime = uint32(packet.Timestamp)
+		t.AgentAddress = packet.AgentAddress
+		t.Enterprise = packet.Enterprise
+		if oid, err := mib.NormalizeOID(packet.Enterprise); err == nil {
+			t.Enterprise = oid
+		}
+	}
+
+	for _, pdu := range packet.Variables {
+		switch {
+		case pdu.Name == sysUpTimeOID && pdu.Type == gosnmp.TimeTicks:
+			t.Uptime = uint32(gosnmp.ToBigInt(pdu.Value).Uint64())
+		case pdu.Name == snmpTrapOID && pdu.Type == gosnmp.ObjectIdentifier:
+			t.OID, _ = pdu.Value.(string)
+		default:
+			t.Varbinds = append(t.Varbinds, NewVarbind(host, pdu, m, now))
+			t.Variables = append(t.Variables, pdu)
+		}
+	}
+
+	if m != nil && t.OID != "" {
+		if node, index := m.Lookup(t.OID); node != nil && index == "" {
+			t.Name = node.String()
+		}
+	}
+
+	return t
+}
+
+// NewVarbind creates a record for a variable of a notification, named using
+// the MIB when one is provided
+func NewVarbind(host string, pdu gosnmp.SnmpPDU, m *mib.MIB, now time.Time) Record {
+	r := Record{
+		Host:      host,
+		FullOID:   pdu.Name,
+		BaseOID:   pdu.Name,
+		PDUType:   pdu.Type,
+		Value:     DecodeValue(pdu),
+		Timestamp: now,
+	}
+
+	if m == nil {
+		return r
+	}
+
+	if node, index := m.Lookup(pdu.Name); node != nil {
+		r.BaseOID, r.MIBName, r.Index = node.OID, node.String(), index
+	}
+	if index, values, ok := TableIndex(m, pdu.Name); ok {
+		r.Index, r.IndexValues = index, values
+	}
+	r.Display = DisplayValue(m, pdu)
+
+	return r
+}
+
+// VarbindName returns the name of a variable used as a field name by sinks,
+// which is its MIB object with any index, such as ifOperStatus.3, or its OID
+// when no MIB definition applies
+func VarbindName(r Record) string {
+	if r.MIBName == "" {
+		return r.FullOID
+	}
+
+	name := r.MIBName
+	if i := strings.Index(name, "::"); i >= 0 {
+		name = name[i+2:]
+	}
+	if r.Index != "" {
+		name += "." + r.Index
+	}
+
+	return name
+}
+
+// V1TrapOID converts the enterprise and trap numbers of a v1 trap to the
+// notification OID used by v2c and v3, as described in RFC 3584 section 3.1
+func V1TrapOID(enterprise string, generic, specific int) string {
+	if generic != enterpriseTrap {
+		return snmpTrapsOID + "." + strconv.Itoa(generic+1)
+	}
+
+	oid, err := mib.NormalizeOID(enterprise)
+	if err != nil {
+		oid = enterprise
+	}
+
+	return oid + ".0." + strconv.Itoa(specific)
+}
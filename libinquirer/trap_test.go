@@ -0,0 +1,56 @@
+package libinquirer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+func testTrap() Trap {
+	return Trap{
+		Host:    localhost,
+		Version: v2,
+		OID:     ".1.3.6.1.6.3.1.1.5.3",
+		Name:    "IF-MIB::linkDown",
+		Uptime:  1200,
+		Varbinds: []Record{
+			{FullOID: ".1.3.6.1.2.1.2.2.1.8.3", MIBName: "IF-MIB::ifOperStatus", Index: "3", PDUType: gosnmp.Integer, Value: int64(2), Display: "down"},
+			{FullOID: ".1.3.6.1.4.1.99999.1.0", PDUType: gosnmp.OctetString, Value: "port flap"},
+		},
+		Timestamp: time.Unix(1500000000, 0).UTC(),
+	}
+}
+
+func TestV1TrapOID(t *testing.T) {
+	tests := []struct {
+		enterprise        string
+		generic, specific int
+		expected          string
+	}{
+		{".1.3.6.1.4.1.2636", 0, 0, ".1.3.6.1.6.3.1.1.5.1"},
+		{".1.3.6.1.4.1.2636", 3, 0, ".1.3.6.1.6.3.1.1.5.4"},
+		{"1.3.6.1.4.1.2636.4", 6, 7, ".1.3.6.1.4.1.2636.4.0.7"},
+	}
+
+	for _, test := range tests {
+		if oid := V1TrapOID(test.enterprise, test.generic, test.specific); oid != test.expected {
+			logrus.WithFields(logrus.Fields{"oid": oid, "expected": test.expected}).Errorln("Incorrect v1 trap OID")
+			t.Fail()
+		}
+	}
+}
+
+func TestVarbindName(t *testing.T) {
+	trap := testTrap()
+	if name := VarbindName(trap.Varbinds[0]); name != "ifOperStatus.3" {
+		logrus.WithField("name", name).Errorln("Incorrect variable name")
+		t.Fail()
+	}
+
+	if name := VarbindName(trap.Varbinds[1]); name != ".1.3.6.1.4.1.99999.1.0" {
+		logrus.WithField("name", name).Errorln("Variable without a MIB name was not named by OID")
+		t.Fail()
+	}
+}
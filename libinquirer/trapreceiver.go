@@ -0,0 +1,714 @@
+package libinquirer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kkirsche/snmpInquirer2/libinquirer/mib"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	// DefaultTrapListen is the address traps are received on when none is
+	// configured
+	DefaultTrapListen = ":162"
+
+	usmStatsNotInTimeWindows = ".1.3.6.1.6.3.15.1.1.2.0"
+	usmStatsUnknownEngineIDs = ".1.3.6.1.6.3.15.1.1.4.0"
+	maxTrapSize              = 65535
+
+	// engineBoots is the snmpEngineBoots of the receiver. The engine time
+	// counts from when the receiver was created, so it never restarts
+	engineBoots = 1
+	// timeWindow is the number of seconds the engine time of a message to
+	// the receiver may differ from its own, as in RFC 3414 section 2.2.3
+	timeWindow = 150
+)
+
+// TrapReceiver receives traps and informs on a UDP socket. Informs are
+// acknowledged and v3 notifications are authenticated using the configured
+// users. For v3 traps the agent is the authoritative engine, so keys are
+// localized using the engine ID of each trap. For v3 informs the receiver is
+// authoritative and answers engine ID discovery with its own engine ID
+type TrapReceiver struct {
+	conn        *net.UDPConn
+	mib         *mib.MIB
+	communities map[string]bool
+	users       map[string]*trapUser
+	engineID    string
+	started     time.Time
+	done        chan struct{}
+	closeOnce   sync.Once
+
+	sync.Mutex
+	unknownEngineIDs uint32
+	notInTimeWindows uint32
+}
+
+// NewTrapReceiver creates a receiver listening on the configured address.
+// Variables are resolved and rendered using the MIB when one is provided
+func NewTrapReceiver(cfg TrapConfiguration, m *mib.MIB) (*TrapReceiver, error) {
+	r := &TrapReceiver{
+		mib:         m,
+		communities: map[string]bool{},
+		users:       map[string]*trapUser{},
+		started:     time.Now(),
+		done:        make(chan struct{}),
+	}
+
+	for _, c := range cfg.Communities {
+		r.communities[c.Reveal()] = true
+	}
+
+	for _, u := range cfg.Users {
+		a, err := NewAuth(u.Username, u.SecurityLevel, u.AuthPassword.Reveal(), u.AuthProtocol, u.PrivPassword.Reveal(), u.PrivProtocol)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid trap user %s", u.Username)
+		}
+		if r.users[u.Username], err = newTrapUser(a); err != nil {
+			return nil, errors.Wrapf(err, "invalid trap user %s", u.Username)
+		}
+	}
+
+	if cfg.EngineID != "" {
+		id, err := DecodeEngineID(cfg.EngineID)
+		if err != nil {
+			return nil, err
+		}
+		r.engineID = id
+	} else {
+		r.engineID = generateEngineID()
+	}
+
+	listen := cfg.Listen
+	if listen == "" {
+		listen = DefaultTrapListen
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", listen)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid trap listen address %s", listen)
+	}
+
+	r.conn, err = net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not listen for traps on %s", listen)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"listen":    r.conn.LocalAddr().String(),
+		"engine_id": hex.EncodeToString([]byte(r.engineID)),
+	}).Debugln("Trap receiver listening")
+
+	return r, nil
+}
+
+// trapUser is a user notifications are authenticated with. The passphrases
+// are converted to keys once, so that only the cheap localization to the
+// engine ID of each notification is done as it is received. Engine IDs of
+// traps are chosen by the sender, so their keys are not cached
+type trapUser struct {
+	*SNMPAuth
+	authKey []byte
+	privKey []byte
+}
+
+func newTrapUser(a *SNMPAuth) (*trapUser, error) {
+	u := &trapUser{SNMPAuth: a}
+
+	var err error
+	if a.SecurityLevel&gosnmp.AuthNoPriv != 0 {
+		if u.authKey, err = PasswordToKey(a.AuthProtocol, a.AuthPassword.Reveal()); err != nil {
+			return nil, errors.Wrap(err, "invalid authentication passphrase")
+		}
+	}
+	if a.SecurityLevel&gosnmp.AuthPriv == gosnmp.AuthPriv {
+		if u.privKey, err = PasswordToKey(a.AuthProtocol, a.PrivPassword.Reveal()); err != nil {
+			return nil, errors.Wrap(err, "invalid privacy passphrase")
+		}
+	}
+
+	return u, nil
+}
+
+// generateEngineID creates an engine ID in the octets format of RFC 3411,
+// using random octets as the administratively assigned value
+func generateEngineID() string {
+	id := []byte{0x80, 0x00, 0x00, 0x00, 0x05}
+	random := make([]byte, 8)
+	rand.Read(random)
+
+	return string(append(id, random...))
+}
+
+// Addr returns the address the receiver is listening on
+func (r *TrapReceiver) Addr() *net.UDPAddr {
+	return r.conn.LocalAddr().(*net.UDPAddr)
+}
+
+// EngineID returns the engine ID of the receiver
+func (r *TrapReceiver) EngineID() string {
+	return r.engineID
+}
+
+// Serve receives notifications until the receiver is closed, calling the
+// handler for each one accepted. Notifications which cannot be decoded or
+// authenticated are logged and dropped
+func (r *TrapReceiver) Serve(handler func(*Trap)) error {
+	buf := make([]byte, maxTrapSize)
+	for {
+		n, remote, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-r.done:
+				return nil
+			default:
+				return errors.Wrap(err, "failed to receive trap")
+			}
+		}
+
+		trap, response, err := r.Handle(buf[:n], remote.IP.String())
+		if err != nil {
+			logrus.WithError(err).WithField("host", remote.IP.String()).Warnln("Dropped SNMP notification")
+		}
+
+		if response != nil {
+			if _, err = r.conn.WriteToUDP(response, remote); err != nil {
+				logrus.WithError(err).WithField("host", remote.IP.String()).Errorln("Failed to send response to SNMP notification")
+			}
+		}
+
+		if trap != nil {
+			handler(trap)
+		}
+	}
+}
+
+// Close stops the receiver
+func (r *TrapReceiver) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.done)
+		err = r.conn.Close()
+	})
+
+	return err
+}
+
+// Handle decodes a single notification received from a host. The response
+// to send, if any, is returned alongside the trap. Engine ID discovery
+// requests produce a response without a trap
+func (r *TrapReceiver) Handle(packet []byte, host string) (trap *Trap, response []byte, err error) {
+	// The decoders of gosnmp panic on some malformed lengths, so a packet
+	// which causes a panic is dropped rather than stopping the receiver
+	defer func() {
+		if p := recover(); p != nil {
+			trap, response, err = nil, nil, errors.Errorf("failed to decode notification: %v", p)
+		}
+	}()
+
+	return r.handle(packet, host)
+}
+
+func (r *TrapReceiver) handle(packet []byte, host string) (*Trap, []byte, error) {
+	version, err := packetVersion(packet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var decoded *gosnmp.SnmpPacket
+	switch version {
+	case gosnmp.Version1, gosnmp.Version2c:
+		decoder := &gosnmp.GoSNMP{Version: version}
+		if decoded, err = decoder.SnmpDecodePacket(packet); err != nil {
+			return nil, nil, err
+		}
+		if len(r.communities) > 0 && !r.communities[decoded.Community] {
+			return nil, nil, errors.Errorf("notification has an unknown community")
+		}
+	case gosnmp.Version3:
+		var response []byte
+		decoded, response, err = r.decodeV3(packet)
+		if decoded == nil || err != nil {
+			return nil, response, err
+		}
+	default:
+		return nil, nil, errors.Errorf("unsupported SNMP version %d", version)
+	}
+
+	switch decoded.PDUType {
+	case gosnmp.Trap, gosnmp.SNMPv2Trap, gosnmp.InformRequest:
+	default:
+		return nil, nil, errors.Errorf("unexpected PDU type 0x%x", byte(decoded.PDUType))
+	}
+
+	trap := NewTrap(host, decoded, r.mib, time.Now())
+	if !trap.Inform {
+		return trap, nil, nil
+	}
+
+	response, err := r.acknowledge(decoded)
+	if err != nil {
+		return trap, nil, errors.Wrap(err, "failed to acknowledge inform")
+	}
+
+	return trap, response, nil
+}
+
+// decodeV3 authenticates and decodes a v3 notification using the user it
+// was sent by. Discovery requests are answered with a report
+func (r *TrapReceiver) decodeV3(packet []byte) (*gosnmp.SnmpPacket, []byte, error) {
+	header, err := parseV3Header(packet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if header.engineID == "" {
+		if header.flags&gosnmp.Reportable == 0 || header.flags&gosnmp.AuthPriv != gosnmp.NoAuthNoPriv {
+			return nil, nil, errors.Errorf("notification has no engine ID")
+		}
+
+		response, err := r.report(packet)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to answer engine ID discovery")
+		}
+		logrus.WithField("engine_id", hex.EncodeToString([]byte(r.engineID))).Debugln("Answered engine ID discovery")
+
+		return nil, response, nil
+	}
+
+	user, ok := r.users[header.username]
+	if !ok {
+		return nil, nil, errors.Errorf("notification is from unknown user %s", header.username)
+	}
+
+	// Only the security level configured for the user is accepted, so that
+	// notifications cannot bypass authentication by omitting it
+	if header.flags&gosnmp.AuthPriv != user.SecurityLevel {
+		return nil, nil, errors.Errorf("notification from user %s does not use the configured security level", header.username)
+	}
+
+	// The HMAC is checked here rather than relying on the decoder, which
+	// does not verify the authentication parameters of notifications
+	if user.SecurityLevel&gosnmp.AuthNoPriv != 0 {
+		if err := authenticateV3(packet, header, user); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to authenticate notification from user %s", header.username)
+		}
+	}
+
+	sp, err := usmParameters(user, header.engineID)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to localize keys of user %s", header.username)
+	}
+
+	decoder := &gosnmp.GoSNMP{
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgFlags:           user.SecurityLevel,
+		SecurityParameters: sp,
+	}
+	decoded := decoder.UnmarshalTrap(packet)
+	if decoded == nil {
+		return nil, nil, errors.Errorf("failed to authenticate or decode notification from user %s", header.username)
+	}
+
+	if decoded.PDUType == gosnmp.InformRequest && header.engineID != r.engineID {
+		return nil, nil, errors.Errorf("inform from user %s is not for the engine ID of the receiver", header.username)
+	}
+
+	// Messages for which the receiver is authoritative must be within its
+	// time window, so that captured informs cannot be replayed, as in
+	// RFC 3414 section 3.2 step 7a
+	if header.engineID == r.engineID && !r.inTimeWindow(header) {
+		response, err := r.notInTimeWindow(decoded)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to report notification outside the time window")
+		}
+
+		return nil, response, errors.Errorf("notification from user %s is outside the time window of the receiver", header.username)
+	}
+
+	return decoded, nil, nil
+}
+
+// inTimeWindow reports whether the engine boots and time of a message match
+// those of the receiver
+func (r *TrapReceiver) inTimeWindow(header v3Header) bool {
+	if header.engineBoots != engineBoots {
+		return false
+	}
+
+	now := int64(time.Since(r.started).Seconds())
+	diff := now - int64(header.engineTime)
+
+	return diff <= timeWindow && diff >= -timeWindow
+}
+
+// authenticateV3 checks the msgAuthenticationParameters of a v3 message
+// against the HMAC of the whole message, calculated with the parameters
+// zeroed and the user's key localized to the authoritative engine, as
+// described in RFC 3414 section 6.3.2 and RFC 7860 section 4.2.2
+func authenticateV3(packet []byte, header v3Header, user *trapUser) error {
+	hash, err := authHash(user.AuthProtocol)
+	if err != nil {
+		return err
+	}
+
+	length := authParametersLength(user.AuthProtocol)
+	if len(header.authParameters) != length {
+		return errors.Errorf("authentication parameters are %d octets, expected %d", len(header.authParameters), length)
+	}
+
+	key, err := LocalizeKey(user.AuthProtocol, user.authKey, header.engineID)
+	if err != nil {
+		return errors.Wrap(err, "failed to localize authentication key")
+	}
+
+	message := append([]byte{}, packet...)
+	for i := 0; i < length; i++ {
+		message[header.authOffset+i] = 0
+	}
+
+	mac := hmac.New(hash.New, key)
+	mac.Write(message)
+	if !hmac.Equal(mac.Sum(nil)[:length], header.authParameters) {
+		return errors.New("authentication parameters do not match the message")
+	}
+
+	return nil
+}
+
+// authParametersLength returns the length of the truncated HMAC carried in
+// the msgAuthenticationParameters of an authentication protocol
+func authParametersLength(proto gosnmp.SnmpV3AuthProtocol) int {
+	switch proto {
+	case gosnmp.SHA224:
+		return 16
+	case gosnmp.SHA256:
+		return 24
+	case gosnmp.SHA384:
+		return 32
+	case gosnmp.SHA512:
+		return 48
+	}
+
+	return 12
+}
+
+// usmParameters creates the USM security parameters of a user for the
+// authoritative engine provided, with the user's keys localized to it
+func usmParameters(user *trapUser, engineID string) (*gosnmp.UsmSecurityParameters, error) {
+	sp := &gosnmp.UsmSecurityParameters{
+		AuthoritativeEngineID: engineID,
+		UserName:              user.Username,
+		Logger:                log.New(ioutil.Discard, "", 0),
+	}
+
+	var err error
+	if user.SecurityLevel&gosnmp.AuthNoPriv != 0 {
+		sp.AuthenticationProtocol = user.AuthProtocol
+		sp.AuthenticationPassphrase = user.AuthPassword.Reveal()
+		if sp.SecretKey, err = LocalizeKey(user.AuthProtocol, user.authKey, engineID); err != nil {
+			return nil, err
+		}
+	}
+	if user.SecurityLevel&gosnmp.AuthPriv == gosnmp.AuthPriv {
+		sp.PrivacyProtocol = user.PrivProtocol
+		sp.PrivacyPassphrase = user.PrivPassword.Reveal()
+		key, err := LocalizeKey(user.AuthProtocol, user.privKey, engineID)
+		if err != nil {
+			return nil, err
+		}
+		if sp.PrivacyKey, err = extendPrivKey(user.PrivProtocol, user.AuthProtocol, key, engineID); err != nil {
+			return nil, err
+		}
+	}
+
+	return sp, nil
+}
+
+// report answers an engine ID discovery request with the engine ID, boots
+// and time of the receiver, as described in RFC 3414 section 4
+func (r *TrapReceiver) report(packet []byte) ([]byte, error) {
+	decoder := &gosnmp.GoSNMP{
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgFlags:           gosnmp.NoAuthNoPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{UserName: "discovery"},
+	}
+	request, err := decoder.SnmpDecodePacket(packet)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Lock()
+	r.unknownEngineIDs++
+	count := r.unknownEngineIDs
+	r.Unlock()
+
+	report := &gosnmp.SnmpPacket{
+		Version:            gosnmp.Version3,
+		MsgFlags:           gosnmp.NoAuthNoPriv,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgID:              request.MsgID,
+		SecurityParameters: r.engineParameters(&gosnmp.UsmSecurityParameters{}),
+		ContextEngineID:    r.engineID,
+		PDUType:            gosnmp.Report,
+		RequestID:          request.RequestID,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: usmStatsUnknownEngineIDs, Type: gosnmp.Counter32, Value: count},
+		},
+	}
+
+	return report.MarshalMsg()
+}
+
+// notInTimeWindow answers a message outside the time window of the receiver
+// with an authenticated report of the receiver's engine boots and time, so
+// that the sender may synchronize with it, as in RFC 3414 section 3.2 step 7a
+func (r *TrapReceiver) notInTimeWindow(request *gosnmp.SnmpPacket) ([]byte, error) {
+	received, ok := request.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if !ok {
+		return nil, errors.Errorf("message has no USM security parameters")
+	}
+
+	sp, _ := received.Copy().(*gosnmp.UsmSecurityParameters)
+	sp.Logger = log.New(ioutil.Discard, "", 0)
+	sp.AuthenticationParameters = ""
+
+	r.Lock()
+	r.notInTimeWindows++
+	count := r.notInTimeWindows
+	r.Unlock()
+
+	report := &gosnmp.SnmpPacket{
+		Version:            gosnmp.Version3,
+		MsgFlags:           gosnmp.AuthNoPriv,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgID:              request.MsgID,
+		SecurityParameters: r.engineParameters(sp),
+		ContextEngineID:    r.engineID,
+		ContextName:        request.ContextName,
+		PDUType:            gosnmp.Report,
+		RequestID:          request.RequestID,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: usmStatsNotInTimeWindows, Type: gosnmp.Counter32, Value: count},
+		},
+	}
+
+	return report.MarshalMsg()
+}
+
+// acknowledge creates the response to an inform, which repeats its
+// variables. v3 responses are secured in the same way as the inform
+func (r *TrapReceiver) acknowledge(inform *gosnmp.SnmpPacket) ([]byte, error) {
+	response := &gosnmp.SnmpPacket{
+		Version:   inform.Version,
+		Community: inform.Community,
+		PDUType:   gosnmp.GetResponse,
+		RequestID: inform.RequestID,
+		Variables: inform.Variables,
+	}
+
+	if inform.Version == gosnmp.Version3 {
+		received, ok := inform.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		if !ok {
+			return nil, errors.Errorf("inform has no USM security parameters")
+		}
+
+		sp, _ := received.Copy().(*gosnmp.UsmSecurityParameters)
+		sp.Logger = log.New(ioutil.Discard, "", 0)
+		sp.AuthenticationParameters = ""
+		if inform.MsgFlags&gosnmp.AuthPriv == gosnmp.AuthPriv {
+			if err := r.privacySalt(sp); err != nil {
+				return nil, err
+			}
+		}
+
+		response.MsgFlags = inform.MsgFlags & gosnmp.AuthPriv
+		response.SecurityModel = gosnmp.UserSecurityModel
+		response.MsgID = inform.MsgID
+		response.SecurityParameters = r.engineParameters(sp)
+		response.ContextEngineID = inform.ContextEngineID
+		response.ContextName = inform.ContextName
+	}
+
+	return response.MarshalMsg()
+}
+
+// engineParameters sets the engine ID, boots and time of the receiver
+func (r *TrapReceiver) engineParameters(sp *gosnmp.UsmSecurityParameters) *gosnmp.UsmSecurityParameters {
+	sp.AuthoritativeEngineID = r.engineID
+	sp.AuthoritativeEngineBoots = engineBoots
+	sp.AuthoritativeEngineTime = uint32(time.Since(r.started).Seconds())
+	if sp.Logger == nil {
+		sp.Logger = log.New(ioutil.Discard, "", 0)
+	}
+
+	return sp
+}
+
+// privacySalt sets a new random salt, which must never be reused with the
+// same key. DES salts begin with the engine boots, as in RFC 3414 8.1.1.1
+func (r *TrapReceiver) privacySalt(sp *gosnmp.UsmSecurityParameters) error {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return errors.Wrap(err, "failed to create privacy salt")
+	}
+	if sp.PrivacyProtocol == gosnmp.DES {
+		binary.BigEndian.PutUint32(salt, engineBoots)
+	}
+	sp.PrivacyParameters = salt
+
+	return nil
+}
+
+// v3Header holds the fields of a v3 message needed to select the user which
+// authenticates it
+type v3Header struct {
+	flags       gosnmp.SnmpV3MsgFlags
+	engineID    string
+	engineBoots uint32
+	engineTime  uint32
+	username    string
+	// authParameters is the msgAuthenticationParameters of the message,
+	// found at authOffset within it
+	authParameters []byte
+	authOffset     int
+}
+
+// packetVersion reads the version of an SNMP message without decoding it
+func packetVersion(packet []byte) (gosnmp.SnmpVersion, error) {
+	_, message, _, err := berElement(packet)
+	if err != nil {
+		return 0, err
+	}
+
+	tag, version, _, err := berElement(message)
+	if err != nil || tag != byte(gosnmp.Integer) || len(version) != 1 {
+		return 0, errors.Errorf("invalid SNMP message version")
+	}
+
+	return gosnmp.SnmpVersion(version[0]), nil
+}
+
+// parseV3Header reads the flags, authoritative engine ID, boots and time,
+// user name and authentication parameters of a v3 message, which precede any
+// encrypted data
+func parseV3Header(packet []byte) (v3Header, error) {
+	var h v3Header
+	invalid := errors.Errorf("invalid SNMP v3 message header")
+
+	// Elements share the array of the packet, so the offset of an element
+	// is the difference of their capacities once the packet is limited to
+	// its length
+	packet = packet[:len(packet):len(packet)]
+
+	_, message, _, err := berElement(packet)
+	if err != nil {
+		return h, err
+	}
+
+	// msgVersion, msgGlobalData and msgSecurityParameters
+	_, _, rest, err := berElement(message)
+	if err != nil {
+		return h, invalid
+	}
+	_, global, rest, err := berElement(rest)
+	if err != nil {
+		return h, invalid
+	}
+	_, security, _, err := berElement(rest)
+	if err != nil {
+		return h, invalid
+	}
+
+	// msgID, msgMaxSize and msgFlags
+	fields := global
+	for i := 0; i < 3; i++ {
+		var value []byte
+		if _, value, fields, err = berElement(fields); err != nil {
+			return h, invalid
+		}
+		if i == 2 {
+			if len(value) != 1 {
+				return h, invalid
+			}
+			h.flags = gosnmp.SnmpV3MsgFlags(value[0])
+		}
+	}
+
+	// msgAuthoritativeEngineID, boots, time, msgUserName and
+	// msgAuthenticationParameters
+	if _, fields, _, err = berElement(security); err != nil {
+		return h, invalid
+	}
+	for i := 0; i < 5; i++ {
+		var value []byte
+		if _, value, fields, err = berElement(fields); err != nil {
+			return h, invalid
+		}
+		switch i {
+		case 0:
+			h.engineID = string(value)
+		case 1, 2:
+			// Non-negative INTEGERs of up to 2^31-1, which may have a
+			// leading zero octet
+			if len(value) < 1 || len(value) > 5 {
+				return h, invalid
+			}
+			var n uint32
+			for _, octet := range value {
+				n = n<<8 | uint32(octet)
+			}
+			if i == 1 {
+				h.engineBoots = n
+			} else {
+				h.engineTime = n
+			}
+		case 3:
+			h.username = string(value)
+		case 4:
+			h.authParameters = value
+			h.authOffset = cap(packet) - cap(value)
+		}
+	}
+
+	return h, nil
+}
+
+// berElement splits the first BER element of a buffer into its tag and
+// contents, returning the bytes following it
+func berElement(b []byte) (byte, []byte, []byte, error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errors.Errorf("truncated BER element")
+	}
+
+	tag, length, offset := b[0], int(b[1]), 2
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || len(b) < offset+n {
+			return 0, nil, nil, errors.Errorf("invalid BER length")
+		}
+		length = 0
+		for _, octet := range b[offset : offset+n] {
+			length = length<<8 | int(octet)
+		}
+		offset += n
+	}
+
+	if length < 0 || len(b)-offset < length {
+		return 0, nil, nil, errors.Errorf("truncated BER element")
+	}
+
+	return tag, b[offset : offset+length], b[offset+length:], nil
+}
@@ -0,0 +1,483 @@
+package libinquirer
+
+import (
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	testTrapUser     = "trapper"
+	testTrapPassword = "trap-password"
+)
+
+func testTrapReceiver(t *testing.T, cfg TrapConfiguration) *TrapReceiver {
+	cfg.Listen = localhost + ":0"
+	r, err := NewTrapReceiver(cfg, testMIB(t))
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to create trap receiver")
+		t.FailNow()
+	}
+
+	return r
+}
+
+func testTrapUsers() []auth {
+	return []auth{{
+		Username:      testTrapUser,
+		SecurityLevel: authpriv,
+		AuthPassword:  testTrapPassword,
+		AuthProtocol:  sha,
+		PrivPassword:  testTrapPassword,
+		PrivProtocol:  aes,
+	}}
+}
+
+func testLinkDown() []gosnmp.SnmpPDU {
+	return []gosnmp.SnmpPDU{
+		{Name: snmpTrapOID, Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.3"},
+		{Name: ".1.3.6.1.2.1.2.2.1.1.3", Type: gosnmp.Integer, Value: 3},
+		{Name: ".1.3.6.1.2.1.2.2.1.8.3", Type: gosnmp.Integer, Value: 2},
+	}
+}
+
+// receiveTraps serves a receiver until it is closed, sending each trap to the
+// channel returned
+func receiveTraps(r *TrapReceiver) chan *Trap {
+	traps := make(chan *Trap, 10)
+	go r.Serve(func(trap *Trap) {
+		traps <- trap
+	})
+
+	return traps
+}
+
+func waitForTrap(t *testing.T, traps chan *Trap) *Trap {
+	select {
+	case trap := <-traps:
+		return trap
+	case <-time.After(5 * time.Second):
+		logrus.Errorln("Timed out waiting for trap")
+		t.FailNow()
+	}
+
+	return nil
+}
+
+func checkLinkDown(t *testing.T, trap *Trap) {
+	if trap.OID != ".1.3.6.1.6.3.1.1.5.3" || trap.Name != "IF-MIB::linkDown" || len(trap.Varbinds) != 2 {
+		logrus.WithField("trap", trap).Errorln("Trap was decoded incorrectly")
+		t.FailNow()
+	}
+
+	status := trap.Varbinds[1]
+	if status.MIBName != "IF-MIB::ifOperStatus" || status.Index != "3" || status.Display != "down" || VarbindName(status) != "ifOperStatus.3" {
+		logrus.WithField("varbind", status).Errorln("Trap variable was not resolved")
+		t.Fail()
+	}
+}
+
+func TestTrapReceiverV2c(t *testing.T) {
+	r := testTrapReceiver(t, TrapConfiguration{Communities: []Secret{testCommunity}})
+	defer r.Close()
+	traps := receiveTraps(r)
+
+	client := &gosnmp.GoSNMP{
+		Target:    localhost,
+		Port:      uint16(r.Addr().Port),
+		Version:   gosnmp.Version2c,
+		Community: testCommunity,
+		Timeout:   2 * time.Second,
+	}
+	if err := client.Connect(); err != nil {
+		logrus.WithError(err).Errorln("Failed to connect to trap receiver")
+		t.FailNow()
+	}
+	defer client.Conn.Close()
+
+	if _, err := client.SendTrap(gosnmp.SnmpTrap{Variables: testLinkDown()}); err != nil {
+		logrus.WithError(err).Errorln("Failed to send trap")
+		t.FailNow()
+	}
+
+	trap := waitForTrap(t, traps)
+	checkLinkDown(t, trap)
+	if trap.Version != v2 || trap.Community != testCommunity || trap.Inform || trap.Host != localhost {
+		logrus.WithField("trap", trap).Errorln("Trap details are invalid")
+		t.Fail()
+	}
+}
+
+func TestTrapReceiverV1(t *testing.T) {
+	r := testTrapReceiver(t, TrapConfiguration{})
+	defer r.Close()
+
+	packet := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version1,
+		Community: testCommunity,
+		PDUType:   gosnmp.Trap,
+		Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.2.2.1.1.3", Type: gosnmp.Integer, Value: 3}},
+		SnmpTrap: gosnmp.SnmpTrap{
+			Enterprise:   ".1.3.6.1.4.1.2636",
+			AgentAddress: "192.0.2.1",
+			GenericTrap:  2,
+			Timestamp:    500,
+		},
+	}
+	b, err := packet.MarshalMsg()
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to marshal trap")
+		t.FailNow()
+	}
+
+	trap, response, err := r.Handle(b, localhost)
+	if err != nil || response != nil {
+		logrus.WithError(err).Errorln("Failed to handle v1 trap")
+		t.FailNow()
+	}
+
+	if trap.Version != v1 || trap.Name != "IF-MIB::linkDown" || trap.AgentAddress != "192.0.2.1" || trap.Uptime != 500 || len(trap.Varbinds) != 1 {
+		logrus.WithField("trap", trap).Errorln("v1 trap was decoded incorrectly")
+		t.Fail()
+	}
+}
+
+func TestTrapReceiverInform(t *testing.T) {
+	r := testTrapReceiver(t, TrapConfiguration{})
+	defer r.Close()
+
+	inform := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: testCommunity,
+		PDUType:   gosnmp.InformRequest,
+		RequestID: 42,
+		Variables: testLinkDown(),
+	}
+	b, _ := inform.MarshalMsg()
+
+	trap, response, err := r.Handle(b, localhost)
+	if err != nil || trap == nil || !trap.Inform {
+		logrus.WithError(err).Errorln("Failed to handle inform")
+		t.FailNow()
+	}
+
+	decoded, err := (&gosnmp.GoSNMP{Version: gosnmp.Version2c}).SnmpDecodePacket(response)
+	if err != nil || decoded.PDUType != gosnmp.GetResponse || decoded.RequestID != 42 || len(decoded.Variables) != 3 {
+		logrus.WithError(err).WithField("response", decoded).Errorln("Inform was not acknowledged")
+		t.Fail()
+	}
+}
+
+func TestTrapReceiverInvalidCommunity(t *testing.T) {
+	r := testTrapReceiver(t, TrapConfiguration{Communities: []Secret{testCommunity}})
+	defer r.Close()
+
+	packet := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: invalid,
+		PDUType:   gosnmp.SNMPv2Trap,
+		Variables: testLinkDown(),
+	}
+	b, _ := packet.MarshalMsg()
+
+	if trap, _, err := r.Handle(b, localhost); err == nil || trap != nil {
+		logrus.Errorln("Trap with an invalid community was accepted")
+		t.Fail()
+	}
+
+	if _, _, err := r.Handle([]byte{0x30, 0x03, 0x02, 0x01}, localhost); err == nil {
+		logrus.Errorln("Truncated packet was accepted")
+		t.Fail()
+	}
+}
+
+func TestTrapReceiverMalformed(t *testing.T) {
+	r := testTrapReceiver(t, TrapConfiguration{Users: testTrapUsers()})
+	defer r.Close()
+
+	trap := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: testCommunity,
+		PDUType:   gosnmp.SNMPv2Trap,
+		Variables: testLinkDown(),
+	}
+	b, _ := trap.MarshalMsg()
+
+	defer func() {
+		if p := recover(); p != nil {
+			logrus.WithField("panic", p).Errorln("Malformed packet stopped the receiver")
+			t.Fail()
+		}
+	}()
+
+	for n := range b {
+		r.Handle(b[:n], localhost)
+	}
+
+	for i := range b {
+		for _, octet := range []byte{0x00, 0x7f, 0x80, 0x84, 0xff, b[i] ^ 0xff} {
+			mutated := append([]byte{}, b...)
+			mutated[i] = octet
+			r.Handle(mutated, localhost)
+		}
+	}
+}
+
+func testV3Client(port int, password string) *gosnmp.GoSNMP {
+	return &gosnmp.GoSNMP{
+		Target:        localhost,
+		Port:          uint16(port),
+		Version:       gosnmp.Version3,
+		Timeout:       2 * time.Second,
+		SecurityModel: gosnmp.UserSecurityModel,
+		MsgFlags:      gosnmp.AuthPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			UserName:                 testTrapUser,
+			AuthoritativeEngineID:    "\x80\x00\x00\x00\x05agent",
+			AuthenticationProtocol:   gosnmp.SHA,
+			AuthenticationPassphrase: password,
+			PrivacyProtocol:          gosnmp.AES,
+			PrivacyPassphrase:        password,
+		},
+	}
+}
+
+func TestTrapReceiverV3(t *testing.T) {
+	r := testTrapReceiver(t, TrapConfiguration{Users: testTrapUsers()})
+	defer r.Close()
+	traps := receiveTraps(r)
+
+	for _, password := range []string{"wrong-password", testTrapPassword} {
+		client := testV3Client(r.Addr().Port, password)
+		if err := client.Connect(); err != nil {
+			logrus.WithError(err).Errorln("Failed to connect to trap receiver")
+			t.FailNow()
+		}
+		if _, err := client.SendTrap(gosnmp.SnmpTrap{Variables: testLinkDown()}); err != nil {
+			logrus.WithError(err).Errorln("Failed to send v3 trap")
+			t.FailNow()
+		}
+		client.Conn.Close()
+	}
+
+	// The trap with the wrong password is dropped, so the first trap
+	// received is the authentic one
+	trap := waitForTrap(t, traps)
+	checkLinkDown(t, trap)
+	if trap.Version != v3 || trap.Username != testTrapUser || trap.Community != "" {
+		logrus.WithField("trap", trap).Errorln("v3 trap details are invalid")
+		t.Fail()
+	}
+}
+
+func TestTrapReceiverV3AuthNoPriv(t *testing.T) {
+	users := testTrapUsers()
+	users[0].SecurityLevel = authnopriv
+	r := testTrapReceiver(t, TrapConfiguration{Users: users})
+	defer r.Close()
+	traps := receiveTraps(r)
+	cached := DefaultKeyCache.Len()
+
+	// Without privacy a trap signed with the wrong key can still be
+	// decoded, so it is only dropped when its HMAC is checked
+	for _, password := range []string{"wrong-password", testTrapPassword} {
+		client := testV3Client(r.Addr().Port, password)
+		client.MsgFlags = gosnmp.AuthNoPriv
+		if err := client.Connect(); err != nil {
+			logrus.WithError(err).Errorln("Failed to connect to trap receiver")
+			t.FailNow()
+		}
+		if _, err := client.SendTrap(gosnmp.SnmpTrap{Variables: testLinkDown()}); err != nil {
+			logrus.WithError(err).Errorln("Failed to send v3 trap")
+			t.FailNow()
+		}
+		client.Conn.Close()
+	}
+
+	trap := waitForTrap(t, traps)
+	checkLinkDown(t, trap)
+
+	select {
+	case trap := <-traps:
+		logrus.WithField("trap", trap).Errorln("Trap signed with the wrong key was accepted")
+		t.Fail()
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Engine IDs of traps are chosen by the sender, so their keys must not
+	// be added to the shared cache
+	if DefaultKeyCache.Len() != cached {
+		logrus.Errorln("Keys localized for received traps were cached")
+		t.Fail()
+	}
+}
+
+func TestTrapReceiverV3Protocols(t *testing.T) {
+	protocols := [][2]string{{sha224, aes192}, {sha256, aes256}, {sha384, aes192c}, {sha512, aes256c}}
+	for _, p := range protocols {
+		users := testTrapUsers()
+		users[0].AuthProtocol, users[0].PrivProtocol = p[0], p[1]
+		r := testTrapReceiver(t, TrapConfiguration{Users: users})
+		traps := receiveTraps(r)
+
+		a, err := NewAuth(testTrapUser, authpriv, testTrapPassword, p[0], testTrapPassword, p[1])
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to create v3 authentication")
+			t.FailNow()
+		}
+
+		client := testV3Client(r.Addr().Port, testTrapPassword)
+		sp := client.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		sp.AuthenticationProtocol, sp.PrivacyProtocol = a.AuthProtocol, a.PrivProtocol
+		if err := client.Connect(); err != nil {
+			logrus.WithError(err).Errorln("Failed to connect to trap receiver")
+			t.FailNow()
+		}
+		if _, err := client.SendTrap(gosnmp.SnmpTrap{Variables: testLinkDown()}); err != nil {
+			logrus.WithError(err).WithField("protocols", p).Errorln("Failed to send v3 trap")
+			t.Fail()
+		}
+
+		checkLinkDown(t, waitForTrap(t, traps))
+		client.Conn.Close()
+		r.Close()
+	}
+}
+
+func TestTrapReceiverV3Inform(t *testing.T) {
+	users := testTrapUsers()
+	users[0].SecurityLevel = authnopriv
+	r := testTrapReceiver(t, TrapConfiguration{Users: users, EngineID: "80000000050102030405"})
+	defer r.Close()
+
+	discovery := &gosnmp.SnmpPacket{
+		Version:            gosnmp.Version3,
+		MsgFlags:           gosnmp.Reportable,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgID:              5,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{Logger: log.New(ioutil.Discard, "", 0)},
+		PDUType:            gosnmp.GetRequest,
+		RequestID:          6,
+	}
+	b, _ := discovery.MarshalMsg()
+
+	trap, response, err := r.Handle(b, localhost)
+	if err != nil || trap != nil || response == nil {
+		logrus.WithError(err).Errorln("Engine ID discovery was not answered")
+		t.FailNow()
+	}
+
+	header, err := parseV3Header(response)
+	if err != nil || header.engineID != r.EngineID() {
+		logrus.WithError(err).Errorln("Report does not contain the engine ID of the receiver")
+		t.Fail()
+	}
+
+	key, _ := LocalizedAuthKey(gosnmp.SHA, testTrapPassword, r.EngineID())
+	sp := &gosnmp.UsmSecurityParameters{
+		AuthoritativeEngineID:    r.EngineID(),
+		AuthoritativeEngineBoots: 1,
+		UserName:                 testTrapUser,
+		AuthenticationProtocol:   gosnmp.SHA,
+		AuthenticationPassphrase: testTrapPassword,
+		SecretKey:                key,
+		Logger:                   log.New(ioutil.Discard, "", 0),
+	}
+	inform := &gosnmp.SnmpPacket{
+		Version:            gosnmp.Version3,
+		MsgFlags:           gosnmp.AuthNoPriv | gosnmp.Reportable,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgID:              7,
+		SecurityParameters: sp,
+		ContextEngineID:    r.EngineID(),
+		PDUType:            gosnmp.InformRequest,
+		RequestID:          8,
+		Variables:          testLinkDown(),
+	}
+	b, err = inform.MarshalMsg()
+	if err != nil {
+		logrus.WithError(err).Errorln("Failed to marshal inform")
+		t.FailNow()
+	}
+
+	trap, response, err = r.Handle(b, localhost)
+	if err != nil || trap == nil || response == nil {
+		logrus.WithError(err).Errorln("Failed to handle v3 inform")
+		t.FailNow()
+	}
+	checkLinkDown(t, trap)
+
+	decoder := &gosnmp.GoSNMP{
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgFlags:           gosnmp.AuthNoPriv,
+		SecurityParameters: sp.Copy(),
+	}
+	decoded := decoder.UnmarshalTrap(response)
+	if decoded == nil || decoded.PDUType != gosnmp.GetResponse || decoded.RequestID != 8 || decoded.MsgID != 7 {
+		logrus.WithField("response", decoded).Errorln("v3 inform was not acknowledged")
+		t.Fail()
+	}
+}
+
+func TestTrapReceiverV3InformTimeWindow(t *testing.T) {
+	users := testTrapUsers()
+	users[0].SecurityLevel = authnopriv
+	r := testTrapReceiver(t, TrapConfiguration{Users: users, EngineID: "80000000050102030405"})
+	defer r.Close()
+
+	// A captured inform replayed later carries an engine time outside the
+	// window of the receiver, as does one from before the engine rebooted
+	key, _ := LocalizedAuthKey(gosnmp.SHA, testTrapPassword, r.EngineID())
+	for _, stale := range [][2]uint32{{1, 1000}, {2, 0}} {
+		sp := &gosnmp.UsmSecurityParameters{
+			AuthoritativeEngineID:    r.EngineID(),
+			AuthoritativeEngineBoots: stale[0],
+			AuthoritativeEngineTime:  stale[1],
+			UserName:                 testTrapUser,
+			AuthenticationProtocol:   gosnmp.SHA,
+			AuthenticationPassphrase: testTrapPassword,
+			SecretKey:                key,
+			Logger:                   log.New(ioutil.Discard, "", 0),
+		}
+		inform := &gosnmp.SnmpPacket{
+			Version:            gosnmp.Version3,
+			MsgFlags:           gosnmp.AuthNoPriv | gosnmp.Reportable,
+			SecurityModel:      gosnmp.UserSecurityModel,
+			MsgID:              9,
+			SecurityParameters: sp,
+			ContextEngineID:    r.EngineID(),
+			PDUType:            gosnmp.InformRequest,
+			RequestID:          10,
+			Variables:          testLinkDown(),
+		}
+		b, err := inform.MarshalMsg()
+		if err != nil {
+			logrus.WithError(err).Errorln("Failed to marshal inform")
+			t.FailNow()
+		}
+
+		trap, response, err := r.Handle(b, localhost)
+		if err == nil || trap != nil || response == nil {
+			logrus.WithError(err).WithField("engine", stale).Errorln("Stale inform was accepted")
+			t.Fail()
+			continue
+		}
+
+		decoder := &gosnmp.GoSNMP{
+			Version:            gosnmp.Version3,
+			SecurityModel:      gosnmp.UserSecurityModel,
+			MsgFlags:           gosnmp.AuthNoPriv,
+			SecurityParameters: sp.Copy(),
+		}
+		decoded := decoder.UnmarshalTrap(response)
+		if decoded == nil || decoded.PDUType != gosnmp.Report || len(decoded.Variables) != 1 || decoded.Variables[0].Name != usmStatsNotInTimeWindows {
+			logrus.WithField("response", decoded).Errorln("Stale inform was not answered with a usmStatsNotInTimeWindows report")
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package libinquirer
+
+import (
+	"crypto"
+	// Register the hashes of the authentication protocols
+	_ "crypto/md5"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/soniah/gosnmp"
+)
+
+// authHash returns the hash function of an authentication protocol
+func authHash(proto gosnmp.SnmpV3AuthProtocol) (crypto.Hash, error) {
+	switch proto {
+	case gosnmp.MD5:
+		return crypto.MD5, nil
+	case gosnmp.SHA:
+		return crypto.SHA1, nil
+	case gosnmp.SHA224:
+		return crypto.SHA224, nil
+	case gosnmp.SHA256:
+		return crypto.SHA256, nil
+	case gosnmp.SHA384:
+		return crypto.SHA384, nil
+	case gosnmp.SHA512:
+		return crypto.SHA512, nil
+	}
+
+	return 0, errors.Errorf("authentication protocol %d has no hash function", proto)
+}
+
+// PasswordToKey converts a passphrase to a key by hashing a megabyte of the
+// repeated passphrase, as described in RFC 3414 section A.2.1 and used by
+// RFC 7860 for the SHA-2 protocols
+func PasswordToKey(proto gosnmp.SnmpV3AuthProtocol, passphrase string) ([]byte, error) {
+	hash, err := authHash(proto)
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, errors.New("passphrase is empty")
+	}
+
+	h := hash.New()
+	chunk := make([]byte, 64)
+	for i := 0; i < 1048576; i += len(chunk) {
+		for j := range chunk {
+			chunk[j] = passphrase[(i+j)%len(passphrase)]
+		}
+		h.Write(chunk)
+	}
+
+	return h.Sum(nil), nil
+}
+
+// LocalizeKey localizes a key to an authoritative engine ID, as described
+// in RFC 3414 section A.2.1
+func LocalizeKey(proto gosnmp.SnmpV3AuthProtocol, key []byte, engineID string) ([]byte, error) {
+	hash, err := authHash(proto)
+	if err != nil {
+		return nil, err
+	}
+
+	h := hash.New()
+	h.Write(key)
+	h.Write([]byte(engineID))
+	h.Write(key)
+
+	return h.Sum(nil), nil
+}
+
+// LocalizedAuthKey returns the authentication key of a passphrase localized
+// to an authoritative engine ID
+func LocalizedAuthKey(proto gosnmp.SnmpV3AuthProtocol, passphrase, engineID string) ([]byte, error) {
+	key, err := PasswordToKey(proto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return LocalizeKey(proto, key, engineID)
+}
+
+// LocalizedPrivKey returns the privacy key of a passphrase localized to an
+// authoritative engine ID. Localized keys shorter than the privacy protocol
+// requires are extended using the algorithm of the protocol
+func LocalizedPrivKey(priv gosnmp.SnmpV3PrivProtocol, auth gosnmp.SnmpV3AuthProtocol, passphrase, engineID string) ([]byte, error) {
+	key, err := LocalizedAuthKey(auth, passphrase, engineID)
+	if err != nil {
+		return nil, err
+	}
+
+	return extendPrivKey(priv, auth, key, engineID)
+}
+
+// extendPrivKey extends a localized key to the length the privacy protocol
+// requires, truncating it when it is longer
+func extendPrivKey(priv gosnmp.SnmpV3PrivProtocol, auth gosnmp.SnmpV3AuthProtocol, key []byte, engineID string) ([]byte, error) {
+	length := privKeyLength(priv)
+	if length == 0 {
+		return nil, errors.Errorf("privacy protocol %d has no key", priv)
+	}
+
+	hash, err := authHash(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	block := key
+	for len(key) < length {
+		switch priv {
+		case gosnmp.AES192C, gosnmp.AES256C:
+			// Reeder: the last block is treated as a passphrase and
+			// localized again
+			if block, err = LocalizedAuthKey(auth, string(block), engineID); err != nil {
+				return nil, err
+			}
+			key = append(key, block...)
+		default:
+			// Blumenthal: the hash of the key so far is appended
+			h := hash.New()
+			h.Write(key)
+			key = append(key, h.Sum(nil)...)
+		}
+	}
+
+	return key[:length], nil
+}
+
+// privKeyLength returns the length of the key of a privacy protocol
+func privKeyLength(priv gosnmp.SnmpV3PrivProtocol) int {
+	switch priv {
+	case gosnmp.DES, gosnmp.AES:
+		return 16
+	case gosnmp.AES192, gosnmp.AES192C:
+		return 24
+	case gosnmp.AES256, gosnmp.AES256C:
+		return 32
+	}
+
+	return 0
+}
+
+// KeyCache holds localized keys so that the password to key algorithm is run
+// once per passphrase, protocol and engine ID rather than for every client.
+// Passphrases are hashed before they are used to look up keys
+type KeyCache struct {
+	mu   sync.Mutex
+	keys map[keyCacheKey][]byte
+}
+
+type keyCacheKey struct {
+	passphrase string
+	auth       gosnmp.SnmpV3AuthProtocol
+	priv       gosnmp.SnmpV3PrivProtocol
+	engineID   string
+}
+
+// DefaultKeyCache is the key cache used when creating clients
+var DefaultKeyCache = NewKeyCache()
+
+// NewKeyCache creates an empty key cache
+func NewKeyCache() *KeyCache {
+	return &KeyCache{keys: map[keyCacheKey][]byte{}}
+}
+
+// AuthKey returns the authentication key of a passphrase localized to an
+// authoritative engine ID, deriving it if it is not cached
+func (c *KeyCache) AuthKey(proto gosnmp.SnmpV3AuthProtocol, passphrase, engineID string) ([]byte, error) {
+	k := keyCacheKey{auth: proto, priv: gosnmp.NoPriv, engineID: engineID}
+	return c.key(k, passphrase, func() ([]byte, error) {
+		return LocalizedAuthKey(proto, passphrase, engineID)
+	})
+}
+
+// PrivKey returns the privacy key of a passphrase localized to an
+// authoritative engine ID, deriving it if it is not cached
+func (c *KeyCache) PrivKey(priv gosnmp.SnmpV3PrivProtocol, auth gosnmp.SnmpV3AuthProtocol, passphrase, engineID string) ([]byte, error) {
+	k := keyCacheKey{auth: auth, priv: priv, engineID: engineID}
+	return c.key(k, passphrase, func() ([]byte, error) {
+		return LocalizedPrivKey(priv, auth, passphrase, engineID)
+	})
+}
+
+// Len returns the number of keys held in the cache
+func (c *KeyCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.keys)
+}
+
+func (c *KeyCache) key(k keyCacheKey, passphrase string, derive func() ([]byte, error)) ([]byte, error) {
+	h := crypto.SHA256.New()
+	h.Write([]byte(passphrase))
+	k.passphrase = string(h.Sum(nil))
+
+	c.mu.Lock()
+	key, ok := c.keys[k]
+	c.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	key, err := derive()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.keys[k] = key
+	c.mu.Unlock()
+
+	return key, nil
+}
+
+// localizeKeys sets the keys of a client's security parameters for its
+// authoritative engine ID using a key cache, unless they are already set.
+// Only the keys required by the security level are set
+func localizeKeys(level gosnmp.SnmpV3MsgFlags, usm *gosnmp.UsmSecurityParameters, cache *KeyCache) error {
+	if usm.AuthoritativeEngineID == "" {
+		return nil
+	}
+
+	var err error
+	if level&gosnmp.AuthNoPriv != 0 && len(usm.SecretKey) == 0 {
+		usm.SecretKey, err = cache.AuthKey(usm.AuthenticationProtocol, usm.AuthenticationPassphrase, usm.AuthoritativeEngineID)
+		if err != nil {
+			return errors.Wrap(err, "failed to localize authentication key")
+		}
+	}
+	if level&gosnmp.AuthPriv == gosnmp.AuthPriv && len(usm.PrivacyKey) == 0 {
+		usm.PrivacyKey, err = cache.PrivKey(usm.PrivacyProtocol, usm.AuthenticationProtocol, usm.PrivacyPassphrase, usm.AuthoritativeEngineID)
+		if err != nil {
+			return errors.Wrap(err, "failed to localize privacy key")
+		}
+	}
+
+	return nil
+}